@@ -6,9 +6,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/marolt/go-discovery/pkg/collector"
+	"github.com/marolt/go-discovery/pkg/redact"
 	"github.com/marolt/go-discovery/pkg/report"
+	"github.com/marolt/go-discovery/pkg/vuln"
 )
 
 // Version information set by build process
@@ -18,14 +21,31 @@ var (
 )
 
 func main() {
+	// A re-executed copy inspects one container filesystem from inside a chroot
+	if root := os.Getenv(collector.ContainerInspectionEnv); root != "" {
+		if err := collector.InspectRoot(root, os.Stdout, log.New(os.Stderr, "", log.LstdFlags)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error inspecting container filesystem %s: %v\n", root, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Add a version flag
 	showVersion := flag.Bool("version", false, "Show version information")
 
 	// Parse command line flags
-	outputFormat := flag.String("format", "yaml", "Output format: yaml or json")
-	outputFile := flag.String("output", "", "Output file (default: system_discovery_report.[yaml|json])")
+	outputFormat := flag.String("format", "yaml", "Output format: yaml, json, cyclonedx or spdx")
+	outputFile := flag.String("output", "", "Output file (default: system_discovery_report.[yaml|json|cdx.json|spdx.json])")
 	logFile := flag.String("log", "system_discovery.log", "Log file")
 	logToStdout := flag.Bool("stdout", true, "Log to stdout as well as log file")
+	vulnDBDir := flag.String("vulndb", "", "Directory with an exported OSV advisory database to match packages against")
+	cloudMetadata := flag.Bool("cloud-metadata", false, "Query the cloud instance metadata service for instance ID, type, region and zone")
+	composeConfig := flag.Bool("compose-config", false, "Record the fully resolved configuration of each Docker Compose project")
+	containerExport := flag.Bool("container-export", false, "Fall back to a docker export copy in the temporary directory for containers whose overlay2 directory is not reachable")
+	noRedact := flag.Bool("no-redact", false, "Write secrets such as passwords, tokens and URL credentials to the report unmasked")
+	redactKeys := flag.String("redact-keys", "", "Comma-separated key name patterns to mask in addition to PASSWORD, PASSWD, TOKEN, SECRET and KEY")
+	var redactAllow repeatedFlag
+	flag.Var(&redactAllow, "redact-allow", "Regular expression for keys and values that are never masked; repeat the flag for several")
 	flag.Parse()
 
 	// Handle version flag
@@ -56,9 +76,45 @@ func main() {
 	// Create the discovery report
 	discoveryReport := collector.RunDiscovery(logger)
 
+	// Ask the cloud metadata service about this instance
+	if *cloudMetadata {
+		collector.QueryCloudMetadata(discoveryReport, collector.DefaultMetadataEndpoint, logger)
+	}
+
+	// Render Compose projects with overrides merged and variables interpolated
+	if *composeConfig {
+		collector.RenderComposeConfigs(discoveryReport, logger)
+	}
+
+	// Unpack and inspect containers that could not be read in place
+	if *containerExport {
+		collector.ExportContainerFilesystems(discoveryReport, logger)
+	}
+
+	// Match discovered packages against the offline vulnerability database
+	if *vulnDBDir != "" {
+		db, err := vuln.LoadDatabase(*vulnDBDir, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load vulnerability database: %v", err)
+		}
+		db.Scan(discoveryReport, logger)
+	}
+
+	// Mask secrets before anything is written to disk
+	if !*noRedact {
+		rules := redact.DefaultRules()
+		rules.KeyPatterns = append(rules.KeyPatterns, splitList(*redactKeys)...)
+		rules.AllowList = append(rules.AllowList, redactAllow...)
+		redactor, err := redact.New(rules)
+		if err != nil {
+			logger.Fatalf("Invalid redaction rules: %v", err)
+		}
+		redactor.Report(discoveryReport, logger)
+	}
+
 	// Determine output file name if not specified
 	if *outputFile == "" {
-		*outputFile = fmt.Sprintf("system_discovery_report.%s", *outputFormat)
+		*outputFile = report.DefaultFileName(*outputFormat)
 	}
 
 	// Write the report
@@ -68,3 +124,30 @@ func main() {
 
 	logger.Println("System discovery completed successfully")
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// repeatedFlag collects every value of a flag that may be given several times
+type repeatedFlag []string
+
+// String joins the collected values for flag's usage output
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+// Set records one occurrence of the flag
+func (f *repeatedFlag) Set(value string) error {
+	if value = strings.TrimSpace(value); value != "" {
+		*f = append(*f, value)
+	}
+	return nil
+}
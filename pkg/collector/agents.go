@@ -0,0 +1,415 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// agentDefinition describes how to find an agent and read its output endpoints
+type agentDefinition struct {
+	name        string
+	binaries    []string
+	services    []string
+	configPaths []string
+	versionArgs []string
+	endpoints   func(configFile string) []string
+}
+
+// knownAgents are the monitoring and log shipping agents looked for on every host
+var knownAgents = []agentDefinition{
+	{
+		name:        "Prometheus",
+		binaries:    []string{"prometheus"},
+		services:    []string{"prometheus"},
+		configPaths: []string{"/etc/prometheus/prometheus.yml", "/usr/local/etc/prometheus.yml"},
+		versionArgs: []string{"--version"},
+		endpoints: yamlEndpoints(
+			`^remote_write\.\d+\.url$`,
+			`^alerting\.alertmanagers\.\d+\.static_configs\.\d+\.targets\.\d+$`,
+		),
+	},
+	{
+		name:        "Grafana",
+		binaries:    []string{"grafana-server", "grafana"},
+		services:    []string{"grafana-server", "grafana"},
+		configPaths: []string{"/etc/grafana/grafana.ini", "/usr/local/etc/grafana/grafana.ini"},
+		versionArgs: []string{"-v"},
+		endpoints:   grafanaEndpoints,
+	},
+	{
+		name:        "Zabbix agent",
+		binaries:    []string{"zabbix_agentd"},
+		services:    []string{"zabbix-agent"},
+		configPaths: []string{"/etc/zabbix/zabbix_agentd.conf", "/usr/local/etc/zabbix_agentd.conf"},
+		versionArgs: []string{"-V"},
+		endpoints:   zabbixEndpoints,
+	},
+	{
+		name:        "Zabbix agent 2",
+		binaries:    []string{"zabbix_agent2"},
+		services:    []string{"zabbix-agent2"},
+		configPaths: []string{"/etc/zabbix/zabbix_agent2.conf", "/usr/local/etc/zabbix_agent2.conf"},
+		versionArgs: []string{"-V"},
+		endpoints:   zabbixEndpoints,
+	},
+	{
+		name:        "Datadog agent",
+		binaries:    []string{"datadog-agent", "/opt/datadog-agent/bin/agent/agent"},
+		services:    []string{"datadog-agent"},
+		configPaths: []string{"/etc/datadog-agent/datadog.yaml"},
+		versionArgs: []string{"version"},
+		endpoints:   datadogEndpoints,
+	},
+	{
+		name:        "Telegraf",
+		binaries:    []string{"telegraf"},
+		services:    []string{"telegraf"},
+		configPaths: []string{"/etc/telegraf/telegraf.conf", "/usr/local/etc/telegraf.conf"},
+		versionArgs: []string{"--version"},
+		endpoints:   telegrafEndpoints,
+	},
+	{
+		name:        "Filebeat",
+		binaries:    []string{"filebeat"},
+		services:    []string{"filebeat"},
+		configPaths: []string{"/etc/filebeat/filebeat.yml", "/usr/local/etc/filebeat/filebeat.yml"},
+		versionArgs: []string{"version"},
+		endpoints:   yamlEndpoints(`^output\.[^.]+\.hosts\.\d+$`, `^cloud\.id$`),
+	},
+	{
+		name:        "Fluent Bit",
+		binaries:    []string{"fluent-bit", "/opt/fluent-bit/bin/fluent-bit", "td-agent-bit"},
+		services:    []string{"fluent-bit", "td-agent-bit"},
+		configPaths: []string{"/etc/fluent-bit/fluent-bit.conf", "/etc/td-agent-bit/td-agent-bit.conf"},
+		versionArgs: []string{"--version"},
+		endpoints:   fluentBitEndpoints,
+	},
+	{
+		name:        "Vector",
+		binaries:    []string{"vector"},
+		services:    []string{"vector"},
+		configPaths: []string{"/etc/vector/vector.yaml", "/etc/vector/vector.yml"},
+		versionArgs: []string{"--version"},
+		endpoints:   yamlEndpoints(`^sinks\.[^.]+\.(endpoint|address|uri|endpoints\.\d+)$`),
+	},
+	{
+		name:        "OpenTelemetry Collector",
+		binaries:    []string{"otelcol", "otelcol-contrib", "otelcol-k8s"},
+		services:    []string{"otelcol", "otelcol-contrib"},
+		configPaths: []string{"/etc/otelcol/config.yaml", "/etc/otelcol-contrib/config.yaml"},
+		versionArgs: []string{"--version"},
+		endpoints:   yamlEndpoints(`^exporters\.[^.]+\.endpoint$`),
+	},
+}
+
+// exporterBinaryPattern matches Prometheus exporters such as node_exporter or prometheus-postgres-exporter
+var exporterBinaryPattern = regexp.MustCompile(`^(\w+_exporter|prometheus-[\w-]+-exporter)$`)
+
+// commandVersionPattern finds the first version number in a --version banner
+var commandVersionPattern = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?(?:[-+][\w.]+)?)`)
+
+// exporterDirs are searched for Prometheus exporter binaries
+var exporterDirs = []string{"/usr/bin", "/usr/sbin", "/usr/local/bin", "/usr/local/sbin", "/opt/prometheus"}
+
+// DetectAgents identifies monitoring, logging and telemetry agents
+func DetectAgents(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting monitoring agents")
+
+	for _, definition := range knownAgents {
+		binary := findAgentBinary(definition.binaries)
+		configFile := findExistingPath(definition.configPaths)
+		if binary == "" && configFile == "" {
+			continue
+		}
+
+		agent := model.Agent{
+			Name:       definition.name,
+			Binary:     binary,
+			ConfigFile: configFile,
+		}
+		if binary != "" {
+			agent.Version = commandVersion(binary, definition.versionArgs)
+		}
+		agent.Status = agentStatus(definition.services, logger)
+		if configFile != "" && definition.endpoints != nil {
+			agent.Endpoints = definition.endpoints(configFile)
+		}
+
+		report.Agents = append(report.Agents, agent)
+		logger.Printf("Detected %s: status=%s, version=%s", agent.Name, agent.Status, agent.Version)
+	}
+
+	// Exporters only serve metrics, so they have no output endpoints
+	for _, binary := range findExporterBinaries() {
+		name := filepath.Base(binary)
+		agent := model.Agent{
+			Name:    name,
+			Binary:  binary,
+			Version: commandVersion(binary, []string{"--version"}),
+			Status:  getServiceStatus(name, strings.ReplaceAll(name, "-", "_"), logger),
+		}
+		report.Agents = append(report.Agents, agent)
+		logger.Printf("Detected exporter %s: status=%s, version=%s", name, agent.Status, agent.Version)
+	}
+
+	logger.Printf("Detected %d agents", len(report.Agents))
+}
+
+// findAgentBinary returns the first binary found by name in PATH or by absolute path
+func findAgentBinary(binaries []string) string {
+	for _, binary := range binaries {
+		if filepath.IsAbs(binary) {
+			if pathExists(binary) {
+				return binary
+			}
+			continue
+		}
+		if path, err := exec.LookPath(binary); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// agentStatus checks each service name an agent is packaged under
+func agentStatus(services []string, logger *log.Logger) string {
+	status := ""
+	for _, service := range services {
+		status = getServiceStatus(service, "", logger)
+		if status == "Running" {
+			break
+		}
+	}
+	return status
+}
+
+// commandVersion runs a binary's version command and extracts the version number
+func commandVersion(binary string, args []string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), serviceStatusTimeout)
+	defer cancel()
+
+	// Some tools print their version to stderr
+	output, _ := exec.CommandContext(ctx, binary, args...).CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		if match := commandVersionPattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// findExporterBinaries lists Prometheus exporter executables in the usual install directories
+func findExporterBinaries() []string {
+	seen := make(map[string]bool)
+	var binaries []string
+	for _, dir := range exporterDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !exporterBinaryPattern.MatchString(entry.Name()) || seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			binaries = append(binaries, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(binaries)
+	return binaries
+}
+
+// yamlEndpoints returns an endpoint reader that collects values whose
+// flattened key path matches one of the patterns
+func yamlEndpoints(patterns ...string) func(string) []string {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		compiled = append(compiled, regexp.MustCompile(pattern))
+	}
+
+	return func(configFile string) []string {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil
+		}
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil
+		}
+
+		flat := make(map[string]string)
+		flattenYAML("", document, flat)
+
+		var endpoints []string
+		for key, value := range flat {
+			for _, pattern := range compiled {
+				if pattern.MatchString(key) {
+					endpoints = appendUnique(endpoints, value)
+					break
+				}
+			}
+		}
+		sort.Strings(endpoints)
+		return endpoints
+	}
+}
+
+// flattenYAML turns nested maps and lists into dotted keys. Keys that already
+// contain dots, as in Filebeat's "output.elasticsearch:", flatten to the same path.
+func flattenYAML(prefix string, value interface{}, flat map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			flattenYAML(join(key), child, flat)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			flattenYAML(join(fmt.Sprint(i)), child, flat)
+		}
+	case nil:
+	default:
+		flat[prefix] = fmt.Sprint(typed)
+	}
+}
+
+// grafanaEndpoints reports the database Grafana stores its state in
+func grafanaEndpoints(configFile string) []string {
+	database := parseINIFile(configFile)["database"]
+	if database["type"] == "" || database["type"] == "sqlite3" {
+		return nil
+	}
+	return []string{database["type"] + "://" + database["host"]}
+}
+
+// zabbixEndpoints reports the servers allowed to poll the agent and those it pushes active checks to
+func zabbixEndpoints(configFile string) []string {
+	entries, err := readKeyValueConfig(configFile, "=")
+	if err != nil {
+		return nil
+	}
+	var endpoints []string
+	for _, entry := range entries {
+		if entry[0] == "Server" || entry[0] == "ServerActive" {
+			for _, server := range strings.Split(entry[1], ",") {
+				endpoints = appendUnique(endpoints, strings.TrimSpace(server))
+			}
+		}
+	}
+	return endpoints
+}
+
+// datadogEndpoints reports the Datadog intake URL or site the agent sends to
+func datadogEndpoints(configFile string) []string {
+	endpoints := yamlEndpoints(`^dd_url$`, `^site$`, `^logs_config\.logs_dd_url$`)(configFile)
+	if len(endpoints) == 0 {
+		// The agent defaults to the US1 site
+		endpoints = []string{"datadoghq.com"}
+	}
+	return endpoints
+}
+
+// telegrafEndpoints reads url-like settings from [[outputs.*]] tables, including telegraf.d
+func telegrafEndpoints(configFile string) []string {
+	files := []string{configFile}
+	extra, _ := filepath.Glob(filepath.Join(filepath.Dir(configFile), "telegraf.d", "*.conf"))
+	files = append(files, extra...)
+
+	var endpoints []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		output := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				output = ""
+				if name, ok := strings.CutPrefix(strings.Trim(line, "[]"), "outputs."); ok {
+					output = name
+				}
+				continue
+			}
+			if output == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			switch strings.TrimSpace(key) {
+			case "url", "urls", "servers", "brokers", "address", "endpoint":
+				// urls = ["http://influx:8086"] or url = "http://..."
+				for _, item := range strings.Split(strings.Trim(strings.TrimSpace(value), "[]"), ",") {
+					if item = strings.Trim(strings.TrimSpace(item), "\"'"); item != "" {
+						endpoints = appendUnique(endpoints, output+": "+item)
+					}
+				}
+			}
+		}
+	}
+	return endpoints
+}
+
+// fluentBitEndpoints reads [OUTPUT] sections of the classic Fluent Bit configuration format
+func fluentBitEndpoints(configFile string) []string {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil
+	}
+
+	var endpoints []string
+	var current map[string]string
+	flush := func() {
+		if current == nil || current["name"] == "" {
+			return
+		}
+		endpoint := current["name"]
+		if host := current["host"]; host != "" {
+			endpoint += "://" + host
+			if port := current["port"]; port != "" {
+				endpoint += ":" + port
+			}
+		}
+		endpoints = appendUnique(endpoints, endpoint)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			flush()
+			current = nil
+			if strings.EqualFold(line, "[OUTPUT]") {
+				current = make(map[string]string)
+			}
+			continue
+		}
+		if current != nil {
+			if fields := strings.Fields(line); len(fields) >= 2 {
+				current[strings.ToLower(fields[0])] = fields[1]
+			}
+		}
+	}
+	flush()
+	return endpoints
+}
@@ -0,0 +1,238 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// kernelModuleDirs hold one directory per installed kernel
+var kernelModuleDirs = []string{"/lib/modules", "/usr/lib/modules"}
+
+// collectPlatformDetails records architecture and timezone, which every platform has
+func collectPlatformDetails(info *model.SystemInfo) {
+	info.Architecture = runtime.GOARCH
+	if runtime.GOOS != "windows" {
+		if output, err := exec.Command("uname", "-m").Output(); err == nil {
+			info.Architecture = strings.TrimSpace(string(output))
+		}
+	}
+	info.Timezone = localTimezone()
+}
+
+// localTimezone returns the IANA name of the system timezone, falling back to its abbreviation
+func localTimezone() string {
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":")
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		if tz := strings.TrimSpace(string(data)); tz != "" {
+			return tz
+		}
+	}
+	// /etc/localtime is usually a symlink into the zoneinfo database
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return name
+		}
+	}
+	name, _ := time.Now().Zone()
+	return name
+}
+
+// collectLinuxBootDetails records kernel modules and command line, boot time,
+// uptime, NTP state and whether a reboot is pending
+func collectLinuxBootDetails(info *model.SystemInfo, logger *log.Logger) {
+	if data, err := os.ReadFile("/proc/cmdline"); err == nil {
+		info.KernelCmdline = strings.TrimSpace(string(data))
+	}
+	info.KernelModules = readKernelModules(logger)
+
+	// /proc/uptime holds seconds since boot; /proc/stat's btime is the boot epoch
+	if data, err := os.ReadFile("/proc/uptime"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				info.UptimeSeconds = int64(seconds)
+				info.Uptime = humanizeDuration(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+	bootTime := readBootTime()
+	if !bootTime.IsZero() {
+		info.BootTime = bootTime.Format(time.RFC3339)
+	}
+
+	info.NTPSynchronized = ntpSynchronized()
+	info.RebootReasons = findRebootReasons(info.Kernel, bootTime)
+	info.RebootRequired = len(info.RebootReasons) > 0
+
+	logger.Printf("Boot details: uptime=%s, %d kernel modules, ntp_synchronized=%s, reboot_required=%t",
+		info.Uptime, len(info.KernelModules), info.NTPSynchronized, info.RebootRequired)
+}
+
+// readKernelModules parses /proc/modules: name size refcount users state address
+func readKernelModules(logger *log.Logger) []model.KernelModule {
+	file, err := os.Open("/proc/modules")
+	if err != nil {
+		// Kernels built without module support have no /proc/modules
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Printf("Error reading /proc/modules: %v", err)
+		}
+		return nil
+	}
+	defer file.Close()
+
+	var modules []model.KernelModule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		module := model.KernelModule{Name: fields[0], State: fields[4]}
+		module.Size, _ = strconv.Atoi(fields[1])
+		for _, user := range strings.Split(fields[3], ",") {
+			if user != "" && user != "-" {
+				module.UsedBy = append(module.UsedBy, user)
+			}
+		}
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+// readBootTime returns the boot time recorded in /proc/stat
+func readBootTime() time.Time {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			if epoch, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				return time.Unix(epoch, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// ntpSynchronized asks timedatectl, chrony or ntpstat whether the clock is
+// synchronised, returning "yes", "no" or "" when no time service answers
+func ntpSynchronized() string {
+	if output, err := exec.Command("timedatectl", "show", "-p", "NTPSynchronized", "--value").Output(); err == nil {
+		switch strings.TrimSpace(string(output)) {
+		case "yes":
+			return "yes"
+		case "no":
+			return "no"
+		}
+	}
+
+	if output, err := exec.Command("chronyc", "-n", "tracking").Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "Leap status" {
+				if strings.TrimSpace(value) == "Not synchronised" {
+					return "no"
+				}
+				return "yes"
+			}
+		}
+	}
+
+	// ntpstat exits 0 when synchronised, 1 when not and 2 when ntpd is unreachable
+	if _, err := exec.LookPath("ntpstat"); err == nil {
+		err := exec.Command("ntpstat").Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return "yes"
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+			return "no"
+		}
+	}
+	return ""
+}
+
+// findRebootReasons combines the Debian reboot-required flag, RHEL's
+// needs-restarting and a check for kernels installed since boot
+func findRebootReasons(runningKernel string, bootTime time.Time) []string {
+	var reasons []string
+
+	if pathExists("/var/run/reboot-required") {
+		reason := "/var/run/reboot-required is present"
+		if data, err := os.ReadFile("/var/run/reboot-required.pkgs"); err == nil {
+			if packages := strings.Fields(string(data)); len(packages) > 0 {
+				reason += " (" + strings.Join(packages, ", ") + ")"
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	// needs-restarting -r exits 1 when core libraries or the kernel were updated
+	if _, err := exec.LookPath("needs-restarting"); err == nil {
+		var exitErr *exec.ExitError
+		if err := exec.Command("needs-restarting", "-r").Run(); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			reasons = append(reasons, "needs-restarting reports updated core packages")
+		}
+	}
+
+	// Compare installed kernels with the running one
+	installed := false
+	checked := false
+	// On merged-/usr distributions /lib/modules and /usr/lib/modules are the same directory
+	seen := make(map[string]bool)
+	for _, dir := range kernelModuleDirs {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		checked = true
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if entry.Name() == runningKernel {
+				installed = true
+				continue
+			}
+			// Only kernels with a bootable image count; module trees linger after removal
+			if bootTime.IsZero() || !anyPathExists([]string{
+				filepath.Join("/boot", "vmlinuz-"+entry.Name()),
+				filepath.Join(dir, entry.Name(), "vmlinuz"),
+			}) {
+				continue
+			}
+			if stat, err := os.Stat(filepath.Join(dir, entry.Name())); err == nil && stat.ModTime().After(bootTime) {
+				reasons = append(reasons, fmt.Sprintf("kernel %s was installed after the last boot", entry.Name()))
+			}
+		}
+	}
+	if checked && !installed && runningKernel != "" && runningKernel != "unknown" && pathExists("/boot") && hasKernelImages() {
+		reasons = append(reasons, fmt.Sprintf("running kernel %s is no longer installed", runningKernel))
+	}
+
+	return reasons
+}
+
+// hasKernelImages reports whether kernels are installed from packages. Containers
+// and custom-booted VMs have none, so a missing module tree means nothing there.
+func hasKernelImages() bool {
+	images, _ := filepath.Glob("/boot/vmlinuz-*")
+	return len(images) > 0
+}
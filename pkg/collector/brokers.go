@@ -0,0 +1,654 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// Install locations searched for Kafka and ZooKeeper configuration when no
+// running process or systemd unit names the file in use. Kafka distributions
+// ship several sample configurations, including a zookeeper.properties, so
+// only the first one per install is reported and the ZooKeeper sample is not.
+var (
+	kafkaConfigPatterns = []string{
+		"/etc/kafka/server.properties",
+		"/etc/kafka/kraft/server.properties",
+		"/opt/kafka*/config/server.properties",
+		"/opt/kafka*/config/kraft/server.properties",
+		"/usr/local/kafka*/config/server.properties",
+	}
+	zookeeperConfigPatterns = []string{
+		"/etc/zookeeper/conf/zoo.cfg",
+		"/etc/zookeeper/zoo.cfg",
+		"/opt/zookeeper*/conf/zoo.cfg",
+	}
+)
+
+// kafkaLaunchers and zookeeperLaunchers are the Java main classes and start
+// scripts that take the configuration file as their first argument
+var (
+	kafkaLaunchers = []string{
+		"kafka.Kafka",
+		"io.confluent.support.metrics.SupportedKafka",
+		"kafka-server-start.sh",
+		"kafka-server-start",
+	}
+	zookeeperLaunchers = []string{
+		"org.apache.zookeeper.server.quorum.QuorumPeerMain",
+		"org.apache.zookeeper.server.ZooKeeperServerMain",
+		"zookeeper-server-start.sh",
+		"zookeeper-server-start",
+	}
+)
+
+// Where a broker's configuration file was found, as reported in ConfigSource
+const (
+	configFromProcess = "process"
+	configFromUnit    = "systemd unit"
+	configFromDefault = "install default"
+)
+
+// launchedConfig is a configuration file together with where it was found
+// and the status of the broker using it
+type launchedConfig struct {
+	path   string
+	source string
+	status string
+}
+
+// jarVersionPattern extracts the version from jar names such as kafka_2.13-3.6.1.jar
+var jarVersionPattern = regexp.MustCompile(`-(\d+\.\d+\.\d+[\w.-]*)\.jar$`)
+
+// DetectMessageBrokers identifies message brokers and streaming platforms
+func DetectMessageBrokers(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting message brokers")
+
+	// Detect RabbitMQ
+	detectRabbitMQ(report, logger)
+
+	// Detect Kafka brokers and ZooKeeper; their start commands name the configuration in use
+	units := findSystemdUnits(".service", logger)
+	detectKafka(report, units, logger)
+	detectZooKeeper(report, units, logger)
+
+	// Detect Mosquitto
+	detectMosquitto(report, logger)
+
+	// Detect NATS
+	detectNATS(report, logger)
+
+	logger.Printf("Detected %d message brokers", len(report.MessageBrokers))
+}
+
+// detectRabbitMQ reads rabbitmq.conf, enabled_plugins and rabbitmq-env.conf
+func detectRabbitMQ(report *model.DiscoveryReport, logger *log.Logger) {
+	configDir := findExistingPath([]string{"/etc/rabbitmq", "/usr/local/etc/rabbitmq"})
+	if _, err := exec.LookPath("rabbitmq-server"); err != nil && configDir == "" {
+		logger.Println("RabbitMQ not found")
+		return
+	}
+	if configDir == "" {
+		configDir = "/etc/rabbitmq"
+	}
+
+	broker := model.MessageBroker{
+		Type:       "RabbitMQ",
+		Status:     getServiceStatus("rabbitmq-server", "rabbitmq", logger),
+		ConfigFile: filepath.Join(configDir, "rabbitmq.conf"),
+	}
+
+	// The server directory is named after the release, e.g. rabbitmq_server-3.12.1
+	servers, _ := filepath.Glob("/usr/lib/rabbitmq/lib/rabbitmq_server-*")
+	if len(servers) > 0 {
+		sort.Strings(servers)
+		broker.Version = strings.TrimPrefix(filepath.Base(servers[len(servers)-1]), "rabbitmq_server-")
+	}
+
+	// enabled_plugins is an Erlang list: [rabbitmq_management,rabbitmq_prometheus].
+	if data, err := os.ReadFile(filepath.Join(configDir, "enabled_plugins")); err == nil {
+		list := strings.Trim(strings.TrimSpace(string(data)), "[].")
+		for _, plugin := range strings.Split(list, ",") {
+			if plugin = strings.TrimSpace(plugin); plugin != "" {
+				broker.Plugins = append(broker.Plugins, plugin)
+			}
+		}
+	}
+
+	// rabbitmq.conf uses the sysctl-like "key = value" format
+	config := parseJavaProperties(broker.ConfigFile)
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, "listeners.tcp."), strings.HasPrefix(key, "listeners.ssl."):
+			broker.Listeners = append(broker.Listeners, strings.Split(key, ".")[1]+":"+config[key])
+		case key == "management.tcp.port", key == "management.ssl.port":
+			broker.Listeners = append(broker.Listeners, "management:"+config[key])
+		}
+	}
+	if len(broker.Listeners) == 0 {
+		broker.Listeners = []string{"tcp:5672"}
+	}
+	if config["management.tcp.port"] == "" && containsString(broker.Plugins, "rabbitmq_management") {
+		broker.Listeners = append(broker.Listeners, "management:15672")
+	}
+
+	// rabbitmq-env.conf names variables without the RABBITMQ_ prefix
+	env := parseShellAssignments(filepath.Join(configDir, "rabbitmq-env.conf"))
+	dataDir := env["MNESIA_BASE"]
+	if dataDir == "" {
+		dataDir = "/var/lib/rabbitmq/mnesia"
+	}
+	broker.DataDirectories = []string{dataDir}
+
+	report.MessageBrokers = append(report.MessageBrokers, broker)
+	logger.Printf("Detected RabbitMQ: status=%s, version=%s, %d plugins", broker.Status, broker.Version, len(broker.Plugins))
+}
+
+// detectKafka reads the server.properties of each running or configured Kafka
+// broker, falling back to the first configuration of each install found
+func detectKafka(report *model.DiscoveryReport, units []*systemdUnit, logger *log.Logger) {
+	configs := findLaunchedConfigs(kafkaLaunchers, units, logger)
+	if len(configs) == 0 {
+		status := getServiceStatus("kafka", "", logger)
+		installs := make(map[string]bool)
+		for _, configFile := range globAll(kafkaConfigPatterns) {
+			if libDir := kafkaLibDir(configFile); !installs[libDir] {
+				installs[libDir] = true
+				configs = append(configs, launchedConfig{path: configFile, source: configFromDefault, status: status})
+			}
+		}
+	}
+	if len(configs) == 0 {
+		logger.Println("Kafka not found")
+		return
+	}
+
+	for _, launched := range configs {
+		configFile := launched.path
+		config := parseJavaProperties(configFile)
+
+		broker := model.MessageBroker{
+			Type:         "Kafka",
+			Status:       launched.status,
+			Version:      jarVersion(kafkaLibDir(configFile), "kafka_*.jar"),
+			ConfigFile:   configFile,
+			ConfigSource: launched.source,
+			BrokerID:     config["broker.id"],
+		}
+		// KRaft mode identifies nodes by node.id and has no ZooKeeper
+		if broker.BrokerID == "" {
+			broker.BrokerID = config["node.id"]
+		}
+		if roles := config["process.roles"]; roles != "" {
+			broker.Type = "Kafka (KRaft " + roles + ")"
+		}
+
+		broker.Listeners = splitMailList(config["listeners"])
+		if len(broker.Listeners) == 0 {
+			broker.Listeners = []string{"PLAINTEXT://:9092"}
+		}
+		logDirs := config["log.dirs"]
+		if logDirs == "" {
+			logDirs = config["log.dir"]
+		}
+		if logDirs == "" {
+			logDirs = "/tmp/kafka-logs"
+		}
+		broker.DataDirectories = strings.Split(logDirs, ",")
+		broker.Cluster = splitMailList(config["zookeeper.connect"])
+		if voters := config["controller.quorum.voters"]; voters != "" {
+			broker.Cluster = strings.Split(voters, ",")
+		}
+
+		report.MessageBrokers = append(report.MessageBrokers, broker)
+		logger.Printf("Detected Kafka: config=%s (%s), broker.id=%s", configFile, launched.source, broker.BrokerID)
+	}
+}
+
+// findLaunchedConfigs returns the configuration files passed to a launcher on
+// the command line of a running process or in the ExecStart of a systemd unit
+func findLaunchedConfigs(launchers []string, units []*systemdUnit, logger *log.Logger) []launchedConfig {
+	var configs []launchedConfig
+	seen := make(map[string]bool)
+	add := func(path, source, status string) {
+		// Files that do not exist on the host belong to processes in containers
+		if path != "" && !seen[path] && pathExists(path) {
+			seen[path] = true
+			configs = append(configs, launchedConfig{path: path, source: source, status: status})
+		}
+	}
+
+	cmdlines, _ := filepath.Glob("/proc/[0-9]*/cmdline")
+	for _, cmdline := range cmdlines {
+		data, err := os.ReadFile(cmdline)
+		if err != nil {
+			continue
+		}
+		config := launcherArgument(strings.Split(strings.TrimRight(string(data), "\x00"), "\x00"), launchers)
+		if config != "" && !filepath.IsAbs(config) {
+			cwd, err := os.Readlink(filepath.Join(filepath.Dir(cmdline), "cwd"))
+			if err != nil {
+				continue
+			}
+			config = filepath.Join(cwd, config)
+		}
+		add(config, configFromProcess, "Running")
+	}
+
+	for _, unit := range units {
+		for _, execStart := range unit.getAll("Service", "ExecStart") {
+			config := launcherArgument(strings.Fields(execStart), launchers)
+			if config != "" && !filepath.IsAbs(config) {
+				config = filepath.Join(unit.get("Service", "WorkingDirectory"), config)
+			}
+			if config != "" && !seen[config] {
+				add(config, configFromUnit, getServiceStatus(strings.TrimSuffix(unit.name, ".service"), "", logger))
+			}
+		}
+	}
+	return configs
+}
+
+// launcherArgument returns the first non-option argument following a launcher
+// main class or start script, e.g. the file in "kafka-server-start.sh -daemon server.properties"
+func launcherArgument(args []string, launchers []string) string {
+	for i, arg := range args {
+		if !containsString(launchers, arg) && !containsString(launchers, filepath.Base(arg)) {
+			continue
+		}
+		for _, next := range args[i+1:] {
+			if !strings.HasPrefix(next, "-") {
+				return next
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// kafkaLibDir returns the libs directory of the Kafka install owning a config file
+func kafkaLibDir(configFile string) string {
+	dir := filepath.Dir(configFile)
+	if filepath.Base(dir) == "kraft" {
+		dir = filepath.Dir(dir)
+	}
+	if filepath.Base(dir) == "config" {
+		return filepath.Join(filepath.Dir(dir), "libs")
+	}
+	// Packaged installs keep jars apart from /etc
+	return "/usr/share/java/kafka"
+}
+
+// detectZooKeeper reads the zoo.cfg or zookeeper.properties of each running or
+// configured ZooKeeper, falling back to the zoo.cfg of standalone installs
+func detectZooKeeper(report *model.DiscoveryReport, units []*systemdUnit, logger *log.Logger) {
+	configs := findLaunchedConfigs(zookeeperLaunchers, units, logger)
+	if len(configs) == 0 {
+		status := getServiceStatus("zookeeper", "", logger)
+		for _, configFile := range globAll(zookeeperConfigPatterns) {
+			configs = append(configs, launchedConfig{path: configFile, source: configFromDefault, status: status})
+		}
+	}
+	if len(configs) == 0 {
+		logger.Println("ZooKeeper not found")
+		return
+	}
+
+	for _, launched := range configs {
+		configFile := launched.path
+		config := parseJavaProperties(configFile)
+
+		broker := model.MessageBroker{
+			Type:         "ZooKeeper",
+			Status:       launched.status,
+			ConfigFile:   configFile,
+			ConfigSource: launched.source,
+		}
+		// zoo.cfg lives in conf/ next to lib/; Kafka's copy shares Kafka's libs/
+		installDir := filepath.Dir(filepath.Dir(configFile))
+		broker.Version = jarVersion(filepath.Join(installDir, "lib"), "zookeeper-*.jar")
+		if broker.Version == "" {
+			broker.Version = jarVersion(filepath.Join(installDir, "libs"), "zookeeper-*.jar")
+		}
+		if broker.Version == "" {
+			broker.Version = jarVersion("/usr/share/java", "zookeeper-*.jar")
+		}
+
+		clientPort := config["clientPort"]
+		if clientPort == "" {
+			clientPort = "2181"
+		}
+		broker.Listeners = []string{"client:" + clientPort}
+		if securePort := config["secureClientPort"]; securePort != "" {
+			broker.Listeners = append(broker.Listeners, "secure-client:"+securePort)
+		}
+
+		for _, key := range []string{"dataDir", "dataLogDir"} {
+			if dir := config[key]; dir != "" {
+				broker.DataDirectories = appendUnique(broker.DataDirectories, dir)
+			}
+		}
+
+		// Ensemble members are listed as server.N=host:peerPort:electionPort
+		var members []string
+		for key, value := range config {
+			if strings.HasPrefix(key, "server.") {
+				members = append(members, key+"="+value)
+			}
+		}
+		sort.Strings(members)
+		broker.Cluster = members
+
+		report.MessageBrokers = append(report.MessageBrokers, broker)
+		logger.Printf("Detected ZooKeeper: config=%s (%s), %d ensemble members", configFile, launched.source, len(members))
+	}
+}
+
+// detectMosquitto reads mosquitto.conf and its include_dir for listeners and persistence
+func detectMosquitto(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/mosquitto/mosquitto.conf", "/usr/local/etc/mosquitto/mosquitto.conf"})
+	if _, err := exec.LookPath("mosquitto"); err != nil && configFile == "" {
+		logger.Println("Mosquitto not found")
+		return
+	}
+
+	broker := model.MessageBroker{
+		Type:       "Mosquitto",
+		Status:     getServiceStatus("mosquitto", "", logger),
+		ConfigFile: configFile,
+	}
+
+	// "mosquitto -h" prints "mosquitto version 2.0.18" and exits non-zero
+	if output, _ := exec.Command("mosquitto", "-h").CombinedOutput(); len(output) > 0 {
+		if fields := strings.Fields(strings.SplitN(string(output), "\n", 2)[0]); len(fields) == 3 && fields[1] == "version" {
+			broker.Version = fields[2]
+		}
+	}
+
+	persistence := false
+	for _, line := range readMosquittoConfig(configFile, 0) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "listener":
+			// listener <port> [bind address/host]
+			listener := fields[1]
+			if len(fields) > 2 {
+				listener = fields[2] + ":" + fields[1]
+			}
+			broker.Listeners = append(broker.Listeners, listener)
+		case "port":
+			broker.Listeners = append(broker.Listeners, fields[1])
+		case "persistence":
+			persistence = fields[1] == "true"
+		case "persistence_location":
+			broker.DataDirectories = []string{fields[1]}
+		}
+	}
+
+	// Mosquitto 2.0 only listens on localhost when no listener is configured
+	if len(broker.Listeners) == 0 {
+		broker.Listeners = []string{"localhost:1883"}
+	}
+	if !persistence {
+		broker.DataDirectories = nil
+	}
+
+	report.MessageBrokers = append(report.MessageBrokers, broker)
+	logger.Printf("Detected Mosquitto: status=%s, version=%s", broker.Status, broker.Version)
+}
+
+// readMosquittoConfig returns config lines with include_dir directories expanded
+func readMosquittoConfig(path string, depth int) []string {
+	data, err := os.ReadFile(path)
+	if err != nil || depth > 4 {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if dir, ok := strings.CutPrefix(line, "include_dir "); ok {
+			// Files are read in alphabetical order, as Mosquitto does
+			files, _ := filepath.Glob(filepath.Join(strings.TrimSpace(dir), "*.conf"))
+			for _, file := range files {
+				lines = append(lines, readMosquittoConfig(file, depth+1)...)
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// detectNATS reads the NATS server configuration for client, cluster and monitoring ports and JetStream storage
+func detectNATS(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/nats-server.conf", "/etc/nats/nats-server.conf", "/etc/nats/nats.conf", "/usr/local/etc/nats-server.conf"})
+	if _, err := exec.LookPath("nats-server"); err != nil && configFile == "" {
+		logger.Println("NATS not found")
+		return
+	}
+
+	broker := model.MessageBroker{
+		Type:       "NATS",
+		Status:     getServiceStatus("nats-server", "nats", logger),
+		ConfigFile: configFile,
+	}
+
+	// "nats-server --version" prints "nats-server: v2.10.7"
+	if output, err := exec.Command("nats-server", "--version").Output(); err == nil {
+		if _, version, ok := strings.Cut(strings.TrimSpace(string(output)), ": "); ok {
+			broker.Version = strings.TrimPrefix(version, "v")
+		}
+	}
+
+	settings := parseNATSConfig(configFile)
+	clientListener := settings["listen"]
+	if clientListener == "" {
+		port := settings["port"]
+		if port == "" {
+			port = "4222"
+		}
+		clientListener = settings["host"] + ":" + port
+		if settings["host"] == "" {
+			clientListener = "0.0.0.0:" + port
+		}
+	}
+	broker.Listeners = []string{"client:" + clientListener}
+
+	for _, listener := range []struct{ name, key string }{
+		{"monitoring", "http_port"},
+		{"monitoring", "http"},
+		{"monitoring-tls", "https_port"},
+		{"cluster", "cluster.listen"},
+		{"cluster", "cluster.port"},
+		{"leafnodes", "leafnodes.listen"},
+		{"leafnodes", "leafnodes.port"},
+		{"gateway", "gateway.port"},
+		{"websocket", "websocket.port"},
+		{"mqtt", "mqtt.port"},
+	} {
+		if value := settings[listener.key]; value != "" {
+			broker.Listeners = append(broker.Listeners, listener.name+":"+value)
+		}
+	}
+
+	for key, value := range settings {
+		if strings.HasPrefix(key, "cluster.routes") {
+			broker.Cluster = append(broker.Cluster, value)
+		}
+	}
+	sort.Strings(broker.Cluster)
+
+	if storeDir := settings["jetstream.store_dir"]; storeDir != "" {
+		broker.DataDirectories = []string{storeDir}
+	} else if settings["jetstream"] != "" && settings["jetstream"] != "disabled" && settings["jetstream"] != "false" {
+		broker.DataDirectories = []string{filepath.Join(os.TempDir(), "nats", "jetstream")}
+	}
+
+	report.MessageBrokers = append(report.MessageBrokers, broker)
+	logger.Printf("Detected NATS: status=%s, version=%s", broker.Status, broker.Version)
+}
+
+// parseNATSConfig flattens the NATS configuration format into dotted keys.
+// Values may be separated by ":", "=" or whitespace; "name { ... }" opens a
+// block and array items are keyed as name.N.
+func parseNATSConfig(path string) map[string]string {
+	settings := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings
+	}
+
+	var blocks []string
+	arrayKey := ""
+	arrayIndex := 0
+	prefix := func() string {
+		if len(blocks) == 0 {
+			return ""
+		}
+		return strings.Join(blocks, ".") + "."
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+		if strings.HasPrefix(line, "//") || line == "" {
+			continue
+		}
+
+		if arrayKey != "" {
+			if strings.HasPrefix(line, "]") {
+				arrayKey = ""
+				continue
+			}
+			settings[fmt.Sprintf("%s%s.%d", prefix(), arrayKey, arrayIndex)] = strings.Trim(strings.TrimSuffix(line, ","), "\"")
+			arrayIndex++
+			continue
+		}
+		if line == "}" {
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			continue
+		}
+
+		key, value := splitNATSLine(line)
+		switch {
+		case value == "{":
+			settings[prefix()+key] = "enabled"
+			blocks = append(blocks, key)
+		case value == "[":
+			arrayKey, arrayIndex = key, 0
+		case strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}"):
+			// Single-line block: jetstream { store_dir: "/data" }
+			settings[prefix()+key] = "enabled"
+			for _, inner := range strings.Split(strings.Trim(value, "{}"), ",") {
+				if innerKey, innerValue := splitNATSLine(strings.TrimSpace(inner)); innerKey != "" {
+					settings[prefix()+key+"."+innerKey] = innerValue
+				}
+			}
+		default:
+			settings[prefix()+key] = value
+		}
+	}
+	return settings
+}
+
+// splitNATSLine splits "key: value", "key = value" or "key value" and unquotes the value
+func splitNATSLine(line string) (string, string) {
+	end := strings.IndexAny(line, ":= \t{[")
+	if end < 0 {
+		return line, ""
+	}
+	key := line[:end]
+	value := strings.TrimLeft(line[end:], ":= \t")
+	return key, strings.Trim(strings.TrimSuffix(value, ","), "\"")
+}
+
+// parseJavaProperties reads a .properties file: "key=value" or "key: value",
+// "#" and "!" comments, and trailing backslash continuations
+func parseJavaProperties(path string) map[string]string {
+	properties := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return properties
+	}
+
+	pending := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		line = pending + line
+		pending = ""
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		end := strings.IndexAny(line, "=:")
+		if end < 0 {
+			continue
+		}
+		properties[strings.TrimSpace(line[:end])] = strings.TrimSpace(line[end+1:])
+	}
+	return properties
+}
+
+// jarVersion returns the version in the name of the first jar matching a pattern
+func jarVersion(dir, pattern string) string {
+	jars, _ := filepath.Glob(filepath.Join(dir, pattern))
+	for _, jar := range jars {
+		// Skip auxiliary jars such as kafka_2.13-3.6.1-test.jar
+		if strings.Contains(jar, "-test") || strings.Contains(jar, "-sources") || strings.Contains(jar, "-javadoc") {
+			continue
+		}
+		if match := jarVersionPattern.FindStringSubmatch(filepath.Base(jar)); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// globAll expands each pattern and returns the matching paths without duplicates
+func globAll(patterns []string) []string {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			paths = appendUnique(paths, match)
+		}
+	}
+	return paths
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"syscall"
+)
+
+// chroot changes the root directory of the whole process to dir
+func chroot(dir string) error {
+	if err := syscall.Chroot(dir); err != nil {
+		return err
+	}
+	return os.Chdir("/")
+}
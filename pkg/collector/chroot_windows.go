@@ -0,0 +1,10 @@
+//go:build windows
+
+package collector
+
+import "errors"
+
+// chroot is not available on Windows, where Linux containers run in a VM
+func chroot(dir string) error {
+	return errors.New("container filesystem inspection is not supported on Windows")
+}
@@ -14,14 +14,59 @@ func RunDiscovery(logger *log.Logger) *model.DiscoveryReport {
 	// Collect system information
 	CollectSystemInfo(report, logger)
 
+	// Detect virtualization, containers and cloud provider
+	DetectPlatform(report, logger)
+
 	// Detect web servers
 	DetectWebServers(report, logger)
 
+	// Describe and audit the SSH daemon
+	DetectSSHServer(report, logger)
+
+	// Detect mail transfer agents and IMAP/POP3 servers
+	DetectMailServers(report, logger)
+
 	// Detect databases
 	DetectDatabases(report, logger)
 
+	// Detect DNS, DHCP, directory and file sharing services
+	DetectInfrastructureServices(report, logger)
+
+	// Detect message brokers and streaming platforms
+	DetectMessageBrokers(report, logger)
+
+	// Detect monitoring, logging and telemetry agents
+	DetectAgents(report, logger)
+
 	// Detect Docker containers
 	DetectDockerContainers(report, logger)
 
+	// Inventory services across all service managers
+	DetectServices(report, logger)
+
+	// Detect cron jobs, timers and other scheduled work
+	DetectScheduledJobs(report, logger)
+
+	// Inventory local users, groups, sudo rules and SSH keys
+	DetectUsers(report, logger)
+
+	// Report SELinux, AppArmor and kernel hardening settings
+	DetectSecurity(report, logger)
+
+	// Find listening sockets and the processes that own them
+	DetectListeners(report, logger)
+
+	// Inventory firewall rules and mark which listeners they expose
+	DetectFirewall(report, logger)
+
+	// Inventory packages installed by the distribution's package manager
+	DetectOSPackages(report, logger)
+
+	// Detect language runtimes and toolchains
+	DetectRuntimes(report, logger)
+
+	// Detect language-level packages (uses web server document roots)
+	DetectLanguagePackages(report, logger)
+
 	return report
 }
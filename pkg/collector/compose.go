@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// composeFileNames are the default compose files in the order Compose looks for them
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// composeCommand returns the command that runs Docker Compose: the v2 plugin
+// when it is available, otherwise a standalone docker-compose binary, or nil
+func composeCommand() []string {
+	if exec.Command("docker", "compose", "version").Run() == nil {
+		return []string{"docker", "compose"}
+	}
+	if _, err := exec.LookPath("docker-compose"); err == nil {
+		return []string{"docker-compose"}
+	}
+	return nil
+}
+
+// runCompose runs a Docker Compose subcommand and returns its output
+func runCompose(compose []string, args ...string) ([]byte, error) {
+	fullArgs := append(append([]string{}, compose[1:]...), args...)
+	return exec.Command(compose[0], fullArgs...).Output()
+}
+
+// composeConfigFiles returns every compose file recorded in a container's
+// labels, including -f overrides, in the order they were applied
+func composeConfigFiles(labels map[string]string) []string {
+	workingDir := labels["com.docker.compose.project.working_dir"]
+
+	var files []string
+	for _, file := range strings.Split(labels["com.docker.compose.project.config_files"], ",") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		if !filepath.IsAbs(file) && workingDir != "" {
+			file = filepath.Join(workingDir, file)
+		}
+		files = appendUnique(files, file)
+	}
+	if len(files) > 0 || workingDir == "" {
+		return files
+	}
+
+	// Compose releases before 1.25 did not record their files, so look for the defaults
+	var candidates []string
+	for _, name := range composeFileNames {
+		candidates = append(candidates, filepath.Join(workingDir, name))
+	}
+	if file := findExistingPath(candidates); file != "" {
+		return []string{file}
+	}
+	return nil
+}
+
+// detectComposeProjects lists Compose projects from docker compose ls and
+// from the labels of running containers
+func detectComposeProjects(report *model.DiscoveryReport, compose []string, logger *log.Logger) {
+	projects := make(map[string]*model.ComposeProject)
+	project := func(name string) *model.ComposeProject {
+		if projects[name] == nil {
+			projects[name] = &model.ComposeProject{Name: name, ConfigFiles: []string{}}
+		}
+		return projects[name]
+	}
+
+	// --all includes stopped projects
+	output, err := runCompose(compose, "ls", "--all", "--format", "json")
+	if err != nil {
+		logger.Printf("Error listing Docker Compose projects: %v", err)
+	} else {
+		var listed []struct {
+			Name        string `json:"Name"`
+			Status      string `json:"Status"`
+			ConfigFiles string `json:"ConfigFiles"`
+		}
+		if err := json.Unmarshal(output, &listed); err != nil {
+			logger.Printf("Error parsing Docker Compose project list: %v", err)
+		}
+		for _, entry := range listed {
+			composeProject := project(entry.Name)
+			composeProject.Status = entry.Status
+			for _, file := range strings.Split(entry.ConfigFiles, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					composeProject.ConfigFiles = appendUnique(composeProject.ConfigFiles, file)
+				}
+			}
+		}
+	}
+
+	// Running containers also cover projects started by Compose v1
+	for _, container := range report.DockerContainers {
+		if container.ManagedBy != "docker-compose" || container.ComposeProject == "" {
+			continue
+		}
+		composeProject := project(container.ComposeProject)
+		if composeProject.WorkingDir == "" {
+			composeProject.WorkingDir = container.Labels["com.docker.compose.project.working_dir"]
+		}
+		for _, file := range container.ComposeFiles {
+			composeProject.ConfigFiles = appendUnique(composeProject.ConfigFiles, file)
+		}
+		if container.ComposeService != "" {
+			composeProject.Services = appendUnique(composeProject.Services, container.ComposeService)
+		}
+	}
+
+	var names []string
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		composeProject := projects[name]
+		if composeProject.WorkingDir == "" && len(composeProject.ConfigFiles) > 0 {
+			composeProject.WorkingDir = filepath.Dir(composeProject.ConfigFiles[0])
+		}
+		sort.Strings(composeProject.Services)
+		report.ComposeProjects = append(report.ComposeProjects, *composeProject)
+		logger.Printf("Detected Docker Compose project %s: status=%s, files=%s", name, composeProject.Status, strings.Join(composeProject.ConfigFiles, ","))
+	}
+}
+
+// RenderComposeConfigs captures the fully resolved configuration of each
+// Compose project, with overrides merged and variables interpolated
+func RenderComposeConfigs(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Rendering Docker Compose project configurations")
+
+	compose := composeCommand()
+	if compose == nil {
+		logger.Println("Docker Compose is not installed")
+		return
+	}
+
+	rendered := 0
+	for i := range report.ComposeProjects {
+		composeProject := &report.ComposeProjects[i]
+		if len(composeProject.ConfigFiles) == 0 {
+			continue
+		}
+
+		args := []string{"--project-name", composeProject.Name}
+		if composeProject.WorkingDir != "" {
+			args = append(args, "--project-directory", composeProject.WorkingDir)
+		}
+		for _, file := range composeProject.ConfigFiles {
+			args = append(args, "--file", file)
+		}
+		output, err := runCompose(compose, append(args, "config")...)
+		if err != nil {
+			logger.Printf("Error rendering Docker Compose project %s: %v", composeProject.Name, err)
+			continue
+		}
+		composeProject.ResolvedConfig = string(output)
+		rendered++
+	}
+
+	logger.Printf("Rendered %d Docker Compose projects", rendered)
+}
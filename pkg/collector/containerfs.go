@@ -0,0 +1,451 @@
+package collector
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// containerWebServers are recognised inside container filesystems by their
+// binaries; nothing from a container image is ever executed
+var containerWebServers = []struct {
+	name        string
+	binaries    []string
+	configPaths []string
+}{
+	{"Apache", []string{"/usr/sbin/apache2", "/usr/sbin/httpd", "/usr/local/apache2/bin/httpd"}, apacheConfigPaths},
+	{"Nginx", []string{"/usr/sbin/nginx", "/usr/local/nginx/sbin/nginx"}, nginxConfigPaths},
+	{"Lighttpd", []string{"/usr/sbin/lighttpd"}, lighttpdConfigPaths},
+	{"Caddy", []string{"/usr/bin/caddy", "/usr/local/bin/caddy"}, caddyConfigPaths},
+}
+
+// Limits for the opt-in docker export, which unpacks the container to disk
+const (
+	maxExportFileSize  = 256 << 20
+	maxExportTotalSize = 4 << 30
+)
+
+// containerRPMDatabases are the rpm database files of RHEL-based images. They
+// can only be read through the image's own rpm, which is never executed, so
+// their packages are not inventoried.
+var containerRPMDatabases = []string{
+	"/var/lib/rpm/rpmdb.sqlite",
+	"/var/lib/rpm/Packages",
+	"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+}
+
+// ContainerInspectionEnv is set when the discovery binary re-executes itself
+// to inspect a container filesystem from inside a chroot
+const ContainerInspectionEnv = "GO_DISCOVERY_INSPECT_ROOT"
+
+// containerInspectionTimeout bounds the inspection of one container filesystem
+const containerInspectionTimeout = 5 * time.Minute
+
+// inspectContainerFilesystem runs the OS, web server and package collectors
+// against the overlay2 merged directory of a running container
+func inspectContainerFilesystem(report *model.DiscoveryReport, container *model.DockerContainer, driver dockerGraphDriver, logger *log.Logger) {
+	// The merged directory is missing on Docker Desktop and when the daemon runs in another mount namespace
+	merged := driver.Data["MergedDir"]
+	if driver.Name != "overlay2" || merged == "" || !pathExists(merged) {
+		logger.Printf("Merged directory of container %s not reachable (driver %s), use -container-export to inspect a docker export copy", container.Name, driver.Name)
+		return
+	}
+	inspectContainerRoot(report, container, merged, "overlay2", logger)
+}
+
+// ExportContainerFilesystems inspects the containers whose merged directory
+// was not reachable from a docker export copy. Each copy is unpacked to the
+// temporary directory, up to 4 GiB per container, and removed afterwards.
+func ExportContainerFilesystems(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Exporting container filesystems")
+
+	exported := 0
+	for i := range report.DockerContainers {
+		container := &report.DockerContainers[i]
+		if container.Filesystem != nil {
+			continue
+		}
+
+		dir, err := os.MkdirTemp("", "discovery-"+container.ContainerID+"-")
+		if err != nil {
+			logger.Printf("Error creating export directory for container %s: %v", container.Name, err)
+			continue
+		}
+		if err := exportContainer(container.ContainerID, dir); err != nil {
+			logger.Printf("Error exporting container %s: %v", container.Name, err)
+		} else {
+			inspectContainerRoot(report, container, dir, "export", logger)
+			exported++
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Printf("Error removing %s: %v", dir, err)
+		}
+	}
+
+	logger.Printf("Exported %d container filesystems", exported)
+}
+
+// inspectContainerRoot collects a container filesystem found at root on the
+// host. The collectors run in a re-executed copy of this binary chrooted to
+// root, so symlinks in the container resolve within it and never reach the host.
+func inspectContainerRoot(report *model.DiscoveryReport, container *model.DockerContainer, root, method string, logger *log.Logger) {
+	executable, err := os.Executable()
+	if err != nil {
+		logger.Printf("Error locating discovery binary: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerInspectionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Env = []string{ContainerInspectionEnv + "=" + root}
+	cmd.Stderr = logger.Writer()
+	output, err := cmd.Output()
+	if err != nil {
+		logger.Printf("Error inspecting filesystem of container %s: %v", container.Name, err)
+		return
+	}
+
+	filesystem := &model.ContainerFilesystem{}
+	if err := json.Unmarshal(output, filesystem); err != nil {
+		logger.Printf("Error parsing filesystem inspection of container %s: %v", container.Name, err)
+		return
+	}
+	filesystem.Method = method
+	if method == "overlay2" {
+		filesystem.RootPath = root
+	}
+
+	// Containers share the host kernel and architecture
+	filesystem.SystemInfo.Kernel = report.SystemInfo.Kernel
+	filesystem.SystemInfo.Architecture = report.SystemInfo.Architecture
+
+	processes := containerProcesses(container.ContainerID)
+	for i := range filesystem.WebServers {
+		if containerWebServerRunning(filesystem.WebServers[i].Type, processes) {
+			filesystem.WebServers[i].Status = "Running"
+		}
+	}
+
+	container.Filesystem = filesystem
+	logger.Printf("Inspected container %s via %s: %s, %d web servers, %d OS packages, %d language packages",
+		container.Name, method, filesystem.SystemInfo.PrettyName, len(filesystem.WebServers), len(filesystem.OSPackages), len(filesystem.Components))
+}
+
+// InspectRoot is the entry point of the re-executed binary. It chroots into
+// a container root filesystem, runs the OS, web server and package collectors
+// and writes the result to output as JSON. Nothing from the container is executed.
+func InspectRoot(root string, output io.Writer, logger *log.Logger) error {
+	logger.Printf("Inspecting container filesystem %s", root)
+	if err := chroot(root); err != nil {
+		return err
+	}
+
+	filesystem := model.ContainerFilesystem{
+		SystemInfo: containerSystemInfo(logger),
+		WebServers: detectContainerWebServers(logger),
+		Components: []model.Component{},
+		OSPackages: collectOSPackageDatabases("/", logger),
+	}
+	if rpmDB := findExistingPath(containerRPMDatabases); rpmDB != "" {
+		logger.Printf("Skipping rpm database %s: rpm packages are not inventoried inside containers", rpmDB)
+	}
+
+	var docRoots []string
+	for _, webServer := range filesystem.WebServers {
+		docRoots = append(docRoots, webServer.DocumentRoots...)
+	}
+	inventory := newComponentInventory()
+	collectLanguagePackages(inventory, "/", docRoots, logger)
+	filesystem.Components = append(filesystem.Components, inventory.components...)
+
+	return json.NewEncoder(output).Encode(filesystem)
+}
+
+// exportContainer unpacks the docker export stream of a container into dir
+func exportContainer(containerID, dir string) error {
+	cmd := exec.Command("docker", "export", containerID)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	extractErr := extractTar(stdout, dir)
+	// Stop the export early if extraction gave up
+	if extractErr != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if extractErr != nil {
+		return extractErr
+	}
+	return waitErr
+}
+
+// extractTar unpacks directories, regular files, symlinks and hard links
+// into dir. Entries are created without following any symlink, so links
+// extracted earlier cannot redirect later entries outside dir. Large files,
+// special files and pseudo filesystems are skipped.
+func extractTar(reader io.Reader, dir string) error {
+	archive := tar.NewReader(reader)
+	var total int64
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := archiveRelPath(header.Name)
+		top := strings.SplitN(name, string(filepath.Separator), 2)[0]
+		if name == "." || top == "proc" || top == "sys" || top == "dev" {
+			continue
+		}
+
+		// Entries below a symlink or a file are only produced by crafted archives
+		parent, err := walkBeneath(dir, filepath.Dir(name), true)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(parent, filepath.Base(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(target); err == nil && info.IsDir() {
+				continue
+			}
+			if err := replaceEntry(target); err != nil {
+				return err
+			}
+			if err := os.Mkdir(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if header.Size > maxExportFileSize {
+				continue
+			}
+			total += header.Size
+			if total > maxExportTotalSize {
+				return fmt.Errorf("container filesystem exceeds %d bytes", int64(maxExportTotalSize))
+			}
+			if err := replaceEntry(target); err != nil {
+				return err
+			}
+			if err := writeTarFile(archive, target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Links are stored as is; they are only resolved inside the chroot
+			if err := replaceEntry(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// Hard link names are relative to the archive root and must name a regular file
+			sourceName := archiveRelPath(header.Linkname)
+			sourceDir, err := walkBeneath(dir, filepath.Dir(sourceName), false)
+			if err != nil {
+				continue
+			}
+			source := filepath.Join(sourceDir, filepath.Base(sourceName))
+			if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if err := replaceEntry(target); err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// archiveRelPath cleans an archive entry name into a path relative to the
+// archive root that cannot climb above it
+func archiveRelPath(name string) string {
+	cleaned := filepath.Clean(string(filepath.Separator) + filepath.FromSlash(name))
+	if rel := strings.TrimPrefix(cleaned, string(filepath.Separator)); rel != "" {
+		return rel
+	}
+	return "."
+}
+
+// walkBeneath returns dir joined with rel after checking that every component
+// is a real directory rather than a symlink. Missing directories are created
+// when create is set.
+func walkBeneath(dir, rel string, create bool) (string, error) {
+	current := dir
+	if rel == "." {
+		return current, nil
+	}
+	for _, component := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, component)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) && create {
+			if err := os.Mkdir(current, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return current, nil
+}
+
+// replaceEntry removes a file or symlink left at path by an earlier entry,
+// so the new entry is created in its place instead of written through it
+func replaceEntry(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return os.RemoveAll(path)
+	}
+	return os.Remove(path)
+}
+
+// writeTarFile copies the current archive entry to a new file at path
+func writeTarFile(archive *tar.Reader, path string, mode os.FileMode) error {
+	// O_EXCL never follows a symlink at path. Only the permission bits are
+	// kept, never setuid or setgid.
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode&0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, archive); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// containerSystemInfo reads the os-release of the chrooted container. The
+// kernel and architecture are the host's and are filled in by the caller.
+func containerSystemInfo(logger *log.Logger) model.SystemInfo {
+	info := model.SystemInfo{
+		OSName:    "Linux",
+		OSVersion: "unknown",
+	}
+
+	release, err := readOSRelease("/")
+	if err != nil {
+		// Distroless and scratch images have no os-release
+		logger.Printf("No os-release in container filesystem: %v", err)
+		return info
+	}
+	applyOSRelease(&info, release)
+	lookupDistroSupport(&info, time.Now(), logger)
+	return info
+}
+
+// detectContainerWebServers finds web servers installed in the chrooted
+// container filesystem from their binaries and configuration files
+func detectContainerWebServers(logger *log.Logger) []model.WebServer {
+	webServers := []model.WebServer{}
+
+	for _, candidate := range containerWebServers {
+		if !anyPathExists(candidate.binaries) {
+			continue
+		}
+
+		// Whether it runs is decided outside the chroot from docker top
+		webServer := model.WebServer{
+			Type:          candidate.name,
+			Status:        "Installed but not running",
+			DocumentRoots: []string{},
+		}
+		if configFile := findExistingPath(candidate.configPaths); configFile != "" {
+			webServer.ConfigFile = configFile
+			webServer.DocumentRoots = extractContainerDocumentRoots(candidate.name, configFile, logger)
+		}
+		webServer.Applications = fingerprintDocumentRoots(webServer.DocumentRoots, logger)
+
+		webServers = append(webServers, webServer)
+		logger.Printf("Detected %s web server in container: config=%s", webServer.Type, webServer.ConfigFile)
+	}
+	return webServers
+}
+
+// extractContainerDocumentRoots reads document roots from a web server
+// configuration, following includes
+func extractContainerDocumentRoots(serverType, configFile string, logger *log.Logger) []string {
+	switch serverType {
+	case "Apache":
+		docRoots := extractApacheDocumentRoots(configFile, logger)
+		for _, includeFile := range extractApacheIncludeFiles("/", configFile, logger) {
+			docRoots = append(docRoots, extractApacheDocumentRoots(includeFile, logger)...)
+		}
+		return docRoots
+	case "Nginx":
+		docRoots := extractNginxDocumentRoots(configFile, logger)
+		for _, includeFile := range extractNginxIncludeFiles("/", configFile, logger) {
+			docRoots = append(docRoots, extractNginxDocumentRoots(includeFile, logger)...)
+		}
+		return docRoots
+	case "Lighttpd":
+		return extractLighttpdDocumentRoots(configFile)
+	case "Caddy":
+		return extractCaddyDocumentRoots(configFile)
+	}
+	return nil
+}
+
+// containerWebServerRunning reports whether one of a web server's binaries
+// is among the processes running in the container
+func containerWebServerRunning(serverType string, processes map[string]bool) bool {
+	for _, candidate := range containerWebServers {
+		if candidate.name != serverType {
+			continue
+		}
+		for _, binary := range candidate.binaries {
+			if processes[filepath.Base(binary)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containerProcesses returns the command names of processes running in a container
+func containerProcesses(containerID string) map[string]bool {
+	processes := make(map[string]bool)
+	output, err := exec.Command("docker", "top", containerID, "-eo", "comm").Output()
+	if err != nil {
+		return processes
+	}
+	// The first line is the COMMAND header
+	for _, line := range strings.Split(string(output), "\n")[1:] {
+		if name := strings.TrimSpace(line); name != "" {
+			processes[name] = true
+		}
+	}
+	return processes
+}
@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// detectISCDHCP reads dhcpd.conf for subnets, pools and host reservations
+func detectISCDHCP(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/dhcp/dhcpd.conf", "/etc/dhcpd.conf", "/usr/local/etc/dhcpd.conf"})
+	if _, err := exec.LookPath("dhcpd"); err != nil && configFile == "" {
+		logger.Println("ISC DHCP server not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:       "ISC DHCP",
+		Status:     getServiceStatus("isc-dhcp-server", "dhcpd", logger),
+		ConfigFile: configFile,
+		Role:       "dhcp",
+		Resources:  []model.ServedResource{},
+	}
+
+	var collect func([]braceStatement)
+	collect = func(statements []braceStatement) {
+		for _, statement := range statements {
+			switch statement.name() {
+			case "authoritative":
+				service.Role = "dhcp, authoritative"
+			case "shared-network", "group":
+				collect(statement.block)
+			case "subnet", "subnet6":
+				// subnet 10.0.0.0 netmask 255.255.255.0 { range 10.0.0.100 10.0.0.200; }
+				name := strings.Join(statement.args[1:], " ")
+				var ranges []string
+				for _, option := range statement.block {
+					if option.name() == "range" || option.name() == "range6" {
+						ranges = append(ranges, strings.Join(option.args[1:], "-"))
+					}
+					if option.name() == "pool" {
+						for _, poolOption := range option.block {
+							if poolOption.name() == "range" {
+								ranges = append(ranges, strings.Join(poolOption.args[1:], "-"))
+							}
+						}
+					}
+				}
+				resource := model.ServedResource{Kind: "subnet", Name: name}
+				if len(ranges) > 0 {
+					resource.Detail = "range " + strings.Join(ranges, ", ")
+				}
+				service.Resources = append(service.Resources, resource)
+				collect(statement.block)
+			case "host":
+				resource := model.ServedResource{Kind: "reservation", Name: statement.value()}
+				if address, ok := statement.find("fixed-address"); ok {
+					resource.Detail = address.value()
+				}
+				service.Resources = append(service.Resources, resource)
+			}
+		}
+	}
+	if configFile != "" {
+		collect(readBraceConfig(configFile, 0, logger))
+	}
+
+	// Interfaces are passed on the command line by the init scripts
+	interfaces := strings.Fields(parseShellAssignments("/etc/default/isc-dhcp-server")["INTERFACESv4"])
+	if len(interfaces) == 0 {
+		interfaces = strings.Fields(parseShellAssignments("/etc/sysconfig/dhcpd")["DHCPDARGS"])
+	}
+	for _, iface := range interfaces {
+		service.Listeners = append(service.Listeners, iface+":67")
+	}
+	if len(service.Listeners) == 0 {
+		service.Listeners = []string{"*:67"}
+	}
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected ISC DHCP: status=%s, %d resources", service.Status, len(service.Resources))
+}
+
+// keaSubnet is a subnet entry in a Kea DHCPv4 or DHCPv6 configuration
+type keaSubnet struct {
+	Subnet string `json:"subnet"`
+	Pools  []struct {
+		Pool string `json:"pool"`
+	} `json:"pools"`
+	Reservations []struct {
+		Hostname  string `json:"hostname"`
+		HWAddress string `json:"hw-address"`
+		IPAddress string `json:"ip-address"`
+	} `json:"reservations"`
+}
+
+// keaServer is the Dhcp4 or Dhcp6 object of a Kea configuration
+type keaServer struct {
+	InterfacesConfig struct {
+		Interfaces []string `json:"interfaces"`
+	} `json:"interfaces-config"`
+	Subnet4        []keaSubnet `json:"subnet4"`
+	Subnet6        []keaSubnet `json:"subnet6"`
+	SharedNetworks []struct {
+		Name    string      `json:"name"`
+		Subnet4 []keaSubnet `json:"subnet4"`
+		Subnet6 []keaSubnet `json:"subnet6"`
+	} `json:"shared-networks"`
+}
+
+// detectKea reads the Kea DHCPv4 and DHCPv6 JSON configurations
+func detectKea(report *model.DiscoveryReport, logger *log.Logger) {
+	for _, variant := range []struct {
+		key, port, service string
+		paths              []string
+	}{
+		{"Dhcp4", "67", "kea-dhcp4-server", []string{"/etc/kea/kea-dhcp4.conf", "/usr/local/etc/kea/kea-dhcp4.conf"}},
+		{"Dhcp6", "547", "kea-dhcp6-server", []string{"/etc/kea/kea-dhcp6.conf", "/usr/local/etc/kea/kea-dhcp6.conf"}},
+	} {
+		configFile := findExistingPath(variant.paths)
+		if configFile == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			logger.Printf("Error reading Kea configuration %s: %v", configFile, err)
+			continue
+		}
+		var config map[string]keaServer
+		if err := json.Unmarshal(stripJSONComments(data), &config); err != nil {
+			logger.Printf("Error parsing Kea configuration %s: %v", configFile, err)
+			continue
+		}
+		server := config[variant.key]
+
+		service := model.InfrastructureService{
+			Type:       "Kea " + variant.key,
+			Status:     getServiceStatus(variant.service, strings.TrimSuffix(variant.service, "-server"), logger),
+			ConfigFile: configFile,
+			Role:       "dhcp",
+			Resources:  []model.ServedResource{},
+		}
+		for _, iface := range server.InterfacesConfig.Interfaces {
+			service.Listeners = append(service.Listeners, iface+":"+variant.port)
+		}
+
+		var subnets []keaSubnet
+		subnets = append(subnets, server.Subnet4...)
+		subnets = append(subnets, server.Subnet6...)
+		for _, network := range server.SharedNetworks {
+			subnets = append(subnets, network.Subnet4...)
+			subnets = append(subnets, network.Subnet6...)
+		}
+		for _, subnet := range subnets {
+			resource := model.ServedResource{Kind: "subnet", Name: subnet.Subnet}
+			var pools []string
+			for _, pool := range subnet.Pools {
+				pools = append(pools, strings.ReplaceAll(pool.Pool, " ", ""))
+			}
+			if len(pools) > 0 {
+				resource.Detail = "pool " + strings.Join(pools, ", ")
+			}
+			service.Resources = append(service.Resources, resource)
+
+			for _, reservation := range subnet.Reservations {
+				name := reservation.Hostname
+				if name == "" {
+					name = reservation.HWAddress
+				}
+				service.Resources = append(service.Resources, model.ServedResource{
+					Kind:   "reservation",
+					Name:   name,
+					Detail: reservation.IPAddress,
+				})
+			}
+		}
+
+		report.Infrastructure = append(report.Infrastructure, service)
+		logger.Printf("Detected %s: status=%s, %d subnets", service.Type, service.Status, len(subnets))
+	}
+}
+
+// stripJSONComments removes the //, # and /* */ comments Kea allows in its JSON files
+func stripJSONComments(data []byte) []byte {
+	var out strings.Builder
+	text := string(data)
+	inString := false
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(text) {
+				i++
+				out.WriteByte(text[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '#' || (c == '/' && i+1 < len(text) && text[i+1] == '/'):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return []byte(out.String())
+			}
+			i += end + 3
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return []byte(out.String())
+}
@@ -0,0 +1,265 @@
+package collector
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// olcDatabasePattern extracts the backend from "olcDatabase={1}mdb.ldif"
+var olcDatabasePattern = regexp.MustCompile(`^olcDatabase=\{-?\d+\}(\w+)\.ldif$`)
+
+// detectSlapd reads the OpenLDAP cn=config directory, or slapd.conf on older setups, for database suffixes
+func detectSlapd(report *model.DiscoveryReport, logger *log.Logger) {
+	configDir := findExistingPath([]string{"/etc/ldap/slapd.d", "/etc/openldap/slapd.d", "/usr/local/etc/openldap/slapd.d"})
+	configFile := findExistingPath([]string{"/etc/ldap/slapd.conf", "/etc/openldap/slapd.conf", "/usr/local/etc/openldap/slapd.conf"})
+	if _, err := exec.LookPath("slapd"); err != nil && configDir == "" && configFile == "" {
+		logger.Println("OpenLDAP slapd not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:      "OpenLDAP",
+		Status:    getServiceStatus("slapd", "", logger),
+		Role:      "directory",
+		Resources: []model.ServedResource{},
+	}
+
+	// slapd.d takes precedence over slapd.conf when both exist
+	if configDir != "" {
+		service.ConfigFile = configDir
+		databases, _ := filepath.Glob(filepath.Join(configDir, "cn=config", "olcDatabase=*.ldif"))
+		for _, database := range databases {
+			match := olcDatabasePattern.FindStringSubmatch(filepath.Base(database))
+			if match == nil {
+				continue
+			}
+			attributes := readLDIFAttributes(database)
+			for _, suffix := range attributes["olcSuffix"] {
+				detail := "backend " + match[1]
+				if directory := attributes["olcDbDirectory"]; len(directory) > 0 {
+					detail += ", directory " + directory[0]
+				}
+				service.Resources = append(service.Resources, model.ServedResource{Kind: "suffix", Name: suffix, Detail: detail})
+			}
+		}
+	} else if configFile != "" {
+		service.ConfigFile = configFile
+		service.Resources = append(service.Resources, parseSlapdConf(configFile)...)
+	}
+
+	// Listener URLs are passed to slapd by the init scripts
+	urls := parseShellAssignments("/etc/default/slapd")["SLAPD_SERVICES"]
+	if urls == "" {
+		urls = parseShellAssignments("/etc/sysconfig/slapd")["SLAPD_URLS"]
+	}
+	if urls == "" {
+		urls = "ldap:///"
+	}
+	service.Listeners = strings.Fields(urls)
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected OpenLDAP: status=%s, %d suffixes", service.Status, len(service.Resources))
+}
+
+// readLDIFAttributes parses a single-entry LDIF file, unfolding continuation
+// lines and decoding base64 ("attr:: ...") values
+func readLDIFAttributes(path string) map[string][]string {
+	attributes := make(map[string][]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return attributes
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if encoded, isBase64 := strings.CutPrefix(value, ":"); isBase64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				continue
+			}
+			value = string(decoded)
+		}
+		attributes[key] = append(attributes[key], strings.TrimSpace(value))
+	}
+	return attributes
+}
+
+// parseSlapdConf reads "database", "suffix" and "directory" directives from slapd.conf
+func parseSlapdConf(path string) []model.ServedResource {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var resources []model.ServedResource
+	backend := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		value := strings.Trim(strings.Join(fields[1:], " "), "\"")
+		switch fields[0] {
+		case "database":
+			backend = value
+		case "suffix":
+			resources = append(resources, model.ServedResource{Kind: "suffix", Name: value, Detail: "backend " + backend})
+		case "directory":
+			if len(resources) > 0 {
+				resources[len(resources)-1].Detail += ", directory " + value
+			}
+		}
+	}
+	return resources
+}
+
+// sambaConfig is smb.conf with parameters keyed by lower-cased section and
+// parameter names; sections keeps the section names as written
+type sambaConfig struct {
+	sections []string
+	params   map[string]map[string]string
+}
+
+// detectSamba reads smb.conf for the server role, interfaces and shares
+func detectSamba(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/samba/smb.conf", "/usr/local/etc/smb4.conf", "/usr/local/samba/etc/smb.conf"})
+	if configFile == "" {
+		logger.Println("Samba not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:       "Samba",
+		Status:     getServiceStatus("smbd", "samba-ad-dc", logger),
+		ConfigFile: configFile,
+		Resources:  []model.ServedResource{},
+	}
+
+	// testparm prints the effective configuration with includes resolved
+	var text string
+	if output, err := exec.Command("testparm", "-s", "--suppress-prompt", configFile).Output(); err == nil {
+		text = string(output)
+	} else if data, err := os.ReadFile(configFile); err == nil {
+		text = string(data)
+	} else {
+		logger.Printf("Error reading Samba configuration %s: %v", configFile, err)
+	}
+	config := parseSambaConfig(text)
+	global := config.params["global"]
+
+	service.Role = global["server role"]
+	if service.Role == "" {
+		switch strings.ToLower(global["security"]) {
+		case "ads", "domain":
+			service.Role = "member server"
+		default:
+			service.Role = "standalone server"
+		}
+	}
+	if realm := global["realm"]; realm != "" {
+		service.Resources = append(service.Resources, model.ServedResource{Kind: "realm", Name: realm})
+	}
+
+	ports := strings.Fields(global["smb ports"])
+	if len(ports) == 0 {
+		ports = []string{"445", "139"}
+	}
+	hosts := []string{"*"}
+	if interfaces := splitMailList(global["interfaces"]); len(interfaces) > 0 && isYes(global["bind interfaces only"]) {
+		hosts = interfaces
+	}
+	for _, host := range hosts {
+		for _, port := range ports {
+			service.Listeners = append(service.Listeners, host+":"+port)
+		}
+	}
+
+	for _, section := range config.sections {
+		if strings.EqualFold(section, "global") {
+			continue
+		}
+		params := config.params[strings.ToLower(section)]
+		resource := model.ServedResource{Kind: "share", Name: section, Detail: params["path"]}
+		if isYes(params["printable"]) || strings.EqualFold(section, "printers") {
+			resource.Kind = "printer"
+		}
+		service.Resources = append(service.Resources, resource)
+	}
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected Samba: status=%s, role=%s, %d shares", service.Status, service.Role, len(service.Resources))
+}
+
+// parseSambaConfig parses smb.conf syntax: [section] headers, "name = value"
+// parameters with case-insensitive names, ";" and "#" comments and "\" continuations
+func parseSambaConfig(text string) sambaConfig {
+	config := sambaConfig{params: make(map[string]map[string]string)}
+	section := ""
+	pending := ""
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = pending + line
+		pending = ""
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section = strings.ToLower(name)
+			if _, exists := config.params[section]; !exists {
+				config.sections = append(config.sections, name)
+				config.params[section] = make(map[string]string)
+			}
+			continue
+		}
+		if section == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.Join(strings.Fields(key), " "))
+		config.params[section][key] = strings.TrimSpace(value)
+	}
+	return config
+}
+
+// isYes reports whether a Samba boolean parameter is enabled
+func isYes(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "true", "1", "on":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// Support states reported for the operating system release
+const (
+	supportSupported = "supported"
+	supportExtended  = "extended-support"
+	supportEOL       = "end-of-life"
+	supportRolling   = "rolling-release"
+	supportUnknown   = "unknown"
+)
+
+// distroEOLData is the end-of-life table. Edit distro_eol.json to add
+// releases or adjust dates; it is compiled into the binary.
+//
+//go:embed distro_eol.json
+var distroEOLData []byte
+
+// distroRelease is an entry in the end-of-life table. EOL is the end of
+// regular security support; Extended is the end of paid or LTS support.
+type distroRelease struct {
+	ID       string `json:"id"`
+	Version  string `json:"version"`
+	EOL      string `json:"eol"`
+	Extended string `json:"extended"`
+	Rolling  bool   `json:"rolling"`
+}
+
+// lookupDistroSupport fills in the support status and end-of-life dates for the release
+func lookupDistroSupport(info *model.SystemInfo, now time.Time, logger *log.Logger) {
+	info.SupportStatus = supportUnknown
+	if info.OSID == "" {
+		return
+	}
+
+	var table struct {
+		Releases []distroRelease `json:"releases"`
+	}
+	if err := json.Unmarshal(distroEOLData, &table); err != nil {
+		logger.Printf("Error parsing embedded end-of-life table: %v", err)
+		return
+	}
+
+	release, ok := findDistroRelease(table.Releases, info.OSID, info.OSVersion)
+	if !ok {
+		logger.Printf("No end-of-life data for %s %s", info.OSID, info.OSVersion)
+		return
+	}
+	if release.Rolling {
+		info.SupportStatus = supportRolling
+		return
+	}
+
+	info.EndOfLife = release.EOL
+	info.ExtendedSupport = release.Extended
+
+	// Support ends at the end of the listed day
+	today := now.Format("2006-01-02")
+	switch {
+	case today <= release.EOL:
+		info.SupportStatus = supportSupported
+	case release.Extended != "" && today <= release.Extended:
+		info.SupportStatus = supportExtended
+	default:
+		info.SupportStatus = supportEOL
+	}
+}
+
+// findDistroRelease finds the table entry for a distribution ID and VERSION_ID.
+// Entries match point releases too, so RHEL "9.4" uses the "9" entry.
+func findDistroRelease(releases []distroRelease, id, version string) (distroRelease, bool) {
+	for _, release := range releases {
+		if release.ID != id {
+			continue
+		}
+		if release.Rolling || version == release.Version ||
+			strings.HasPrefix(version, release.Version+".") {
+			return release, true
+		}
+	}
+	return distroRelease{}, false
+}
@@ -0,0 +1,345 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// detectBind reads named.conf for listen-on addresses and zones, including zones inside views
+func detectBind(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{
+		"/etc/bind/named.conf",             // Debian/Ubuntu
+		"/etc/named.conf",                  // RHEL/Fedora/SUSE
+		"/etc/namedb/named.conf",           // FreeBSD base
+		"/usr/local/etc/namedb/named.conf", // FreeBSD ports
+	})
+	if _, err := exec.LookPath("named"); err != nil && configFile == "" {
+		logger.Println("BIND not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:       "BIND",
+		Status:     getServiceStatus("named", "bind9", logger),
+		ConfigFile: configFile,
+		Resources:  []model.ServedResource{},
+	}
+
+	statements := readBraceConfig(configFile, 0, logger)
+	recursion := "yes"
+	hasPrimary, hasSecondary := false, false
+
+	var collectZones func([]braceStatement, string)
+	collectZones = func(statements []braceStatement, view string) {
+		for _, statement := range statements {
+			switch statement.name() {
+			case "view":
+				collectZones(statement.block, statement.value())
+			case "zone":
+				zoneType := ""
+				if typeStatement, ok := statement.find("type"); ok {
+					zoneType = typeStatement.value()
+				}
+				// Hint zones only prime the resolver
+				if zoneType == "hint" {
+					continue
+				}
+				switch zoneType {
+				case "master", "primary":
+					hasPrimary = true
+				case "slave", "secondary":
+					hasSecondary = true
+				}
+
+				detail := "type " + zoneType
+				if file, ok := statement.find("file"); ok {
+					detail += ", file " + file.value()
+				}
+				if view != "" {
+					detail += ", view " + view
+				}
+				service.Resources = append(service.Resources, model.ServedResource{
+					Kind:   "zone",
+					Name:   statement.value(),
+					Detail: detail,
+				})
+			}
+		}
+	}
+	collectZones(statements, "")
+
+	for _, statement := range statements {
+		if statement.name() != "options" {
+			continue
+		}
+		for _, option := range statement.block {
+			switch option.name() {
+			case "listen-on", "listen-on-v6":
+				port := "53"
+				for i, arg := range option.args {
+					if arg == "port" && i+1 < len(option.args) {
+						port = option.args[i+1]
+					}
+				}
+				for _, address := range option.block {
+					service.Listeners = append(service.Listeners, net.JoinHostPort(strings.Join(address.args, " "), port))
+				}
+			case "recursion":
+				recursion = option.value()
+			}
+		}
+	}
+	if len(service.Listeners) == 0 {
+		service.Listeners = []string{"any:53"}
+	}
+
+	var roles []string
+	if hasPrimary {
+		roles = append(roles, "authoritative")
+	}
+	if hasSecondary {
+		roles = append(roles, "secondary")
+	}
+	if recursion != "no" {
+		roles = append(roles, "recursive")
+	}
+	service.Role = strings.Join(roles, ", ")
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected BIND: status=%s, %d zones", service.Status, len(service.Resources))
+}
+
+// detectUnbound reads unbound.conf for interfaces and local, stub, forward and auth zones
+func detectUnbound(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{
+		"/etc/unbound/unbound.conf",
+		"/usr/local/etc/unbound/unbound.conf",
+		"/var/unbound/unbound.conf", // OpenBSD
+	})
+	if configFile == "" {
+		logger.Println("Unbound not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:       "Unbound",
+		Status:     getServiceStatus("unbound", "", logger),
+		ConfigFile: configFile,
+		Role:       "recursive",
+		Resources:  []model.ServedResource{},
+	}
+
+	entries := readUnboundConfig(configFile, 0)
+	port := "53"
+	var interfaces []string
+	clause := ""
+	var zone *model.ServedResource
+	flushZone := func() {
+		if zone != nil && zone.Name != "" {
+			service.Resources = append(service.Resources, *zone)
+		}
+		zone = nil
+	}
+
+	for _, entry := range entries {
+		key, value := entry[0], entry[1]
+
+		// Clause headers such as "server:" and "forward-zone:" have no value
+		if value == "" && !strings.Contains(key, " ") {
+			flushZone()
+			clause = key
+			if clause == "forward-zone" || clause == "stub-zone" || clause == "auth-zone" {
+				zone = &model.ServedResource{Kind: clause}
+			}
+			continue
+		}
+
+		switch {
+		case clause == "server" && key == "interface":
+			interfaces = append(interfaces, value)
+		case clause == "server" && key == "port":
+			port = value
+		case clause == "server" && key == "local-zone":
+			// local-zone: "example.lan." static
+			fields := strings.Fields(value)
+			resource := model.ServedResource{Kind: "local-zone", Name: strings.Trim(fields[0], "\"")}
+			if len(fields) > 1 {
+				resource.Detail = "type " + fields[1]
+			}
+			service.Resources = append(service.Resources, resource)
+		case zone != nil && key == "name":
+			zone.Name = value
+		case zone != nil && (strings.HasSuffix(key, "-addr") || strings.HasSuffix(key, "-host") || key == "zonefile"):
+			if zone.Detail != "" {
+				zone.Detail += ", "
+			}
+			zone.Detail += key + " " + value
+		}
+	}
+	flushZone()
+
+	// Unbound answers on localhost only unless interfaces are configured
+	if len(interfaces) == 0 {
+		interfaces = []string{"127.0.0.1", "::1"}
+	}
+	for _, iface := range interfaces {
+		if address, ifacePort, ok := strings.Cut(iface, "@"); ok {
+			service.Listeners = append(service.Listeners, net.JoinHostPort(address, ifacePort))
+		} else {
+			service.Listeners = append(service.Listeners, net.JoinHostPort(iface, port))
+		}
+	}
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected Unbound: status=%s, %d zones", service.Status, len(service.Resources))
+}
+
+// readUnboundConfig reads "key: value" entries, expanding include globs in place
+func readUnboundConfig(path string, depth int) [][2]string {
+	entries, err := readKeyValueConfig(path, ":")
+	if err != nil || depth > 8 {
+		return nil
+	}
+
+	var expanded [][2]string
+	for _, entry := range entries {
+		if entry[0] == "include" || entry[0] == "include-toplevel" {
+			matches, _ := filepath.Glob(entry[1])
+			for _, match := range matches {
+				expanded = append(expanded, readUnboundConfig(match, depth+1)...)
+			}
+			continue
+		}
+		expanded = append(expanded, entry)
+	}
+	return expanded
+}
+
+// detectDnsmasq reads dnsmasq.conf and its conf-dir for DNS and DHCP settings
+func detectDnsmasq(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/dnsmasq.conf", "/usr/local/etc/dnsmasq.conf"})
+	if configFile == "" {
+		logger.Println("dnsmasq not found")
+		return
+	}
+
+	service := model.InfrastructureService{
+		Type:       "dnsmasq",
+		Status:     getServiceStatus("dnsmasq", "", logger),
+		ConfigFile: configFile,
+		Resources:  []model.ServedResource{},
+	}
+
+	port := "53"
+	var addresses, interfaces []string
+	dhcp := false
+
+	for _, entry := range readDnsmasqConfig(configFile, 0) {
+		key, value := entry[0], entry[1]
+		switch key {
+		case "port":
+			port = value
+		case "listen-address":
+			addresses = append(addresses, strings.Split(value, ",")...)
+		case "interface":
+			interfaces = append(interfaces, strings.Split(value, ",")...)
+		case "dhcp-range":
+			dhcp = true
+			service.Resources = append(service.Resources, model.ServedResource{Kind: "dhcp-range", Name: value})
+		case "dhcp-host":
+			service.Resources = append(service.Resources, model.ServedResource{Kind: "reservation", Name: value})
+		case "address", "server", "local":
+			// address=/example.lan/10.0.0.1, server=/corp/10.1.1.1, local=/lan/
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			if len(parts) < 2 || parts[0] == "" {
+				continue
+			}
+			kind := map[string]string{"address": "address", "server": "forward-zone", "local": "local-zone"}[key]
+			service.Resources = append(service.Resources, model.ServedResource{
+				Kind:   kind,
+				Name:   parts[0],
+				Detail: strings.Join(parts[1:], "/"),
+			})
+		case "addn-hosts":
+			service.Resources = append(service.Resources, model.ServedResource{Kind: "hosts-file", Name: value})
+		}
+	}
+
+	var roles []string
+	if port != "0" {
+		roles = append(roles, "dns")
+		switch {
+		case len(addresses) > 0:
+			for _, address := range addresses {
+				service.Listeners = append(service.Listeners, net.JoinHostPort(strings.TrimSpace(address), port))
+			}
+		case len(interfaces) > 0:
+			for _, iface := range interfaces {
+				service.Listeners = append(service.Listeners, fmt.Sprintf("%s:%s", strings.TrimSpace(iface), port))
+			}
+		default:
+			service.Listeners = append(service.Listeners, "*:"+port)
+		}
+	}
+	if dhcp {
+		roles = append(roles, "dhcp")
+		service.Listeners = append(service.Listeners, "*:67")
+	}
+	service.Role = strings.Join(roles, ", ")
+
+	report.Infrastructure = append(report.Infrastructure, service)
+	logger.Printf("Detected dnsmasq: status=%s, role=%s", service.Status, service.Role)
+}
+
+// readDnsmasqConfig reads key=value options, following conf-file and conf-dir
+func readDnsmasqConfig(path string, depth int) [][2]string {
+	entries, err := readKeyValueConfig(path, "=")
+	if err != nil || depth > 8 {
+		return nil
+	}
+
+	var expanded [][2]string
+	for _, entry := range entries {
+		switch entry[0] {
+		case "conf-file":
+			expanded = append(expanded, readDnsmasqConfig(entry[1], depth+1)...)
+		case "conf-dir":
+			// conf-dir=/etc/dnsmasq.d,.bak excludes suffixes; conf-dir=/etc/dnsmasq.d/,*.conf selects them
+			parts := strings.Split(entry[1], ",")
+			files, _ := filepath.Glob(filepath.Join(parts[0], "*"))
+			for _, file := range files {
+				base := filepath.Base(file)
+				if strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") || !dnsmasqConfDirSelects(base, parts[1:]) {
+					continue
+				}
+				expanded = append(expanded, readDnsmasqConfig(file, depth+1)...)
+			}
+		default:
+			expanded = append(expanded, entry)
+		}
+	}
+	return expanded
+}
+
+// dnsmasqConfDirSelects applies conf-dir's suffix filters to a file name
+func dnsmasqConfDirSelects(name string, filters []string) bool {
+	selected := true
+	for _, filter := range filters {
+		if suffix, ok := strings.CutPrefix(filter, "*"); ok {
+			// Any include pattern means only matching files are read
+			if strings.HasSuffix(name, suffix) {
+				return true
+			}
+			selected = false
+		} else if strings.HasSuffix(name, filter) {
+			return false
+		}
+	}
+	return selected
+}
@@ -1,10 +1,14 @@
 package collector
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/marolt/go-discovery/pkg/model"
@@ -39,97 +43,56 @@ func DetectDockerContainers(report *model.DiscoveryReport, logger *log.Logger) {
 	containerIDs := strings.Fields(string(output))
 	for _, containerID := range containerIDs {
 		container := model.DockerContainer{
-			ContainerID: containerID,
-			Ports:       []string{},
-			Volumes:     []string{},
-			Networks:    []string{},
+			ContainerID:  containerID,
+			PortMappings: []model.ContainerPort{},
+			Mounts:       []model.ContainerMount{},
+			Ports:        []string{},
+			Volumes:      []string{},
+			Networks:     []string{},
 		}
 
-		// Get container name
-		nameCmd := exec.Command("docker", "inspect", "--format", "{{.Name}}", containerID)
-		nameOutput, err := nameCmd.Output()
-		if err == nil {
-			container.Name = strings.TrimSpace(strings.TrimPrefix(string(nameOutput), "/"))
-		} else {
-			logger.Printf("Error getting name for container %s: %v", containerID, err)
+		// Everything below comes from a single docker inspect payload
+		info, err := inspectContainer(containerID)
+		if err != nil {
+			logger.Printf("Error inspecting container %s: %v", containerID, err)
+			info = &dockerInspect{}
+		}
+		applyContainerConfig(&container, info)
+		if container.Name == "" {
 			container.Name = "unknown"
 		}
-
-		// Get container image
-		imageCmd := exec.Command("docker", "inspect", "--format", "{{.Config.Image}}", containerID)
-		imageOutput, err := imageCmd.Output()
-		if err == nil {
-			container.Image = strings.TrimSpace(string(imageOutput))
-		} else {
-			logger.Printf("Error getting image for container %s: %v", containerID, err)
+		if container.Image == "" {
 			container.Image = "unknown"
 		}
 
-		// Get port mappings
-		portsCmd := exec.Command("docker", "inspect", "--format", "{{range $p, $conf := .NetworkSettings.Ports}} {{$p}}->{{(index $conf 0).HostPort}} {{end}}", containerID)
-		portsOutput, err := portsCmd.Output()
-		if err == nil {
-			portsList := strings.Split(strings.TrimSpace(string(portsOutput)), " ")
-			for _, port := range portsList {
-				if port != "" {
-					container.Ports = append(container.Ports, port)
-				}
-			}
-		} else {
-			logger.Printf("Error getting ports for container %s: %v", containerID, err)
-		}
-
-		// Get volume mappings
-		volumesCmd := exec.Command("docker", "inspect", "--format", "{{range .Mounts}}{{.Source}}:{{.Destination}} {{end}}", containerID)
-		volumesOutput, err := volumesCmd.Output()
-		if err == nil {
-			volumesList := strings.Fields(strings.TrimSpace(string(volumesOutput)))
-			container.Volumes = volumesList
-		} else {
-			logger.Printf("Error getting volumes for container %s: %v", containerID, err)
-		}
-
-		// Get networks
-		networksCmd := exec.Command("docker", "inspect", "--format", "{{range $net, $conf := .NetworkSettings.Networks}}{{$net}} {{end}}", containerID)
-		networksOutput, err := networksCmd.Output()
-		if err == nil {
-			networksList := strings.Fields(strings.TrimSpace(string(networksOutput)))
-			container.Networks = networksList
-		} else {
-			logger.Printf("Error getting networks for container %s: %v", containerID, err)
-		}
-
 		// Check if container is managed by Docker Compose
-		composeProjectCmd := exec.Command("docker", "inspect", "--format", "{{index .Config.Labels \"com.docker.compose.project\"}}", containerID)
-		composeProjectOutput, err := composeProjectCmd.Output()
-		composeProject := strings.TrimSpace(string(composeProjectOutput))
-
-		composeServiceCmd := exec.Command("docker", "inspect", "--format", "{{index .Config.Labels \"com.docker.compose.service\"}}", containerID)
-		composeServiceOutput, err := composeServiceCmd.Output()
-		composeService := strings.TrimSpace(string(composeServiceOutput))
+		composeProject := info.Config.Labels["com.docker.compose.project"]
+		composeService := info.Config.Labels["com.docker.compose.service"]
 
 		// Determine management type and set related fields
-		if composeProject != "" || composeService != "" {
+		labels := info.Config.Labels
+		if labels["com.docker.swarm.service.name"] != "" {
+			// Swarm task containers carry the service and task they belong to
+			container.ManagedBy = "swarm"
+			container.SwarmService = labels["com.docker.swarm.service.name"]
+			container.SwarmServiceID = labels["com.docker.swarm.service.id"]
+			container.SwarmTask = labels["com.docker.swarm.task.name"]
+			container.SwarmTaskID = labels["com.docker.swarm.task.id"]
+			container.SwarmStack = labels["com.docker.stack.namespace"]
+			container.ComposeProject = "-"
+			container.ComposeService = "-"
+			container.ComposeFile = "-"
+		} else if composeProject != "" || composeService != "" {
 			container.ManagedBy = "docker-compose"
 			container.ComposeProject = composeProject
 			container.ComposeService = composeService
 
-			// Find compose file location
-			composeFileCmd := exec.Command("docker", "inspect", "--format", "{{index .Config.Labels \"com.docker.compose.project.working_dir\"}}", containerID)
-			composeFileOutput, err := composeFileCmd.Output()
-			if err == nil {
-				workingDir := strings.TrimSpace(string(composeFileOutput))
-				container.ComposeFile = filepath.Join(workingDir, "docker-compose.yml")
-
-				// Check if the file exists, try .yaml extension if not
-				if _, err := os.Stat(container.ComposeFile); os.IsNotExist(err) {
-					yamlPath := filepath.Join(workingDir, "docker-compose.yaml")
-					if _, err := os.Stat(yamlPath); err == nil {
-						container.ComposeFile = yamlPath
-					}
-				}
+			// Find every compose file that contributed to the container
+			container.ComposeFiles = composeConfigFiles(info.Config.Labels)
+			if len(container.ComposeFiles) > 0 {
+				container.ComposeFile = container.ComposeFiles[0]
 			} else {
-				logger.Printf("Error getting compose file for container %s: %v", containerID, err)
+				logger.Printf("No compose file labels on container %s", containerID)
 				container.ComposeFile = "unknown"
 			}
 		} else {
@@ -139,26 +102,219 @@ func DetectDockerContainers(report *model.DiscoveryReport, logger *log.Logger) {
 			container.ComposeFile = "-"
 		}
 
+		// Run the OS, web server and package collectors inside the container
+		inspectContainerFilesystem(report, &container, info.GraphDriver, logger)
+
 		// Add container to report
 		report.DockerContainers = append(report.DockerContainers, container)
 	}
 
-	// Also check if Docker Compose is installed and find compose files
-	if _, err := exec.LookPath("docker-compose"); err == nil || isDockerComposeV2Available() {
+	// Record Swarm membership and, on managers, the cluster's services
+	detectSwarm(report, logger)
+
+	// Also check if Docker Compose is installed and list its projects
+	if compose := composeCommand(); compose != nil {
 		logger.Println("Docker Compose is installed")
+		detectComposeProjects(report, compose, logger)
 		findComposeFiles(logger)
 	}
 
 	logger.Printf("Detected %d Docker containers", len(report.DockerContainers))
 }
 
-// isDockerComposeV2Available checks if Docker Compose V2 is available
-func isDockerComposeV2Available() bool {
-	cmd := exec.Command("docker", "compose", "version")
-	return cmd.Run() == nil
+// dockerInspect holds the parts of a docker inspect payload the collector uses
+type dockerInspect struct {
+	Name   string `json:"Name"`
+	Config struct {
+		Image      string            `json:"Image"`
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+		WorkingDir string            `json:"WorkingDir"`
+		User       string            `json:"User"`
+		Env        []string          `json:"Env"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		Privileged        bool     `json:"Privileged"`
+		CapAdd            []string `json:"CapAdd"`
+		CapDrop           []string `json:"CapDrop"`
+		NetworkMode       string   `json:"NetworkMode"`
+		PidMode           string   `json:"PidMode"`
+		SecurityOpt       []string `json:"SecurityOpt"`
+		NanoCpus          int64    `json:"NanoCpus"`
+		CpuShares         int64    `json:"CpuShares"`
+		CpuQuota          int64    `json:"CpuQuota"`
+		CpuPeriod         int64    `json:"CpuPeriod"`
+		CpusetCpus        string   `json:"CpusetCpus"`
+		Memory            int64    `json:"Memory"`
+		MemoryReservation int64    `json:"MemoryReservation"`
+		MemorySwap        int64    `json:"MemorySwap"`
+		PidsLimit         *int64   `json:"PidsLimit"`
+		LogConfig         struct {
+			Type   string            `json:"Type"`
+			Config map[string]string `json:"Config"`
+		} `json:"LogConfig"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Ports    map[string][]dockerPortBinding `json:"Ports"`
+		Networks map[string]json.RawMessage     `json:"Networks"`
+	} `json:"NetworkSettings"`
+	Mounts      []dockerMount     `json:"Mounts"`
+	GraphDriver dockerGraphDriver `json:"GraphDriver"`
+}
+
+// dockerPortBinding is a host address a container port is published on
+type dockerPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// dockerMount is a bind mount, volume or tmpfs attached to a container
+type dockerMount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	RW          bool   `json:"RW"`
+	Propagation string `json:"Propagation"`
+}
+
+// dockerGraphDriver names the storage driver and its layer directories
+type dockerGraphDriver struct {
+	Name string            `json:"Name"`
+	Data map[string]string `json:"Data"`
+}
+
+// inspectContainer fetches the full docker inspect payload of a container
+func inspectContainer(containerID string) (*dockerInspect, error) {
+	output, err := exec.Command("docker", "inspect", "--type", "container", containerID).Output()
+	if err != nil {
+		return nil, err
+	}
+	var payload []dockerInspect
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload) != 1 {
+		return nil, fmt.Errorf("expected one container, got %d", len(payload))
+	}
+	return &payload[0], nil
+}
+
+// applyContainerConfig copies the configuration, runtime settings and
+// resource limits of an inspect payload to the container
+func applyContainerConfig(container *model.DockerContainer, info *dockerInspect) {
+	container.Name = strings.TrimPrefix(info.Name, "/")
+	container.Image = info.Config.Image
+	container.Entrypoint = info.Config.Entrypoint
+	container.Command = info.Config.Cmd
+	container.WorkingDir = info.Config.WorkingDir
+	container.User = info.Config.User
+	container.Labels = info.Config.Labels
+
+	if len(info.Config.Env) > 0 {
+		container.Environment = make(map[string]string)
+		for _, variable := range info.Config.Env {
+			name, value, _ := strings.Cut(variable, "=")
+			container.Environment[name] = value
+		}
+	}
+
+	host := info.HostConfig
+	container.Privileged = host.Privileged
+	container.CapAdd = host.CapAdd
+	container.CapDrop = host.CapDrop
+	container.NetworkMode = host.NetworkMode
+	container.PidMode = host.PidMode
+	container.SecurityOpt = host.SecurityOpt
+	container.LogDriver = host.LogConfig.Type
+	if len(host.LogConfig.Config) > 0 {
+		container.LogOptions = host.LogConfig.Config
+	}
+	container.Resources = model.ContainerResources{
+		CPUs:              float64(host.NanoCpus) / 1e9,
+		CPUShares:         host.CpuShares,
+		CPUQuota:          host.CpuQuota,
+		CPUPeriod:         host.CpuPeriod,
+		CPUSet:            host.CpusetCpus,
+		MemoryBytes:       host.Memory,
+		MemoryReservation: host.MemoryReservation,
+		MemorySwapBytes:   host.MemorySwap,
+	}
+	// Docker reports an unlimited pids limit as 0 or -1 depending on the version
+	if host.PidsLimit != nil && *host.PidsLimit > 0 {
+		container.Resources.PidsLimit = *host.PidsLimit
+	}
+
+	container.PortMappings = containerPorts(info.NetworkSettings.Ports)
+	for _, mount := range info.Mounts {
+		container.Mounts = append(container.Mounts, model.ContainerMount{
+			Type:        mount.Type,
+			Name:        mount.Name,
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			ReadOnly:    !mount.RW,
+			Propagation: mount.Propagation,
+		})
+	}
+
+	// Legacy renderings kept for consumers of the old string fields
+	for _, port := range container.PortMappings {
+		if port.HostPort != 0 {
+			container.Ports = appendUnique(container.Ports, fmt.Sprintf("%d/%s->%d", port.ContainerPort, port.Protocol, port.HostPort))
+		}
+	}
+	for _, mount := range container.Mounts {
+		container.Volumes = append(container.Volumes, mount.Source+":"+mount.Destination)
+	}
+
+	for network := range info.NetworkSettings.Networks {
+		container.Networks = append(container.Networks, network)
+	}
+	sort.Strings(container.Networks)
+}
+
+// containerPorts flattens docker's port map into one entry per host binding,
+// sorted by protocol and container port. Exposed ports without bindings are
+// listed once with no host address.
+func containerPorts(ports map[string][]dockerPortBinding) []model.ContainerPort {
+	var result []model.ContainerPort
+	for spec, bindings := range ports {
+		number, protocol, _ := strings.Cut(spec, "/")
+		containerPort, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if len(bindings) == 0 {
+			result = append(result, model.ContainerPort{ContainerPort: containerPort, Protocol: protocol})
+			continue
+		}
+		for _, binding := range bindings {
+			hostPort, _ := strconv.Atoi(binding.HostPort)
+			result = append(result, model.ContainerPort{
+				ContainerPort: containerPort,
+				Protocol:      protocol,
+				HostIP:        binding.HostIP,
+				HostPort:      hostPort,
+			})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Protocol != result[j].Protocol {
+			return result[i].Protocol < result[j].Protocol
+		}
+		if result[i].ContainerPort != result[j].ContainerPort {
+			return result[i].ContainerPort < result[j].ContainerPort
+		}
+		return result[i].HostIP < result[j].HostIP
+	})
+	return result
 }
 
-// findComposeFiles looks for docker-compose files in common locations
+// findComposeFiles looks for compose files in common locations
 func findComposeFiles(logger *log.Logger) {
 	// Common paths to search for compose files
 	paths := []string{"/opt", "/srv", "/home"}
@@ -174,15 +330,15 @@ func findComposeFiles(logger *log.Logger) {
 				return filepath.SkipDir
 			}
 
-			// Check if file is a compose file
-			if !info.IsDir() && (info.Name() == "docker-compose.yml" || info.Name() == "docker-compose.yaml") {
-				logger.Printf("Found docker-compose file: %s", path)
+			// Check if file has one of the names Compose picks up by default
+			if !info.IsDir() && containsString(composeFileNames, info.Name()) {
+				logger.Printf("Found compose file: %s", path)
 			}
 			return nil
 		})
 
 		if err != nil {
-			logger.Printf("Error searching for docker-compose files in %s: %v", path, err)
+			logger.Printf("Error searching for compose files in %s: %v", path, err)
 		}
 	}
 }
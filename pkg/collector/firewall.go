@@ -0,0 +1,1019 @@
+package collector
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// Firewall rule actions after normalisation
+const (
+	firewallAllow  = "allow"
+	firewallDeny   = "deny"
+	firewallJump   = "jump"
+	firewallReturn = "return"
+)
+
+// Listener exposure states set by the firewall cross-reference
+const (
+	exposureExposed    = "exposed"
+	exposureRestricted = "restricted"
+	exposureFiltered   = "filtered"
+	exposureLocal      = "local"
+)
+
+// maxChainDepth bounds how deep jumps between chains are followed
+const maxChainDepth = 32
+
+// inputChain is a chain every inbound packet for a local socket starts in:
+// an input-hook base chain, a ufw rule list or a firewalld zone. Its rules
+// and the chains they jump to are walked in order, falling back to the policy.
+type inputChain struct {
+	backend string
+	chain   string
+	family  string // ipv4, ipv6 or empty for both
+	policy  string
+	// firewalld hands each packet to exactly one zone, chosen by source
+	// address or interface, so zones are alternatives rather than stages
+	zone    bool
+	sources []string
+}
+
+// DetectFirewall collects host firewall rules from nftables, iptables, firewalld
+// and ufw, then marks each discovered listener as exposed or filtered
+func DetectFirewall(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting firewall rules")
+
+	firewall := model.FirewallInfo{
+		Backends: []model.FirewallBackend{},
+		Rules:    []model.FirewallRule{},
+	}
+
+	// firewalld and ufw program nftables/iptables themselves; parse their own
+	// configuration first and skip the tables they generate to avoid duplicates
+	var chains []inputChain
+	firewalldActive := collectFirewalldRules(&firewall, &chains, logger)
+	ufwActive := collectUFWRules(&firewall, &chains, logger)
+	collectNftablesRules(&firewall, &chains, firewalldActive, logger)
+	collectIptablesRules(&firewall, &chains, ufwActive, ufwActive || firewalldActive, logger)
+
+	markListenerExposure(report.Listeners, firewall.Rules, chains)
+
+	report.Firewall = firewall
+	logger.Printf("Detected %d firewall rules from %d backends", len(firewall.Rules), len(firewall.Backends))
+}
+
+// nftRuleset is the top-level structure of `nft -j list ruleset`
+type nftRuleset struct {
+	Nftables []map[string]json.RawMessage `json:"nftables"`
+}
+
+// nftChain is a chain object from nft JSON output
+type nftChain struct {
+	Family string `json:"family"`
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+	Hook   string `json:"hook"`
+	Policy string `json:"policy"`
+}
+
+// nftRule is a rule object from nft JSON output
+type nftRule struct {
+	Family string                       `json:"family"`
+	Table  string                       `json:"table"`
+	Chain  string                       `json:"chain"`
+	Expr   []map[string]json.RawMessage `json:"expr"`
+}
+
+// nftMatch is a match statement comparing a packet field with a value
+type nftMatch struct {
+	Op   string `json:"op"`
+	Left struct {
+		Payload *struct {
+			Protocol string `json:"protocol"`
+			Field    string `json:"field"`
+		} `json:"payload"`
+		Meta *struct {
+			Key string `json:"key"`
+		} `json:"meta"`
+		Ct *struct {
+			Key string `json:"key"`
+		} `json:"ct"`
+	} `json:"left"`
+	Right json.RawMessage `json:"right"`
+}
+
+// nftFamilies maps the nftables families that filter local traffic to address families
+var nftFamilies = map[string]string{"ip": "ipv4", "ip6": "ipv6", "inet": ""}
+
+// iptablesTables are the tables iptables-nft creates in the ip and ip6 families
+var iptablesTables = map[string]bool{"filter": true, "mangle": true, "raw": true, "security": true, "nat": true}
+
+// collectNftablesRules parses the JSON ruleset of input-hook chains and the
+// chains they jump to
+func collectNftablesRules(firewall *model.FirewallInfo, chains *[]inputChain, skipFirewalld bool, logger *log.Logger) {
+	if _, err := exec.LookPath("nft"); err != nil {
+		return
+	}
+
+	output, err := exec.Command("nft", "-j", "list", "ruleset").Output()
+	if err != nil {
+		logger.Printf("Error listing nftables ruleset: %v", err)
+		return
+	}
+
+	// The iptables backend already reports the tables of iptables-nft
+	baseChains, rules, policy, err := parseNftRuleset(output, skipFirewalld, iptablesUsesNftables())
+	if err != nil {
+		logger.Printf("Error parsing nftables ruleset: %v", err)
+		return
+	}
+	if len(baseChains) == 0 {
+		return
+	}
+
+	firewall.Backends = append(firewall.Backends, model.FirewallBackend{Name: "nftables", InputPolicy: policy})
+	firewall.Rules = append(firewall.Rules, rules...)
+	*chains = append(*chains, baseChains...)
+}
+
+// parseNftRuleset returns the input base chains of `nft -j list ruleset`
+// output, the rules reachable from them in ruleset order and the strictest
+// base chain policy
+func parseNftRuleset(data []byte, skipFirewalld, iptablesCompat bool) ([]inputChain, []model.FirewallRule, string, error) {
+	var ruleset nftRuleset
+	if err := json.Unmarshal(data, &ruleset); err != nil {
+		return nil, nil, "", err
+	}
+
+	// First pass: find input base chains and their policies
+	var baseChains []inputChain
+	tables := make(map[string]bool)
+	policy := ""
+	for _, object := range ruleset.Nftables {
+		raw, ok := object["chain"]
+		if !ok {
+			continue
+		}
+		var chain nftChain
+		if json.Unmarshal(raw, &chain) != nil || chain.Hook != "input" {
+			continue
+		}
+		family, ok := nftFamilies[chain.Family]
+		if !ok {
+			continue
+		}
+		if skipFirewalld && chain.Table == "firewalld" {
+			continue
+		}
+		if iptablesCompat && chain.Family != "inet" && iptablesTables[chain.Table] {
+			continue
+		}
+		tables[chain.Family+"|"+chain.Table] = true
+		baseChains = append(baseChains, inputChain{
+			backend: "nftables",
+			chain:   nftChainName(chain.Family, chain.Table, chain.Name),
+			family:  family,
+			policy:  chain.Policy,
+		})
+		policy = strictestPolicy(policy, chain.Policy)
+	}
+	if len(baseChains) == 0 {
+		return nil, nil, "", nil
+	}
+
+	// Second pass: rules of those tables, in ruleset order
+	var rules []model.FirewallRule
+	for _, object := range ruleset.Nftables {
+		raw, ok := object["rule"]
+		if !ok {
+			continue
+		}
+		var rule nftRule
+		if json.Unmarshal(raw, &rule) != nil || !tables[rule.Family+"|"+rule.Table] {
+			continue
+		}
+		if parsed, ok := parseNftRule(rule); ok {
+			rules = append(rules, parsed)
+		}
+	}
+
+	var starts []string
+	for _, chain := range baseChains {
+		starts = append(starts, chain.chain)
+	}
+	return baseChains, reachableRules(rules, starts), policy, nil
+}
+
+// nftChainName identifies a chain; jumps stay within the family and table
+func nftChainName(family, table, chain string) string {
+	return family + " " + table + "/" + chain
+}
+
+// iptablesUsesNftables reports whether iptables is the nf_tables variant,
+// whose tables nft lists as well
+func iptablesUsesNftables() bool {
+	output, err := exec.Command("iptables", "-V").Output()
+	return err == nil && strings.Contains(string(output), "nf_tables")
+}
+
+// parseNftRule converts a rule into a port/source rule. Matches on anything
+// else, such as interfaces or destination addresses, are kept as conditions so
+// the rule still counts as a possible allow. Loopback rules and rules that only
+// apply to established connections are skipped.
+func parseNftRule(rule nftRule) (model.FirewallRule, bool) {
+	parsed := model.FirewallRule{
+		Backend:  "nftables",
+		Chain:    nftChainName(rule.Family, rule.Table, rule.Chain),
+		Protocol: "any",
+		Port:     "any",
+		Source:   "any",
+	}
+
+	for _, statement := range rule.Expr {
+		for kind, raw := range statement {
+			switch kind {
+			case "match":
+				var match nftMatch
+				if json.Unmarshal(raw, &match) != nil {
+					return parsed, false
+				}
+				value := nftValueString(match.Right)
+				left := match.Left
+				if match.Op != "==" && match.Op != "in" {
+					parsed.Conditions = append(parsed.Conditions, nftMatchString(match, value))
+					continue
+				}
+				switch {
+				case left.Payload != nil && left.Payload.Field == "dport":
+					// "th dport" matches any transport header; the protocol comes from meta l4proto
+					if nftL4Protocols[left.Payload.Protocol] {
+						parsed.Protocol = left.Payload.Protocol
+					}
+					parsed.Port = value
+				case left.Payload != nil && left.Payload.Field == "saddr":
+					parsed.Source = value
+				case left.Meta != nil && left.Meta.Key == "l4proto":
+					parsed.Protocol = value
+				case left.Meta != nil && (left.Meta.Key == "iifname" || left.Meta.Key == "iif") && value == "lo":
+					// Loopback rules never apply to remote clients
+					return parsed, false
+				case left.Ct != nil && left.Ct.Key == "state":
+					// Rules for established or related traffic never admit new connections
+					if !strings.Contains(value, "new") {
+						return parsed, false
+					}
+				default:
+					parsed.Conditions = append(parsed.Conditions, nftMatchString(match, value))
+				}
+			case "accept":
+				parsed.Action = firewallAllow
+			case "drop", "reject":
+				parsed.Action = firewallDeny
+			case "jump":
+				var jump struct {
+					Target string `json:"target"`
+				}
+				if json.Unmarshal(raw, &jump) != nil || jump.Target == "" {
+					return parsed, false
+				}
+				parsed.Action = firewallJump
+				parsed.Target = nftChainName(rule.Family, rule.Table, jump.Target)
+			case "return":
+				parsed.Action = firewallReturn
+			case "vmap":
+				// The verdict depends on the key; a map that can accept is a possible allow
+				if strings.Contains(string(raw), `"accept"`) {
+					parsed.Action = firewallAllow
+					parsed.Conditions = append(parsed.Conditions, "verdict map")
+				}
+			case "counter", "log", "comment":
+				// Informational statements
+			default:
+				// Statements such as limit only let part of the traffic through
+				parsed.Conditions = append(parsed.Conditions, kind)
+			}
+		}
+	}
+
+	return parsed, parsed.Action != ""
+}
+
+// nftL4Protocols are the transport protocols nft payload matches can name
+var nftL4Protocols = map[string]bool{"tcp": true, "udp": true, "udplite": true, "sctp": true, "dccp": true}
+
+// nftMatchString renders a match that is not modelled, e.g. "meta iifname == eth0"
+func nftMatchString(match nftMatch, value string) string {
+	left := "expression"
+	switch {
+	case match.Left.Payload != nil:
+		left = match.Left.Payload.Protocol + " " + match.Left.Payload.Field
+	case match.Left.Meta != nil:
+		left = "meta " + match.Left.Meta.Key
+	case match.Left.Ct != nil:
+		left = "ct " + match.Left.Ct.Key
+	}
+	return left + " " + match.Op + " " + value
+}
+
+// nftValueString renders the right-hand side of an nft match as text
+func nftValueString(raw json.RawMessage) string {
+	var scalar interface{}
+	if err := json.Unmarshal(raw, &scalar); err != nil {
+		return ""
+	}
+
+	switch value := scalar.(type) {
+	case float64:
+		return strconv.Itoa(int(value))
+	case string:
+		return value
+	case map[string]interface{}:
+		if set, ok := value["set"].([]interface{}); ok {
+			var items []string
+			for _, item := range set {
+				encoded, _ := json.Marshal(item)
+				items = append(items, nftValueString(encoded))
+			}
+			return strings.Join(items, ",")
+		}
+		if bounds, ok := value["range"].([]interface{}); ok && len(bounds) == 2 {
+			return fmt.Sprintf("%v-%v", bounds[0], bounds[1])
+		}
+		if prefix, ok := value["prefix"].(map[string]interface{}); ok {
+			return fmt.Sprintf("%v/%v", prefix["addr"], prefix["len"])
+		}
+	}
+	return string(raw)
+}
+
+// collectIptablesRules parses iptables-save and ip6tables-save output, or saved
+// rule files when the command is unavailable. Saved rules may not be loaded, so
+// they are reported but not used for listener exposure, and neither is the
+// INPUT chain when ufw or firewalld manages it.
+func collectIptablesRules(firewall *model.FirewallInfo, chains *[]inputChain, skipUFW, managed bool, logger *log.Logger) {
+	sources := []struct {
+		backend string
+		family  string
+		command string
+		files   []string
+	}{
+		{"iptables", "ipv4", "iptables-save", []string{"/etc/iptables/rules.v4", "/etc/sysconfig/iptables"}},
+		{"ip6tables", "ipv6", "ip6tables-save", []string{"/etc/iptables/rules.v6", "/etc/sysconfig/ip6tables"}},
+	}
+
+	found := false
+	for _, source := range sources {
+		var data []byte
+		if _, err := exec.LookPath(source.command); err == nil {
+			data, _ = exec.Command(source.command).Output()
+		}
+		saved := false
+		if len(strings.TrimSpace(string(data))) == 0 {
+			if path := findExistingPath(source.files); path != "" {
+				data, _ = os.ReadFile(path)
+				saved = true
+				logger.Printf("%s is unavailable, reporting saved rules from %s", source.command, path)
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		rules, inputPolicy := parseIptablesSave(string(data), source.backend, skipUFW)
+		if inputPolicy == "" && len(rules) == 0 {
+			continue
+		}
+		found = true
+		for i := range rules {
+			rules[i].Saved = saved
+		}
+		firewall.Rules = append(firewall.Rules, rules...)
+		firewall.Backends = append(firewall.Backends, model.FirewallBackend{Name: source.backend, InputPolicy: inputPolicy, Saved: saved})
+
+		if !saved && !managed {
+			*chains = append(*chains, inputChain{backend: source.backend, chain: "INPUT", family: source.family, policy: inputPolicy})
+		}
+	}
+
+	if !found {
+		logger.Println("No iptables rules found")
+	}
+}
+
+// parseIptablesSave extracts the filter-table rules reachable from INPUT from
+// iptables-save formatted text
+func parseIptablesSave(text, backend string, skipUFW bool) ([]model.FirewallRule, string) {
+	var rules []model.FirewallRule
+	chains := make(map[string]bool)
+	policy := ""
+	table := ""
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = strings.TrimPrefix(line, "*")
+		case table != "filter":
+			continue
+		case strings.HasPrefix(line, ":"):
+			// ":INPUT DROP [0:0]" or ":user-chain - [0:0]"
+			fields := strings.Fields(strings.TrimPrefix(line, ":"))
+			if len(fields) >= 2 {
+				chains[fields[0]] = true
+				if fields[0] == "INPUT" {
+					policy = strings.ToLower(fields[1])
+				}
+			}
+		case strings.HasPrefix(line, "-A "):
+			rule, ok := parseIptablesRule(splitIptablesLine(line), backend)
+			if !ok || (rule.Action == firewallJump && !chains[rule.Target]) {
+				continue
+			}
+			if skipUFW && (strings.HasPrefix(rule.Chain, "ufw") || strings.HasPrefix(rule.Target, "ufw")) {
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return reachableRules(rules, []string{"INPUT"}), policy
+}
+
+// splitIptablesLine splits an iptables-save line into arguments, honouring
+// the quotes and backslash escapes iptables-save writes around comments
+func splitIptablesLine(line string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	quote := rune(0)
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// parseIptablesRule converts "-A CHAIN [-s SRC] [-p PROTO] [-m MOD] [--dport N] -j TARGET".
+// Negations and matches that are not modelled, such as interfaces, destinations
+// or ipsets, are kept as conditions so the rule still counts as a possible allow.
+func parseIptablesRule(args []string, backend string) (model.FirewallRule, bool) {
+	rule := model.FirewallRule{Backend: backend, Protocol: "any", Port: "any", Source: "any"}
+
+	for i := 0; i < len(args); i++ {
+		next := func() string {
+			if i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return ""
+		}
+		// condition records an option with the values that follow it
+		condition := func(option ...string) {
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && args[i+1] != "!" {
+				i++
+				option = append(option, args[i])
+			}
+			rule.Conditions = append(rule.Conditions, strings.Join(option, " "))
+		}
+
+		switch args[i] {
+		case "!":
+			if i+1 < len(args) {
+				i++
+				condition("!", args[i])
+			}
+		case "-A":
+			rule.Chain = next()
+		case "-s", "--source":
+			rule.Source = next()
+		case "-p", "--protocol":
+			rule.Protocol = next()
+		case "--dport", "--dports", "--destination-port":
+			rule.Port = strings.ReplaceAll(next(), ":", "-")
+		case "-i", "--in-interface":
+			// Loopback rules never apply to remote clients
+			if next() == "lo" {
+				return rule, false
+			}
+			rule.Conditions = append(rule.Conditions, "-i "+args[i])
+		case "-m", "--match":
+			// Modules only restrict traffic through their options
+			next()
+		case "--ctstate", "--state":
+			// Rules for established or related traffic never admit new connections
+			if !strings.Contains(next(), "NEW") {
+				return rule, false
+			}
+		case "--syn":
+			// New TCP connections always start with a SYN
+		case "--comment", "--reject-with":
+			next()
+		case "-j", "--jump":
+			switch target := next(); target {
+			case "ACCEPT":
+				rule.Action = firewallAllow
+			case "DROP", "REJECT":
+				rule.Action = firewallDeny
+			case "RETURN":
+				rule.Action = firewallReturn
+			default:
+				// Only jumps to user-defined chains are kept by the caller
+				rule.Action = firewallJump
+				rule.Target = target
+			}
+		default:
+			condition(args[i])
+		}
+	}
+
+	return rule, rule.Action != ""
+}
+
+// reachableRules keeps the rules of the start chains and of every chain
+// reachable from them through jumps, preserving their order
+func reachableRules(rules []model.FirewallRule, starts []string) []model.FirewallRule {
+	reachable := make(map[string]bool)
+	queue := append([]string{}, starts...)
+	for len(queue) > 0 {
+		chain := queue[0]
+		queue = queue[1:]
+		if reachable[chain] {
+			continue
+		}
+		reachable[chain] = true
+		for _, rule := range rules {
+			if rule.Chain == chain && rule.Action == firewallJump {
+				queue = append(queue, rule.Target)
+			}
+		}
+	}
+
+	kept := []model.FirewallRule{}
+	for _, rule := range rules {
+		if reachable[rule.Chain] {
+			kept = append(kept, rule)
+		}
+	}
+	return kept
+}
+
+// firewalldZone is the XML structure of a firewalld zone file
+type firewalldZone struct {
+	Target   string `xml:"target,attr"`
+	Services []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"service"`
+	Ports   []firewalldPort `xml:"port"`
+	Sources []struct {
+		Address string `xml:"address,attr"`
+	} `xml:"source"`
+	Interfaces []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"interface"`
+	Rules []struct {
+		Source *struct {
+			Address string `xml:"address,attr"`
+		} `xml:"source"`
+		Service *struct {
+			Name string `xml:"name,attr"`
+		} `xml:"service"`
+		Port   *firewalldPort `xml:"port"`
+		Accept *struct{}      `xml:"accept"`
+		Drop   *struct{}      `xml:"drop"`
+		Reject *struct{}      `xml:"reject"`
+	} `xml:"rule"`
+}
+
+// firewalldPort is a port element in firewalld zone and service files
+type firewalldPort struct {
+	Protocol string `xml:"protocol,attr"`
+	Port     string `xml:"port,attr"`
+}
+
+// collectFirewalldRules reads the default zone and every zone bound to interfaces or sources
+func collectFirewalldRules(firewall *model.FirewallInfo, chains *[]inputChain, logger *log.Logger) bool {
+	if !pathExists("/etc/firewalld") {
+		return false
+	}
+	if getServiceStatus("firewalld", "", logger) != "Running" {
+		logger.Println("firewalld is configured but not running")
+		return false
+	}
+
+	defaultZone := "public"
+	if conf := parseShellAssignments("/etc/firewalld/firewalld.conf"); conf["DefaultZone"] != "" {
+		defaultZone = conf["DefaultZone"]
+	}
+
+	// Administrator zones override the shipped defaults
+	zoneFiles := make(map[string]string)
+	for _, dir := range []string{"/usr/lib/firewalld/zones", "/etc/firewalld/zones"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.xml"))
+		for _, match := range matches {
+			zoneFiles[strings.TrimSuffix(filepath.Base(match), ".xml")] = match
+		}
+	}
+
+	policy := ""
+	for name, path := range zoneFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var zone firewalldZone
+		if err := xml.Unmarshal(data, &zone); err != nil {
+			logger.Printf("Error parsing firewalld zone %s: %v", path, err)
+			continue
+		}
+
+		// Only zones that can receive traffic matter
+		if name != defaultZone && len(zone.Interfaces) == 0 && len(zone.Sources) == 0 {
+			continue
+		}
+
+		rules, chain := firewalldZoneRules(name, zone)
+		firewall.Rules = append(firewall.Rules, rules...)
+		*chains = append(*chains, chain)
+		policy = strictestPolicy(policy, chain.policy)
+	}
+
+	firewall.Backends = append(firewall.Backends, model.FirewallBackend{Name: "firewalld", InputPolicy: policy})
+	return true
+}
+
+// firewalldZoneRules converts a zone into rules, in the order firewalld
+// evaluates them, and the input chain the zone represents
+func firewalldZoneRules(name string, zone firewalldZone) ([]model.FirewallRule, inputChain) {
+	var rules []model.FirewallRule
+
+	sources := []string{"any"}
+	if len(zone.Sources) > 0 {
+		sources = nil
+		for _, source := range zone.Sources {
+			sources = append(sources, source.Address)
+		}
+	}
+
+	// Rich rules come first so that their denials override open ports
+	for _, rich := range zone.Rules {
+		rule := model.FirewallRule{Backend: "firewalld", Chain: name, Protocol: "any", Port: "any", Source: "any"}
+		switch {
+		case rich.Accept != nil:
+			rule.Action = firewallAllow
+		case rich.Drop != nil, rich.Reject != nil:
+			rule.Action = firewallDeny
+		default:
+			continue
+		}
+		if rich.Source != nil {
+			rule.Source = rich.Source.Address
+		}
+		richPorts := []firewalldPort{}
+		if rich.Port != nil {
+			richPorts = append(richPorts, *rich.Port)
+		}
+		if rich.Service != nil {
+			richPorts = append(richPorts, firewalldServicePorts(rich.Service.Name)...)
+		}
+		if len(richPorts) == 0 {
+			rules = append(rules, rule)
+		}
+		for _, port := range richPorts {
+			rule.Protocol = port.Protocol
+			rule.Port = port.Port
+			rules = append(rules, rule)
+		}
+	}
+
+	var ports []firewalldPort
+	ports = append(ports, zone.Ports...)
+	for _, service := range zone.Services {
+		ports = append(ports, firewalldServicePorts(service.Name)...)
+	}
+	for _, source := range sources {
+		for _, port := range ports {
+			rules = append(rules, model.FirewallRule{
+				Backend:  "firewalld",
+				Chain:    name,
+				Action:   firewallAllow,
+				Protocol: port.Protocol,
+				Port:     port.Port,
+				Source:   source,
+			})
+		}
+	}
+
+	// Zones with the default target reject unmatched traffic
+	chain := inputChain{backend: "firewalld", chain: name, policy: "reject", zone: true}
+	switch strings.ToUpper(zone.Target) {
+	case "ACCEPT":
+		chain.policy = "accept"
+	case "DROP":
+		chain.policy = "drop"
+	}
+	for _, source := range zone.Sources {
+		chain.sources = append(chain.sources, source.Address)
+	}
+	return rules, chain
+}
+
+// firewalldServiceDirs hold firewalld service definitions, administrator ones first
+var firewalldServiceDirs = []string{"/etc/firewalld/services", "/usr/lib/firewalld/services"}
+
+// firewalldServicePorts resolves a firewalld service name to its ports
+func firewalldServicePorts(name string) []firewalldPort {
+	for _, dir := range firewalldServiceDirs {
+		data, err := os.ReadFile(filepath.Join(dir, name+".xml"))
+		if err != nil {
+			continue
+		}
+		var service struct {
+			Ports []firewalldPort `xml:"port"`
+		}
+		if xml.Unmarshal(data, &service) == nil {
+			return service.Ports
+		}
+	}
+	return nil
+}
+
+// collectUFWRules reads ufw's rule tuples from user.rules and user6.rules
+func collectUFWRules(firewall *model.FirewallInfo, chains *[]inputChain, logger *log.Logger) bool {
+	if conf := parseShellAssignments("/etc/ufw/ufw.conf"); !strings.EqualFold(conf["ENABLED"], "yes") {
+		return false
+	}
+
+	policy := "drop"
+	if defaults := parseShellAssignments("/etc/default/ufw"); defaults["DEFAULT_INPUT_POLICY"] != "" {
+		policy = strings.ToLower(defaults["DEFAULT_INPUT_POLICY"])
+	}
+	firewall.Backends = append(firewall.Backends, model.FirewallBackend{Name: "ufw", InputPolicy: policy})
+
+	files := []struct {
+		path   string
+		chain  string
+		family string
+	}{
+		{"/etc/ufw/user.rules", "ufw-user-input", "ipv4"},
+		{"/etc/ufw/user6.rules", "ufw6-user-input", "ipv6"},
+	}
+	for _, file := range files {
+		*chains = append(*chains, inputChain{backend: "ufw", chain: file.chain, family: file.family, policy: policy})
+
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			logger.Printf("Error reading %s: %v", file.path, err)
+			continue
+		}
+
+		firewall.Rules = append(firewall.Rules, parseUFWRules(string(data), file.chain)...)
+	}
+	return true
+}
+
+// parseUFWRules reads the inbound rule tuples ufw records in user.rules
+func parseUFWRules(text, chain string) []model.FirewallRule {
+	var rules []model.FirewallRule
+	for _, line := range strings.Split(text, "\n") {
+		// ### tuple ### allow tcp 22 0.0.0.0/0 any 0.0.0.0/0 in
+		rest, ok := strings.CutPrefix(line, "### tuple ### ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 7 || fields[len(fields)-1] != "in" && !strings.HasPrefix(fields[len(fields)-1], "in_") {
+			continue
+		}
+
+		rule := model.FirewallRule{
+			Backend:  "ufw",
+			Chain:    chain,
+			Action:   firewallDeny,
+			Protocol: fields[1],
+			Port:     strings.ReplaceAll(fields[2], ":", "-"),
+			Source:   fields[5],
+		}
+		if fields[0] == "allow" || fields[0] == "limit" {
+			rule.Action = firewallAllow
+		}
+		if rule.Source == "0.0.0.0/0" || rule.Source == "::/0" {
+			rule.Source = "any"
+		}
+		// Rules bound to an interface (in_eth0) only cover part of the traffic
+		if iface, ok := strings.CutPrefix(fields[len(fields)-1], "in_"); ok {
+			rule.Conditions = []string{"in on " + iface}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// strictestPolicy combines two default policies, preferring the one that blocks
+func strictestPolicy(current, candidate string) string {
+	candidate = strings.ToLower(candidate)
+	if candidate == "drop" || candidate == "reject" || current == "" {
+		if current == "drop" || current == "reject" {
+			return current
+		}
+		return candidate
+	}
+	return current
+}
+
+// exposureRank orders exposure states from most to least reachable
+var exposureRank = map[string]int{exposureExposed: 2, exposureRestricted: 1, exposureFiltered: 0}
+
+// markListenerExposure decides per listener whether remote clients can reach it
+func markListenerExposure(listeners []model.Listener, rules []model.FirewallRule, chains []inputChain) {
+	for i := range listeners {
+		listener := &listeners[i]
+		if isLoopbackAddress(listener.Address) {
+			listener.Exposure = exposureLocal
+			continue
+		}
+
+		// Wildcard IPv6 sockets also accept IPv4 connections
+		ip := net.ParseIP(listener.Address)
+		switch {
+		case ip != nil && ip.To4() != nil:
+			listener.Exposure = familyExposure(listener, "ipv4", rules, chains)
+		case ip != nil && ip.IsUnspecified():
+			listener.Exposure = leastRestrictive(
+				familyExposure(listener, "ipv4", rules, chains),
+				familyExposure(listener, "ipv6", rules, chains))
+		default:
+			listener.Exposure = familyExposure(listener, "ipv6", rules, chains)
+		}
+	}
+}
+
+// familyExposure combines the verdicts of the input chains of one address
+// family. Packets must pass every base chain of every backend, but only one
+// firewalld zone.
+func familyExposure(listener *model.Listener, family string, rules []model.FirewallRule, chains []inputChain) string {
+	exposure := exposureExposed
+	zones := ""
+	for _, chain := range chains {
+		if chain.family != "" && chain.family != family {
+			continue
+		}
+		verdict := chainExposure(chain, listener, rules)
+		if chain.zone {
+			zones = leastRestrictive(zones, verdict)
+			continue
+		}
+		exposure = mostRestrictive(exposure, verdict)
+	}
+	if zones != "" {
+		exposure = mostRestrictive(exposure, zones)
+	}
+	return exposure
+}
+
+// chainExposure walks an input chain for a listener and falls back to the
+// chain policy when no rule decides for every source address
+func chainExposure(chain inputChain, listener *model.Listener, rules []model.FirewallRule) string {
+	allowedSome := false
+	// Zones bound to sources only ever see part of the address space
+	verdict := walkChain(rules, chain.backend, chain.chain, listener, len(chain.sources) > 0, 0, &allowedSome)
+	if verdict == "" {
+		verdict = firewallAllow
+		if chain.policy == "drop" || chain.policy == "reject" {
+			verdict = firewallDeny
+		}
+	}
+
+	switch {
+	case verdict == firewallAllow && len(chain.sources) == 0:
+		return exposureExposed
+	case verdict == firewallAllow || allowedSome:
+		return exposureRestricted
+	default:
+		return exposureFiltered
+	}
+}
+
+// walkChain evaluates the rules of a chain in order, following jumps. It
+// returns the action of the first rule that matches the listener for every
+// source address, or "" when packets fall through the chain or return from it.
+// Rules limited to some sources or by other conditions, or reached through such
+// a rule, never end the walk; an accepting one sets allowedSome.
+func walkChain(rules []model.FirewallRule, backend, chain string, listener *model.Listener, partial bool, depth int, allowedSome *bool) string {
+	if depth > maxChainDepth {
+		return ""
+	}
+
+	for _, rule := range rules {
+		if rule.Backend != backend || rule.Chain != chain || rule.Saved || !firewallRuleMatches(rule, listener) {
+			continue
+		}
+		// Rules with unmodelled conditions may not match every packet either
+		rulePartial := partial || rule.Source != "any" || len(rule.Conditions) > 0
+
+		switch rule.Action {
+		case firewallAllow:
+			if rulePartial {
+				*allowedSome = true
+				continue
+			}
+			return firewallAllow
+		case firewallDeny:
+			if !rulePartial {
+				return firewallDeny
+			}
+		case firewallJump:
+			if verdict := walkChain(rules, backend, rule.Target, listener, rulePartial, depth+1, allowedSome); verdict != "" {
+				return verdict
+			}
+		case firewallReturn:
+			if !rulePartial {
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+// mostRestrictive returns the less reachable of two exposure states
+func mostRestrictive(a, b string) string {
+	if exposureRank[b] < exposureRank[a] {
+		return b
+	}
+	return a
+}
+
+// leastRestrictive returns the more reachable of two exposure states, treating
+// an empty state as unset
+func leastRestrictive(a, b string) string {
+	if a == "" || exposureRank[b] > exposureRank[a] {
+		return b
+	}
+	return a
+}
+
+// firewallRuleMatches reports whether a rule applies to a listener's protocol and port
+func firewallRuleMatches(rule model.FirewallRule, listener *model.Listener) bool {
+	// nft sets such as "meta l4proto { tcp, udp }" list several protocols
+	if rule.Protocol != "any" && rule.Protocol != "all" && !containsString(strings.Split(rule.Protocol, ","), listener.Protocol) {
+		return false
+	}
+	if rule.Port == "any" {
+		return true
+	}
+
+	for _, part := range strings.Split(rule.Port, ",") {
+		low, high, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(low)
+		if err != nil {
+			continue
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(high); err != nil {
+				continue
+			}
+		}
+		if listener.Port >= lo && listener.Port <= hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,364 @@
+package collector
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// iptablesSaveFixture is iptables-save output with a DROP policy, a user
+// chain reached from INPUT and one that is never jumped to
+const iptablesSaveFixture = `# Generated by iptables-save v1.8.9 (nf_tables) on Sat Oct 17 09:12:44 2026
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+-A PREROUTING -p tcp -m tcp --dport 8080 -j DNAT --to-destination 10.0.0.2:80
+COMMIT
+*filter
+:INPUT DROP [0:0]
+:FORWARD DROP [0:0]
+:OUTPUT ACCEPT [0:0]
+:services - [0:0]
+:unused - [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -m comment --comment "allow ssh" -j ACCEPT
+-A INPUT -s 10.0.0.0/8 -p tcp -m tcp --dport 5432 -j ACCEPT
+-A INPUT -i eth1 -p tcp -m tcp --dport 9100 -j ACCEPT
+-A INPUT -p tcp -m set --match-set trusted src -m tcp --dport 8443 -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 3306 -j DROP
+-A INPUT -j services
+-A INPUT -j LOG --log-prefix "input drop: "
+-A FORWARD -p tcp -m tcp --dport 80 -j ACCEPT
+-A services -p tcp -m multiport --dports 80,443 -j ACCEPT
+-A services -p tcp -m tcp --dport 25 -j RETURN
+-A unused -p tcp -m tcp --dport 6379 -j ACCEPT
+COMMIT
+`
+
+// nftRulesetFixture is `nft -j list ruleset` output for an inet filter table
+// with a drop policy and a chain the input chain jumps to
+const nftRulesetFixture = `{"nftables": [
+  {"metainfo": {"version": "1.0.6", "release_name": "Lester Gooch #5", "json_schema_version": 1}},
+  {"table": {"family": "inet", "name": "filter", "handle": 1}},
+  {"chain": {"family": "inet", "table": "filter", "name": "input", "handle": 1, "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}},
+  {"chain": {"family": "inet", "table": "filter", "name": "output", "handle": 2, "type": "filter", "hook": "output", "prio": 0, "policy": "accept"}},
+  {"chain": {"family": "inet", "table": "filter", "name": "web", "handle": 3}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 4, "expr": [
+    {"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "lo"}}, {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 5, "expr": [
+    {"match": {"op": "in", "left": {"ct": {"key": "state"}}, "right": ["established", "related"]}}, {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 6, "expr": [
+    {"match": {"op": "==", "left": {"meta": {"key": "l4proto"}}, "right": "tcp"}},
+    {"match": {"op": "==", "left": {"payload": {"protocol": "th", "field": "dport"}}, "right": 22}},
+    {"counter": {"packets": 0, "bytes": 0}}, {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 7, "expr": [
+    {"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "eth1"}},
+    {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 9100}}, {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 8, "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": {"set": [80, 443]}}}, {"jump": {"target": "web"}}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 9, "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "udp", "field": "dport"}}, "right": 53}}, {"drop": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "web", "handle": 10, "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "ip", "field": "saddr"}}, "right": {"prefix": {"addr": "192.168.0.0", "len": 16}}}}, {"accept": null}]}},
+  {"rule": {"family": "inet", "table": "filter", "chain": "output", "handle": 11, "expr": [
+    {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 25}}, {"drop": null}]}}
+]}`
+
+// firewalldPublicZoneFixture is a public zone with services, a port and rich rules
+const firewalldPublicZoneFixture = `<?xml version="1.0" encoding="utf-8"?>
+<zone>
+  <short>Public</short>
+  <service name="ssh"/>
+  <port protocol="tcp" port="8080"/>
+  <rule family="ipv4">
+    <source address="203.0.113.0/24"/>
+    <port protocol="tcp" port="8080"/>
+    <reject/>
+  </rule>
+  <rule family="ipv4">
+    <port protocol="tcp" port="3306"/>
+    <drop/>
+  </rule>
+</zone>`
+
+// firewalldInternalZoneFixture accepts everything from one source range
+const firewalldInternalZoneFixture = `<?xml version="1.0" encoding="utf-8"?>
+<zone target="ACCEPT">
+  <short>Internal</short>
+  <source address="10.0.0.0/8"/>
+</zone>`
+
+// firewalldSSHServiceFixture is the shipped ssh service definition
+const firewalldSSHServiceFixture = `<?xml version="1.0" encoding="utf-8"?>
+<service>
+  <short>SSH</short>
+  <port protocol="tcp" port="22"/>
+</service>`
+
+// ufwUserRulesFixture is /etc/ufw/user.rules as written by ufw
+const ufwUserRulesFixture = `*filter
+:ufw-user-input - [0:0]
+:ufw-user-output - [0:0]
+
+### RULES ###
+
+### tuple ### allow tcp 22 0.0.0.0/0 any 0.0.0.0/0 in
+-A ufw-user-input -p tcp --dport 22 -j ACCEPT
+
+### tuple ### allow tcp 5432 0.0.0.0/0 any 10.0.0.0/8 in
+-A ufw-user-input -p tcp --dport 5432 -s 10.0.0.0/8 -j ACCEPT
+
+### tuple ### deny tcp 3306 0.0.0.0/0 any 0.0.0.0/0 in
+-A ufw-user-input -p tcp --dport 3306 -j DROP
+
+### tuple ### allow tcp 9100 0.0.0.0/0 any 0.0.0.0/0 in_eth1
+-A ufw-user-input -i eth1 -p tcp --dport 9100 -j ACCEPT
+
+### tuple ### limit tcp 2222:2223 0.0.0.0/0 any 0.0.0.0/0 in
+-A ufw-user-input -p tcp --dport 2222:2223 -j ufw-user-limit-accept
+
+### tuple ### allow udp 51820 0.0.0.0/0 any 0.0.0.0/0 out
+-A ufw-user-output -p udp --dport 51820 -j ACCEPT
+
+### END RULES ###
+COMMIT
+`
+
+// exposureCase is a listener and the exposure a firewall should give it
+type exposureCase struct {
+	protocol string
+	address  string
+	port     int
+	want     string
+}
+
+// checkExposure marks the listeners of the cases and compares their exposure
+func checkExposure(t *testing.T, cases []exposureCase, rules []model.FirewallRule, chains []inputChain) {
+	t.Helper()
+	listeners := make([]model.Listener, len(cases))
+	for i, c := range cases {
+		listeners[i] = model.Listener{Protocol: c.protocol, Address: c.address, Port: c.port}
+	}
+	markListenerExposure(listeners, rules, chains)
+	for i, c := range cases {
+		if listeners[i].Exposure != c.want {
+			t.Errorf("%s %s:%d exposure = %q, want %q", c.protocol, c.address, c.port, listeners[i].Exposure, c.want)
+		}
+	}
+}
+
+func TestSplitIptablesLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`-A INPUT -p tcp --dport 22 -j ACCEPT`, []string{"-A", "INPUT", "-p", "tcp", "--dport", "22", "-j", "ACCEPT"}},
+		{`-A INPUT -m comment --comment "allow ssh" -j ACCEPT`, []string{"-A", "INPUT", "-m", "comment", "--comment", "allow ssh", "-j", "ACCEPT"}},
+		{`-A INPUT -m comment --comment "say \"hi\"" -j ACCEPT`, []string{"-A", "INPUT", "-m", "comment", "--comment", `say "hi"`, "-j", "ACCEPT"}},
+		{`-A INPUT -m comment --comment 'single quoted' -j DROP`, []string{"-A", "INPUT", "-m", "comment", "--comment", "single quoted", "-j", "DROP"}},
+		{`-A INPUT   -m comment --comment ""  -j DROP`, []string{"-A", "INPUT", "-m", "comment", "--comment", "", "-j", "DROP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := splitIptablesLine(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitIptablesLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIptablesRule(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want model.FirewallRule
+		ok   bool
+	}{
+		{"quoted comment", `-A INPUT -p tcp -m tcp --dport 22 -m comment --comment "allow ssh" -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "tcp", Port: "22", Source: "any"}, true},
+		{"interface", `-A INPUT -i eth0 -p tcp --dport 80 -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "tcp", Port: "80", Source: "any", Conditions: []string{"-i eth0"}}, true},
+		{"destination", `-A INPUT -d 192.0.2.10/32 -p tcp --dport 443 -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "tcp", Port: "443", Source: "any", Conditions: []string{"-d 192.0.2.10/32"}}, true},
+		{"ipset", `-A INPUT -p tcp -m set --match-set admins src --dport 22 -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "tcp", Port: "22", Source: "any", Conditions: []string{"--match-set admins src"}}, true},
+		{"negated source", `-A INPUT ! -s 10.0.0.0/8 -p tcp --dport 22 -j DROP`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallDeny, Protocol: "tcp", Port: "22", Source: "any", Conditions: []string{"! -s 10.0.0.0/8"}}, true},
+		{"port range", `-A INPUT -p udp -m udp --dport 60000:61000 -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "udp", Port: "60000-61000", Source: "any"}, true},
+		{"new connections", `-A INPUT -p tcp -m conntrack --ctstate NEW -m tcp --syn --dport 25 -j ACCEPT`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallAllow, Protocol: "tcp", Port: "25", Source: "any"}, true},
+		{"user chain", `-A INPUT -j services`,
+			model.FirewallRule{Backend: "iptables", Chain: "INPUT", Action: firewallJump, Target: "services", Protocol: "any", Port: "any", Source: "any"}, true},
+		{"loopback", `-A INPUT -i lo -j ACCEPT`, model.FirewallRule{}, false},
+		{"established", `-A INPUT -m state --state RELATED,ESTABLISHED -j ACCEPT`, model.FirewallRule{}, false},
+		{"no target", `-A INPUT -p tcp --dport 22`, model.FirewallRule{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseIptablesRule(splitIptablesLine(tt.line), "iptables")
+			if ok != tt.ok {
+				t.Fatalf("parseIptablesRule(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIptablesRule(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIptablesExposure(t *testing.T) {
+	rules, policy := parseIptablesSave(iptablesSaveFixture, "iptables", false)
+	if policy != "drop" {
+		t.Errorf("policy = %q, want drop", policy)
+	}
+	for _, rule := range rules {
+		if rule.Chain != "INPUT" && rule.Chain != "services" {
+			t.Errorf("rule of chain %s kept, want only INPUT and the chains it reaches", rule.Chain)
+		}
+	}
+
+	chains := []inputChain{{backend: "iptables", chain: "INPUT", family: "ipv4", policy: policy}}
+	checkExposure(t, []exposureCase{
+		// The rule carries a quoted comment
+		{"tcp", "0.0.0.0", 22, exposureExposed},
+		{"tcp", "0.0.0.0", 5432, exposureRestricted},
+		// Interface and ipset matches are possible allows, not drops
+		{"tcp", "0.0.0.0", 9100, exposureRestricted},
+		{"tcp", "0.0.0.0", 8443, exposureRestricted},
+		{"tcp", "0.0.0.0", 3306, exposureFiltered},
+		// Reached through the services chain
+		{"tcp", "192.0.2.10", 443, exposureExposed},
+		// Returned from services, then the policy applies
+		{"tcp", "0.0.0.0", 25, exposureFiltered},
+		// Only allowed in a chain nothing jumps to
+		{"tcp", "0.0.0.0", 6379, exposureFiltered},
+		{"tcp", "127.0.0.1", 8080, exposureLocal},
+	}, rules, chains)
+
+	// Rules read from a saved file are reported but never decide exposure
+	for i := range rules {
+		rules[i].Saved = true
+	}
+	checkExposure(t, []exposureCase{{"tcp", "0.0.0.0", 22, exposureFiltered}}, rules, chains)
+}
+
+func TestParseNftRule(t *testing.T) {
+	_, rules, _, err := parseNftRuleset([]byte(nftRulesetFixture), false, false)
+	if err != nil {
+		t.Fatalf("parseNftRuleset() error = %v", err)
+	}
+
+	want := []model.FirewallRule{
+		// th dport must not replace the protocol from meta l4proto
+		{Backend: "nftables", Chain: "inet filter/input", Action: firewallAllow, Protocol: "tcp", Port: "22", Source: "any"},
+		{Backend: "nftables", Chain: "inet filter/input", Action: firewallAllow, Protocol: "tcp", Port: "9100", Source: "any", Conditions: []string{"meta iifname == eth1"}},
+		{Backend: "nftables", Chain: "inet filter/input", Action: firewallJump, Target: "inet filter/web", Protocol: "tcp", Port: "80,443", Source: "any"},
+		{Backend: "nftables", Chain: "inet filter/input", Action: firewallDeny, Protocol: "udp", Port: "53", Source: "any"},
+		{Backend: "nftables", Chain: "inet filter/web", Action: firewallAllow, Protocol: "any", Port: "any", Source: "192.168.0.0/16"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules =\n%+v\nwant\n%+v", rules, want)
+	}
+}
+
+func TestNftablesExposure(t *testing.T) {
+	chains, rules, policy, err := parseNftRuleset([]byte(nftRulesetFixture), false, false)
+	if err != nil {
+		t.Fatalf("parseNftRuleset() error = %v", err)
+	}
+	wantChains := []inputChain{{backend: "nftables", chain: "inet filter/input", policy: "drop"}}
+	if !reflect.DeepEqual(chains, wantChains) || policy != "drop" {
+		t.Errorf("chains = %+v, policy %q, want %+v, drop", chains, policy, wantChains)
+	}
+
+	checkExposure(t, []exposureCase{
+		{"tcp", "::", 22, exposureExposed},
+		{"tcp", "0.0.0.0", 9100, exposureRestricted},
+		{"tcp", "0.0.0.0", 443, exposureRestricted},
+		{"udp", "0.0.0.0", 53, exposureFiltered},
+		{"tcp", "0.0.0.0", 8000, exposureFiltered},
+		{"tcp", "::1", 22, exposureLocal},
+	}, rules, chains)
+}
+
+func TestFirewalldExposure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ssh.xml"), []byte(firewalldSSHServiceFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(dirs []string) { firewalldServiceDirs = dirs }(firewalldServiceDirs)
+	firewalldServiceDirs = []string{dir}
+
+	var rules []model.FirewallRule
+	var chains []inputChain
+	for _, zone := range []struct{ name, xml string }{
+		{"public", firewalldPublicZoneFixture},
+		{"internal", firewalldInternalZoneFixture},
+	} {
+		var parsed firewalldZone
+		if err := xml.Unmarshal([]byte(zone.xml), &parsed); err != nil {
+			t.Fatalf("parsing zone %s: %v", zone.name, err)
+		}
+		zoneRules, chain := firewalldZoneRules(zone.name, parsed)
+		rules = append(rules, zoneRules...)
+		chains = append(chains, chain)
+	}
+
+	wantChains := []inputChain{
+		{backend: "firewalld", chain: "public", policy: "reject", zone: true},
+		{backend: "firewalld", chain: "internal", policy: "accept", zone: true, sources: []string{"10.0.0.0/8"}},
+	}
+	if !reflect.DeepEqual(chains, wantChains) {
+		t.Errorf("chains = %+v, want %+v", chains, wantChains)
+	}
+
+	checkExposure(t, []exposureCase{
+		// ssh resolved from the service definition
+		{"tcp", "0.0.0.0", 22, exposureExposed},
+		{"tcp", "0.0.0.0", 8080, exposureExposed},
+		// Dropped in public, but the internal zone accepts its sources
+		{"tcp", "0.0.0.0", 3306, exposureRestricted},
+		{"tcp", "0.0.0.0", 5432, exposureRestricted},
+	}, rules, chains)
+
+	// Without the internal zone, unmatched traffic hits the public zone's reject
+	checkExposure(t, []exposureCase{
+		{"tcp", "0.0.0.0", 3306, exposureFiltered},
+		{"tcp", "0.0.0.0", 5432, exposureFiltered},
+	}, rules, chains[:1])
+}
+
+func TestUFWExposure(t *testing.T) {
+	rules := parseUFWRules(ufwUserRulesFixture, "ufw-user-input")
+	if len(rules) != 5 {
+		t.Fatalf("got %d rules, want 5 inbound tuples", len(rules))
+	}
+	if want := []string{"in on eth1"}; !reflect.DeepEqual(rules[3].Conditions, want) {
+		t.Errorf("interface rule conditions = %q, want %q", rules[3].Conditions, want)
+	}
+
+	chains := []inputChain{
+		{backend: "ufw", chain: "ufw-user-input", family: "ipv4", policy: "drop"},
+		{backend: "ufw", chain: "ufw6-user-input", family: "ipv6", policy: "drop"},
+	}
+	checkExposure(t, []exposureCase{
+		{"tcp", "0.0.0.0", 22, exposureExposed},
+		{"tcp", "0.0.0.0", 5432, exposureRestricted},
+		{"tcp", "0.0.0.0", 3306, exposureFiltered},
+		{"tcp", "0.0.0.0", 9100, exposureRestricted},
+		{"tcp", "0.0.0.0", 2223, exposureExposed},
+		// Outbound tuples do not open anything
+		{"udp", "0.0.0.0", 51820, exposureFiltered},
+		// user6.rules is empty, so IPv6 clients only meet the policy
+		{"tcp", "::", 22, exposureExposed},
+		{"tcp", "2001:db8::1", 22, exposureFiltered},
+	}, rules, chains)
+}
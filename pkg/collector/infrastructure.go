@@ -0,0 +1,205 @@
+package collector
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// DetectInfrastructureServices identifies DNS, DHCP, directory and file sharing servers
+func DetectInfrastructureServices(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting DNS, DHCP and directory services")
+
+	// Detect DNS servers
+	detectBind(report, logger)
+	detectUnbound(report, logger)
+	detectDnsmasq(report, logger)
+
+	// Detect DHCP servers
+	detectISCDHCP(report, logger)
+	detectKea(report, logger)
+
+	// Detect directory and file sharing services
+	detectSlapd(report, logger)
+	detectSamba(report, logger)
+
+	logger.Printf("Detected %d infrastructure services", len(report.Infrastructure))
+}
+
+// braceStatement is a statement in the named.conf / dhcpd.conf syntax: words
+// terminated by ";" or followed by a "{ ... }" block
+type braceStatement struct {
+	args  []string
+	block []braceStatement
+}
+
+// name returns the statement keyword
+func (s braceStatement) name() string {
+	if len(s.args) == 0 {
+		return ""
+	}
+	return s.args[0]
+}
+
+// value returns the first argument after the keyword
+func (s braceStatement) value() string {
+	if len(s.args) < 2 {
+		return ""
+	}
+	return s.args[1]
+}
+
+// find returns the first sub-statement with the given keyword
+func (s braceStatement) find(keyword string) (braceStatement, bool) {
+	for _, statement := range s.block {
+		if statement.name() == keyword {
+			return statement, true
+		}
+	}
+	return braceStatement{}, false
+}
+
+// readBraceConfig parses a named.conf or dhcpd.conf style file, expanding include statements
+func readBraceConfig(path string, depth int, logger *log.Logger) []braceStatement {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading %s: %v", path, err)
+		return nil
+	}
+	if depth > 8 {
+		logger.Printf("Include nesting too deep at %s", path)
+		return nil
+	}
+
+	tokens := tokenizeBraceConfig(string(data))
+	pos := 0
+	statements := parseBraceStatements(tokens, &pos)
+
+	var expanded []braceStatement
+	for _, statement := range statements {
+		if statement.name() == "include" && statement.value() != "" {
+			include := statement.value()
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			expanded = append(expanded, readBraceConfig(include, depth+1, logger)...)
+			continue
+		}
+		expanded = append(expanded, statement)
+	}
+	return expanded
+}
+
+// tokenizeBraceConfig splits text into words, quoted strings and the
+// punctuation "{", "}" and ";", dropping //, # and /* */ comments
+func tokenizeBraceConfig(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			flush()
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				end = len(text) - i - 1
+			}
+			tokens = append(tokens, text[i+1:i+1+end])
+			i += end + 1
+		case c == '#' || (c == '/' && i+1 < len(text) && text[i+1] == '/'):
+			flush()
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			flush()
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '{' || c == '}' || c == ';':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseBraceStatements builds statements from tokens until a closing brace or the end
+func parseBraceStatements(tokens []string, pos *int) []braceStatement {
+	var statements []braceStatement
+	var current braceStatement
+
+	for *pos < len(tokens) {
+		token := tokens[*pos]
+		*pos++
+
+		switch token {
+		case ";":
+			if len(current.args) > 0 || current.block != nil {
+				statements = append(statements, current)
+			}
+			current = braceStatement{}
+		case "{":
+			current.block = parseBraceStatements(tokens, pos)
+			if current.block == nil {
+				current.block = []braceStatement{}
+			}
+			// dhcpd.conf blocks are not followed by a semicolon
+			if *pos >= len(tokens) || tokens[*pos] != ";" {
+				statements = append(statements, current)
+				current = braceStatement{}
+			}
+		case "}":
+			if len(current.args) > 0 {
+				statements = append(statements, current)
+			}
+			return statements
+		default:
+			current.args = append(current.args, token)
+		}
+	}
+
+	if len(current.args) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+// readKeyValueConfig reads "key=value" or "key: value" lines, keeping repeated keys in order
+func readKeyValueConfig(path, separator string) ([][2]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries [][2]string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if comment := strings.Index(line, " #"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+		key, value, _ := strings.Cut(line, separator)
+		entries = append(entries, [2]string{strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), "\"")})
+	}
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// procNetFiles maps /proc/net socket tables to the protocol they describe
+var procNetFiles = []struct {
+	path     string
+	protocol string
+}{
+	{"/proc/net/tcp", "tcp"},
+	{"/proc/net/tcp6", "tcp"},
+	{"/proc/net/udp", "udp"},
+	{"/proc/net/udp6", "udp"},
+}
+
+// Socket states in /proc/net tables
+const (
+	tcpStateListen = "0A"
+	udpStateClosed = "07"
+)
+
+// DetectListeners finds listening TCP and UDP sockets from the kernel socket tables
+func DetectListeners(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting listening sockets")
+
+	processes := mapSocketInodesToProcesses()
+	seen := make(map[string]bool)
+
+	for _, table := range procNetFiles {
+		data, err := os.ReadFile(table.path)
+		if err != nil {
+			continue
+		}
+
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) < 10 {
+				continue
+			}
+
+			state := fields[3]
+			if (table.protocol == "tcp" && state != tcpStateListen) ||
+				(table.protocol == "udp" && state != udpStateClosed) {
+				continue
+			}
+
+			address, port, ok := parseProcNetAddress(fields[1])
+			if !ok {
+				continue
+			}
+
+			key := table.protocol + "|" + address + "|" + strconv.Itoa(port)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			report.Listeners = append(report.Listeners, model.Listener{
+				Protocol: table.protocol,
+				Address:  address,
+				Port:     port,
+				Process:  processes[fields[9]],
+			})
+		}
+	}
+
+	sort.Slice(report.Listeners, func(i, j int) bool {
+		a, b := report.Listeners[i], report.Listeners[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Address < b.Address
+	})
+
+	logger.Printf("Detected %d listening sockets", len(report.Listeners))
+}
+
+// parseProcNetAddress decodes a hex "ADDR:PORT" pair. Addresses are stored as
+// 32-bit words in host byte order, which is little-endian on supported platforms.
+func parseProcNetAddress(text string) (string, int, bool) {
+	addrHex, portHex, ok := strings.Cut(text, ":")
+	if !ok {
+		return "", 0, false
+	}
+
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, false
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+	return ip.String(), int(port), true
+}
+
+// mapSocketInodesToProcesses maps socket inodes to the name of the process holding them
+func mapSocketInodesToProcesses() map[string]string {
+	processes := make(map[string]string)
+
+	fdDirs, _ := filepath.Glob("/proc/[0-9]*/fd")
+	for _, fdDir := range fdDirs {
+		entries, err := os.ReadDir(fdDir)
+		if err != nil {
+			// Other users' processes are not readable without root
+			continue
+		}
+
+		pidDir := filepath.Dir(fdDir)
+		name := ""
+		for _, entry := range entries {
+			target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			if name == "" {
+				comm, _ := os.ReadFile(filepath.Join(pidDir, "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			processes[inode] = name
+		}
+	}
+	return processes
+}
+
+// isLoopbackAddress reports whether a listener only accepts local connections
+func isLoopbackAddress(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,435 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// postfixParameters are the main.cf settings reported for Postfix
+var postfixParameters = []string{"myhostname", "relayhost", "inet_interfaces", "smtpd_tls_cert_file", "smtpd_tls_chain_files", "smtp_tls_cert_file"}
+
+// eximOptions are the main configuration options reported for Exim
+var eximOptions = []string{"primary_hostname", "local_interfaces", "daemon_smtp_ports", "tls_certificate"}
+
+// DetectMailServers identifies installed MTAs and IMAP/POP3 servers
+func DetectMailServers(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting mail servers")
+
+	// Detect Postfix
+	detectPostfix(report, logger)
+
+	// Detect Exim
+	detectExim(report, logger)
+
+	// Detect Sendmail
+	detectSendmail(report, logger)
+
+	// Detect Dovecot
+	detectDovecot(report, logger)
+
+	logger.Printf("Detected %d mail servers", len(report.MailServers))
+}
+
+// detectPostfix reads main.cf and master.cf, preferring postconf for effective values
+func detectPostfix(report *model.DiscoveryReport, logger *log.Logger) {
+	configDir := findExistingPath([]string{"/etc/postfix", "/usr/local/etc/postfix"})
+	if _, err := exec.LookPath("postconf"); err != nil && configDir == "" {
+		logger.Println("Postfix not found")
+		return
+	}
+	if configDir == "" {
+		configDir = "/etc/postfix"
+	}
+
+	mailServer := model.MailServer{
+		Type:       "Postfix",
+		Status:     getServiceStatus("postfix", "master", logger),
+		ConfigFile: filepath.Join(configDir, "main.cf"),
+	}
+
+	// postconf includes built-in defaults such as myhostname; fall back to main.cf
+	var params map[string]string
+	if output, err := exec.Command("postconf", postfixParameters...).Output(); err == nil {
+		params = parsePostfixParameters(string(output))
+	} else if data, err := os.ReadFile(mailServer.ConfigFile); err == nil {
+		params = parsePostfixParameters(string(data))
+	} else {
+		logger.Printf("Error reading Postfix configuration %s: %v", mailServer.ConfigFile, err)
+		params = map[string]string{}
+	}
+
+	mailServer.Hostname = params["myhostname"]
+	mailServer.RelayHost = params["relayhost"]
+	mailServer.ListenAddresses = splitMailList(params["inet_interfaces"])
+	if len(mailServer.ListenAddresses) == 0 {
+		mailServer.ListenAddresses = []string{"all"}
+	}
+	for _, key := range []string{"smtpd_tls_cert_file", "smtpd_tls_chain_files", "smtp_tls_cert_file"} {
+		for _, file := range splitMailList(params[key]) {
+			mailServer.TLSCertificates = appendUnique(mailServer.TLSCertificates, file)
+		}
+	}
+
+	// master.cf: service type private unpriv chroot wakeup maxproc command
+	if data, err := os.ReadFile(filepath.Join(configDir, "master.cf")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" || strings.HasPrefix(line, "#") || line[0] == ' ' || line[0] == '\t' {
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[1] == "inet" {
+				mailServer.Listeners = append(mailServer.Listeners, fields[0])
+			}
+		}
+	}
+	mailServer.Protocols = []string{"smtp"}
+
+	report.MailServers = append(report.MailServers, mailServer)
+	logger.Printf("Detected Postfix: status=%s, relayhost=%s, inet_interfaces=%s",
+		mailServer.Status, mailServer.RelayHost, strings.Join(mailServer.ListenAddresses, ","))
+}
+
+// parsePostfixParameters parses "name = value" lines as written in main.cf and
+// printed by postconf. Lines starting with whitespace continue the previous value.
+func parsePostfixParameters(text string) map[string]string {
+	params := make(map[string]string)
+	last := ""
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && last != "" {
+			params[last] += " " + strings.TrimSpace(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		last = strings.TrimSpace(key)
+		params[last] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// detectExim asks exim for its effective options, falling back to the main section of its config
+func detectExim(report *model.DiscoveryReport, logger *log.Logger) {
+	binary := ""
+	for _, name := range []string{"exim4", "exim"} {
+		if _, err := exec.LookPath(name); err == nil {
+			binary = name
+			break
+		}
+	}
+	if binary == "" {
+		logger.Println("Exim not found")
+		return
+	}
+
+	mailServer := model.MailServer{
+		Type:      "Exim",
+		Status:    getServiceStatus("exim4", "exim", logger),
+		Protocols: []string{"smtp"},
+	}
+
+	// "exim -bV" names the configuration file in use
+	if output, err := exec.Command(binary, "-bV").Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			if path, ok := strings.CutPrefix(line, "Configuration file is "); ok {
+				mailServer.ConfigFile = strings.TrimSpace(path)
+			}
+		}
+	}
+	if mailServer.ConfigFile == "" {
+		mailServer.ConfigFile = findExistingPath([]string{
+			"/var/lib/exim4/config.autogenerated", // Debian/Ubuntu generated config
+			"/etc/exim4/exim4.conf",
+			"/etc/exim/exim.conf", // RHEL/Fedora
+			"/etc/exim.conf",
+			"/usr/local/etc/exim/configure", // FreeBSD
+		})
+	}
+
+	var options map[string]string
+	if output, err := exec.Command(binary, append([]string{"-bP"}, eximOptions...)...).Output(); err == nil {
+		options = parsePostfixParameters(string(output))
+	} else {
+		options = parseEximMainSection(mailServer.ConfigFile)
+	}
+
+	mailServer.Hostname = options["primary_hostname"]
+	mailServer.ListenAddresses = splitEximList(options["local_interfaces"])
+	if ports := splitEximList(options["daemon_smtp_ports"]); len(ports) > 0 {
+		mailServer.Listeners = ports
+	} else {
+		mailServer.Listeners = []string{"smtp"}
+	}
+	if certificate := options["tls_certificate"]; certificate != "" {
+		mailServer.TLSCertificates = []string{certificate}
+	}
+
+	// Debian's debconf-driven setup records the smarthost separately
+	debconf := parseShellAssignments("/etc/exim4/update-exim4.conf.conf")
+	mailServer.RelayHost = debconf["dc_smarthost"]
+	if mailServer.RelayHost == "" {
+		mailServer.RelayHost = findEximSmarthost(mailServer.ConfigFile)
+	}
+	if len(mailServer.ListenAddresses) == 0 && debconf["dc_local_interfaces"] != "" {
+		mailServer.ListenAddresses = splitEximList(debconf["dc_local_interfaces"])
+	}
+
+	report.MailServers = append(report.MailServers, mailServer)
+	logger.Printf("Detected Exim: status=%s, config=%s", mailServer.Status, mailServer.ConfigFile)
+}
+
+// parseEximMainSection reads "option = value" lines before the first "begin" section
+func parseEximMainSection(path string) map[string]string {
+	options := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return options
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "begin ") {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return options
+}
+
+// findEximSmarthost looks for a catch-all manualroute router, the usual way to relay all mail
+func findEximSmarthost(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(key) != "route_list" {
+			continue
+		}
+		// route_list = * smarthost.example.com
+		if fields := strings.Fields(value); len(fields) >= 2 && fields[0] == "*" {
+			return strings.Trim(fields[1], "\"")
+		}
+	}
+	return ""
+}
+
+// splitEximList splits a colon-separated Exim list; "<; " switches the separator to semicolons
+func splitEximList(value string) []string {
+	separator := ":"
+	if rest, ok := strings.CutPrefix(value, "<;"); ok {
+		separator = ";"
+		value = rest
+	}
+	var items []string
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// detectSendmail reads sendmail.cf. Postfix and Exim also install a sendmail
+// binary, so only the presence of sendmail.cf counts.
+func detectSendmail(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/mail/sendmail.cf", "/etc/sendmail.cf"})
+	if configFile == "" {
+		logger.Println("Sendmail not found")
+		return
+	}
+
+	mailServer := model.MailServer{
+		Type:       "Sendmail",
+		Status:     getServiceStatus("sendmail", "", logger),
+		ConfigFile: configFile,
+		Protocols:  []string{"smtp"},
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		logger.Printf("Error reading Sendmail configuration %s: %v", configFile, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "Dj"):
+			// Dj overrides the canonical hostname
+			mailServer.Hostname = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "DS"):
+			// DS is the smart relay host
+			mailServer.RelayHost = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "O DaemonPortOptions="):
+			// O DaemonPortOptions=Port=smtp,Addr=127.0.0.1, Name=MTA
+			options := parseSendmailDaemonOptions(strings.TrimPrefix(line, "O DaemonPortOptions="))
+			port := options["Port"]
+			if port == "" {
+				port = "smtp"
+			}
+			mailServer.Listeners = append(mailServer.Listeners, fmt.Sprintf("%s:%s", options["Name"], port))
+			if address := options["Addr"]; address != "" {
+				mailServer.ListenAddresses = appendUnique(mailServer.ListenAddresses, address)
+			}
+		case strings.HasPrefix(line, "O ServerCertFile="):
+			mailServer.TLSCertificates = append(mailServer.TLSCertificates, strings.TrimPrefix(line, "O ServerCertFile="))
+		}
+	}
+
+	report.MailServers = append(report.MailServers, mailServer)
+	logger.Printf("Detected Sendmail: status=%s, config=%s", mailServer.Status, mailServer.ConfigFile)
+}
+
+// parseSendmailDaemonOptions splits "Port=smtp,Addr=127.0.0.1, Name=MTA" into its keys
+func parseSendmailDaemonOptions(value string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range strings.Split(value, ",") {
+		if key, val, ok := strings.Cut(strings.TrimSpace(option), "="); ok {
+			options[key] = val
+		}
+	}
+	return options
+}
+
+// detectDovecot reads the effective configuration from doveconf, or the config files when it is unavailable
+func detectDovecot(report *model.DiscoveryReport, logger *log.Logger) {
+	configFile := findExistingPath([]string{"/etc/dovecot/dovecot.conf", "/usr/local/etc/dovecot/dovecot.conf"})
+	if _, err := exec.LookPath("dovecot"); err != nil && configFile == "" {
+		logger.Println("Dovecot not found")
+		return
+	}
+
+	mailServer := model.MailServer{
+		Type:       "Dovecot",
+		Status:     getServiceStatus("dovecot", "", logger),
+		ConfigFile: configFile,
+	}
+
+	// doveconf prints the full configuration with defaults and includes resolved
+	var text string
+	if output, err := exec.Command("doveconf").Output(); err == nil {
+		text = string(output)
+	} else if configFile != "" {
+		text = readDovecotConfig(configFile, 0)
+	}
+
+	settings, listeners := parseDovecotConfig(text)
+	mailServer.Hostname = settings["hostname"]
+	mailServer.Protocols = strings.Fields(settings["protocols"])
+	mailServer.ListenAddresses = splitMailList(settings["listen"])
+	mailServer.MailLocation = settings["mail_location"]
+	mailServer.Listeners = listeners
+	if settings["ssl"] != "no" {
+		if certificate := strings.TrimPrefix(settings["ssl_cert"], "<"); certificate != "" {
+			mailServer.TLSCertificates = []string{certificate}
+		}
+	}
+
+	report.MailServers = append(report.MailServers, mailServer)
+	logger.Printf("Detected Dovecot: status=%s, protocols=%s", mailServer.Status, strings.Join(mailServer.Protocols, " "))
+}
+
+// readDovecotConfig returns a config file with its !include and !include_try directives expanded
+func readDovecotConfig(path string, depth int) string {
+	data, err := os.ReadFile(path)
+	if err != nil || depth > 8 {
+		return ""
+	}
+
+	var expanded strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "!include") {
+			fields := strings.Fields(trimmed)
+			if len(fields) < 2 {
+				continue
+			}
+			pattern := fields[1]
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(path), pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			for _, match := range matches {
+				expanded.WriteString(readDovecotConfig(match, depth+1))
+				expanded.WriteString("\n")
+			}
+			continue
+		}
+		expanded.WriteString(line)
+		expanded.WriteString("\n")
+	}
+	return expanded.String()
+}
+
+// parseDovecotConfig returns top-level settings and the inet listeners of
+// each service as "name:port"
+func parseDovecotConfig(text string) (map[string]string, []string) {
+	settings := make(map[string]string)
+	var listeners []string
+	var sections []string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(line, "{"):
+			sections = append(sections, strings.TrimSpace(strings.TrimSuffix(line, "{")))
+		case line == "}":
+			if len(sections) > 0 {
+				sections = sections[:len(sections)-1]
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+			if len(sections) == 0 {
+				// protocols.d includes extend a setting with "protocols = $protocols imap"
+				settings[key] = strings.TrimSpace(strings.ReplaceAll(value, "$"+key, settings[key]))
+				continue
+			}
+
+			// service imap-login { inet_listener imaps { port = 993 } }
+			current := strings.Fields(sections[len(sections)-1])
+			if key == "port" && len(current) == 2 && current[0] == "inet_listener" && value != "0" {
+				listeners = append(listeners, current[1]+":"+value)
+			}
+		}
+	}
+	return settings, listeners
+}
+
+// splitMailList splits a comma or whitespace separated list
+func splitMailList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// appendUnique appends a value unless the slice already contains it
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
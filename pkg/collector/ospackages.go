@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// DetectOSPackages inventories the packages installed by the distribution's
+// package manager (dpkg, rpm or apk)
+func DetectOSPackages(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting OS packages")
+
+	report.OSPackages = collectOSPackageDatabases("/", logger)
+	report.OSPackages = append(report.OSPackages, collectRPMPackages(logger)...)
+
+	logger.Printf("Detected %d OS packages", len(report.OSPackages))
+}
+
+// collectOSPackageDatabases reads the dpkg and apk databases below root.
+// The rpm database can only be read through rpm itself, so it is not covered.
+func collectOSPackageDatabases(root string, logger *log.Logger) []model.Component {
+	packages := []model.Component{}
+
+	// Distroless images keep one status file per package in status.d
+	dpkgFiles := []string{"/var/lib/dpkg/status"}
+	statusParts, _ := filepath.Glob(filepath.Join(root, "/var/lib/dpkg/status.d/*"))
+	for _, part := range statusParts {
+		if !strings.HasSuffix(part, ".md5sums") {
+			dpkgFiles = append(dpkgFiles, "/var/lib/dpkg/status.d/"+filepath.Base(part))
+		}
+	}
+	for _, file := range dpkgFiles {
+		packages = append(packages, parseDpkgStatus(root, file, logger)...)
+	}
+
+	return append(packages, parseApkInstalled(root, "/lib/apk/db/installed", logger)...)
+}
+
+// readPackageStanzas splits a dpkg or apk database into blank-line separated
+// stanzas of "Key: value" fields, dropping continuation lines
+func readPackageStanzas(path string) ([]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var stanzas []map[string]string
+	stanza := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(stanza) > 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = make(map[string]string)
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			stanza[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(stanza) > 0 {
+		stanzas = append(stanzas, stanza)
+	}
+	return stanzas, scanner.Err()
+}
+
+// parseDpkgStatus returns the installed packages listed in a dpkg status file
+func parseDpkgStatus(root, path string, logger *log.Logger) []model.Component {
+	stanzas, err := readPackageStanzas(filepath.Join(root, path))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Printf("Error reading dpkg database %s: %v", path, err)
+		}
+		return nil
+	}
+
+	var packages []model.Component
+	for _, stanza := range stanzas {
+		// Status files in status.d carry no Status field
+		if status := strings.Fields(stanza["Status"]); len(status) == 3 && status[2] != "installed" {
+			continue
+		}
+		if stanza["Package"] == "" || stanza["Version"] == "" {
+			continue
+		}
+
+		component := model.Component{
+			Ecosystem:    "deb",
+			Name:         stanza["Package"],
+			Version:      stanza["Version"],
+			Architecture: stanza["Architecture"],
+			Location:     path,
+		}
+		// Source is "name" or "name (version)" when the versions differ
+		if source, version, ok := strings.Cut(stanza["Source"], " ("); ok {
+			component.Source = source
+			component.SourceVersion = strings.TrimSuffix(version, ")")
+		} else if stanza["Source"] != "" {
+			component.Source = stanza["Source"]
+		}
+		packages = append(packages, component)
+	}
+	return packages
+}
+
+// parseApkInstalled returns the packages listed in an apk installed database
+func parseApkInstalled(root, path string, logger *log.Logger) []model.Component {
+	stanzas, err := readPackageStanzas(filepath.Join(root, path))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Printf("Error reading apk database %s: %v", path, err)
+		}
+		return nil
+	}
+
+	var packages []model.Component
+	for _, stanza := range stanzas {
+		if stanza["P"] == "" || stanza["V"] == "" {
+			continue
+		}
+		component := model.Component{
+			Ecosystem:    "apk",
+			Name:         stanza["P"],
+			Version:      stanza["V"],
+			Architecture: stanza["A"],
+			Location:     path,
+		}
+		// The origin is the aport the package was built from
+		if stanza["o"] != stanza["P"] {
+			component.Source = stanza["o"]
+		}
+		packages = append(packages, component)
+	}
+	return packages
+}
+
+// collectRPMPackages lists the packages in the host's rpm database
+func collectRPMPackages(logger *log.Logger) []model.Component {
+	if _, err := exec.LookPath("rpm"); err != nil {
+		return nil
+	}
+
+	// The epoch is only printed when the package has one
+	output, err := exec.Command("rpm", "-qa", "--queryformat",
+		"%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{SOURCERPM}\t%{ARCH}\n").Output()
+	if err != nil {
+		logger.Printf("Error querying rpm database: %v", err)
+		return nil
+	}
+
+	var packages []model.Component
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "\t")
+		// gpg-pubkey entries are imported signing keys, not packages
+		if len(fields) != 4 || fields[0] == "" || fields[0] == "gpg-pubkey" {
+			continue
+		}
+		component := model.Component{
+			Ecosystem: "rpm",
+			Name:      fields[0],
+			Version:   fields[1],
+			Location:  "/var/lib/rpm",
+		}
+		// Packages without an architecture, such as gpg keys, print "(none)"
+		if fields[3] != "(none)" {
+			component.Architecture = fields[3]
+		}
+		if source := sourceRPMName(fields[2]); source != fields[0] {
+			component.Source = source
+		}
+		packages = append(packages, component)
+	}
+	return packages
+}
+
+// sourceRPMName extracts the package name from a source rpm file name such
+// as "openssl-3.0.7-24.el9.src.rpm"
+func sourceRPMName(sourceRPM string) string {
+	name := strings.TrimSuffix(sourceRPM, ".src.rpm")
+	if name == sourceRPM {
+		return ""
+	}
+	// Drop the release and then the version
+	for i := 0; i < 2; i++ {
+		dash := strings.LastIndex(name, "-")
+		if dash < 0 {
+			return ""
+		}
+		name = name[:dash]
+	}
+	return name
+}
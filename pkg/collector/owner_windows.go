@@ -0,0 +1,8 @@
+//go:build windows
+
+package collector
+
+// fileOwner is not implemented on Windows, where files have ACL owners rather than uids
+func fileOwner(path string) string {
+	return ""
+}
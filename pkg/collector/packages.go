@@ -0,0 +1,486 @@
+package collector
+
+import (
+	"archive/zip"
+	"bufio"
+	"debug/buildinfo"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// DetectLanguagePackages identifies application-level packages installed by
+// language package managers (pip, npm, gem, Composer, Go modules and Maven)
+func DetectLanguagePackages(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting language packages")
+
+	inventory := newComponentInventory()
+
+	// Collect document roots from detected web servers
+	var docRoots []string
+	for _, webServer := range report.WebServers {
+		docRoots = append(docRoots, webServer.DocumentRoots...)
+	}
+
+	collectLanguagePackages(inventory, "/", docRoots, logger)
+
+	report.Components = append(report.Components, inventory.components...)
+	logger.Printf("Detected %d language packages", len(inventory.components))
+}
+
+// collectLanguagePackages runs every package collector against the filesystem
+// below root. Document roots are host paths, already resolved against root.
+func collectLanguagePackages(inv *componentInventory, root string, docRoots []string, logger *log.Logger) {
+	collectPythonPackages(inv, root, logger)
+	collectNodePackages(inv, root, docRoots, logger)
+	collectRubyGems(inv, root, logger)
+	collectComposerPackages(inv, docRoots, logger)
+	collectGoBinaries(inv, root, logger)
+	collectJavaArchives(inv, root, docRoots, logger)
+}
+
+// componentInventory accumulates components while dropping duplicates
+type componentInventory struct {
+	components []model.Component
+	seen       map[string]bool
+}
+
+// newComponentInventory creates an empty component inventory
+func newComponentInventory() *componentInventory {
+	return &componentInventory{
+		components: []model.Component{},
+		seen:       make(map[string]bool),
+	}
+}
+
+// add records a component unless it is incomplete or already known
+func (inv *componentInventory) add(ecosystem, name, version, location string) {
+	if name == "" {
+		return
+	}
+	key := ecosystem + "|" + name + "|" + version + "|" + location
+	if inv.seen[key] {
+		return
+	}
+	inv.seen[key] = true
+	inv.components = append(inv.components, model.Component{
+		Ecosystem: ecosystem,
+		Name:      name,
+		Version:   version,
+		Location:  location,
+	})
+}
+
+// collectPythonPackages reads *.dist-info and *.egg-info metadata from site-packages directories
+func collectPythonPackages(inv *componentInventory, root string, logger *log.Logger) {
+	sitePatterns := []string{
+		"/usr/lib/python3*/site-packages",
+		"/usr/lib/python3*/dist-packages",
+		"/usr/lib64/python3*/site-packages",
+		"/usr/local/lib/python3*/site-packages",
+		"/usr/local/lib/python3*/dist-packages",
+		"/opt/homebrew/lib/python3*/site-packages", // macOS (Homebrew ARM64)
+	}
+
+	for _, pattern := range sitePatterns {
+		siteDirs, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, siteDir := range siteDirs {
+			entries, err := os.ReadDir(siteDir)
+			if err != nil {
+				logger.Printf("Error reading Python site directory %s: %v", siteDir, err)
+				continue
+			}
+
+			for _, entry := range entries {
+				var metadataFile string
+				switch {
+				case strings.HasSuffix(entry.Name(), ".dist-info"):
+					metadataFile = filepath.Join(siteDir, entry.Name(), "METADATA")
+				case strings.HasSuffix(entry.Name(), ".egg-info"):
+					// egg-info may be a directory or a single PKG-INFO style file
+					metadataFile = filepath.Join(siteDir, entry.Name())
+					if entry.IsDir() {
+						metadataFile = filepath.Join(metadataFile, "PKG-INFO")
+					}
+				default:
+					continue
+				}
+
+				name, version := parsePythonMetadata(metadataFile)
+				inv.add("pypi", name, version, filepath.Join(siteDir, entry.Name()))
+			}
+		}
+	}
+}
+
+// parsePythonMetadata extracts Name and Version headers from a core metadata file
+func parsePythonMetadata(path string) (string, string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	var name, version string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Headers end at the first blank line; the description follows
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Name:"); ok {
+			name = strings.TrimSpace(value)
+		} else if value, ok := strings.CutPrefix(line, "Version:"); ok {
+			version = strings.TrimSpace(value)
+		}
+	}
+	return name, version
+}
+
+// collectNodePackages finds global node_modules and npm projects in document roots
+func collectNodePackages(inv *componentInventory, root string, docRoots []string, logger *log.Logger) {
+	globalDirs := []string{
+		"/usr/lib/node_modules",
+		"/usr/local/lib/node_modules",
+		"/opt/homebrew/lib/node_modules", // macOS (Homebrew ARM64)
+	}
+	for _, dir := range globalDirs {
+		collectNodeModules(inv, filepath.Join(root, dir))
+	}
+
+	for _, docRoot := range docRoots {
+		walkLimited(docRoot, 3, func(path string, entry fs.DirEntry) {
+			switch {
+			case entry.IsDir() && entry.Name() == "node_modules":
+				collectNodeModules(inv, path)
+			case entry.Name() == "package-lock.json":
+				collectPackageLock(inv, path, logger)
+			}
+		})
+	}
+}
+
+// collectNodeModules reads package.json of each top-level package in a node_modules directory
+func collectNodeModules(inv *componentInventory, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// Scoped packages (@scope/name) are nested one level deeper
+		if strings.HasPrefix(entry.Name(), "@") {
+			collectNodeModules(inv, filepath.Join(dir, entry.Name()))
+			continue
+		}
+
+		pkgDir := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+		if err != nil {
+			continue
+		}
+		var manifest struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &manifest); err == nil {
+			inv.add("npm", manifest.Name, manifest.Version, pkgDir)
+		}
+	}
+}
+
+// collectPackageLock reads resolved dependency versions from an npm package-lock.json
+func collectPackageLock(inv *componentInventory, path string, logger *log.Logger) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading %s: %v", path, err)
+		return
+	}
+
+	var lock struct {
+		Packages map[string]struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"packages"`
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		logger.Printf("Error parsing %s: %v", path, err)
+		return
+	}
+
+	// Lockfile v2/v3 lists packages keyed by their node_modules path
+	for key, pkg := range lock.Packages {
+		if key == "" {
+			continue
+		}
+		name := pkg.Name
+		if name == "" {
+			idx := strings.LastIndex(key, "node_modules/")
+			if idx < 0 {
+				continue
+			}
+			name = key[idx+len("node_modules/"):]
+		}
+		inv.add("npm", name, pkg.Version, path)
+	}
+
+	// Lockfile v1 only has the dependencies map
+	if len(lock.Packages) == 0 {
+		for name, dep := range lock.Dependencies {
+			inv.add("npm", name, dep.Version, path)
+		}
+	}
+}
+
+// collectRubyGems derives installed gems from gemspec file names in gem specification directories
+func collectRubyGems(inv *componentInventory, root string, logger *log.Logger) {
+	specPatterns := []string{
+		"/var/lib/gems/*/specifications",
+		"/usr/lib/ruby/gems/*/specifications",
+		"/usr/lib64/ruby/gems/*/specifications",
+		"/usr/share/gems/specifications",
+		"/usr/local/lib/ruby/gems/*/specifications",
+		"/usr/local/share/gems/specifications",
+	}
+
+	for _, pattern := range specPatterns {
+		specDirs, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, specDir := range specDirs {
+			specs, err := filepath.Glob(filepath.Join(specDir, "*.gemspec"))
+			if err != nil {
+				logger.Printf("Error reading gem specifications in %s: %v", specDir, err)
+				continue
+			}
+			for _, spec := range specs {
+				name, version := splitGemSpecName(strings.TrimSuffix(filepath.Base(spec), ".gemspec"))
+				inv.add("gem", name, version, spec)
+			}
+		}
+	}
+}
+
+// splitGemSpecName splits "name-1.2.3[-platform]" into the gem name and version
+func splitGemSpecName(spec string) (string, string) {
+	for i := len(spec) - 2; i > 0; i-- {
+		if spec[i] == '-' && spec[i+1] >= '0' && spec[i+1] <= '9' {
+			version := spec[i+1:]
+			// Drop platform suffixes such as -x86_64-linux
+			if idx := strings.Index(version, "-"); idx > 0 {
+				version = version[:idx]
+			}
+			return spec[:i], version
+		}
+	}
+	return spec, ""
+}
+
+// collectComposerPackages reads composer.lock files in and directly above document roots
+func collectComposerPackages(inv *componentInventory, docRoots []string, logger *log.Logger) {
+	for _, docRoot := range docRoots {
+		// Frameworks usually serve a public/ subdirectory of the project
+		parentLock := filepath.Join(filepath.Dir(docRoot), "composer.lock")
+		if _, err := os.Stat(parentLock); err == nil {
+			parseComposerLock(inv, parentLock, logger)
+		}
+
+		walkLimited(docRoot, 3, func(path string, entry fs.DirEntry) {
+			if !entry.IsDir() && entry.Name() == "composer.lock" {
+				parseComposerLock(inv, path, logger)
+			}
+		})
+	}
+}
+
+// parseComposerLock records packages pinned in a composer.lock file
+func parseComposerLock(inv *componentInventory, path string, logger *log.Logger) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading %s: %v", path, err)
+		return
+	}
+
+	type composerPackage struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages    []composerPackage `json:"packages"`
+		PackagesDev []composerPackage `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		logger.Printf("Error parsing %s: %v", path, err)
+		return
+	}
+
+	for _, pkg := range append(lock.Packages, lock.PackagesDev...) {
+		inv.add("composer", pkg.Name, pkg.Version, path)
+	}
+}
+
+// collectGoBinaries reads module build info embedded in Go executables
+func collectGoBinaries(inv *componentInventory, root string, logger *log.Logger) {
+	binDirs := []string{"/usr/local/bin", "/usr/local/sbin", "/usr/bin", "/usr/sbin", "/opt"}
+
+	for _, dir := range binDirs {
+		walkLimited(filepath.Join(root, dir), 3, func(path string, entry fs.DirEntry) {
+			if entry.IsDir() || !entry.Type().IsRegular() {
+				return
+			}
+			info, err := entry.Info()
+			if err != nil || info.Mode().Perm()&0111 == 0 {
+				return
+			}
+
+			// Non-Go executables simply fail to parse
+			buildInfo, err := buildinfo.ReadFile(path)
+			if err != nil {
+				return
+			}
+
+			logger.Printf("Found Go binary %s built with %s", path, buildInfo.GoVersion)
+			inv.add("golang", "stdlib", strings.TrimPrefix(buildInfo.GoVersion, "go"), path)
+			inv.add("golang", buildInfo.Main.Path, buildInfo.Main.Version, path)
+			for _, dep := range buildInfo.Deps {
+				// Replaced modules are what actually got compiled in
+				if dep.Replace != nil {
+					dep = dep.Replace
+				}
+				inv.add("golang", dep.Path, dep.Version, path)
+			}
+		})
+	}
+}
+
+// collectJavaArchives inspects jar files for Maven coordinates or manifest versions
+func collectJavaArchives(inv *componentInventory, root string, docRoots []string, logger *log.Logger) {
+	var searchDirs []string
+	for _, dir := range []string{"/opt", "/srv", "/usr/share/java", "/usr/local/lib", "/var/lib"} {
+		searchDirs = append(searchDirs, filepath.Join(root, dir))
+	}
+	searchDirs = append(searchDirs, docRoots...)
+
+	for _, dir := range searchDirs {
+		walkLimited(dir, 5, func(path string, entry fs.DirEntry) {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jar") {
+				return
+			}
+			if err := readJarMetadata(inv, path); err != nil {
+				logger.Printf("Error reading jar %s: %v", path, err)
+			}
+		})
+	}
+}
+
+// readJarMetadata records pom.properties coordinates, falling back to MANIFEST.MF
+func readJarMetadata(inv *componentInventory, path string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	var manifest *zip.File
+	foundPom := false
+	for _, file := range archive.File {
+		switch {
+		case strings.HasPrefix(file.Name, "META-INF/maven/") && strings.HasSuffix(file.Name, "/pom.properties"):
+			props := readZipProperties(file, "=")
+			if props["artifactId"] != "" {
+				inv.add("maven", props["groupId"]+":"+props["artifactId"], props["version"], path)
+				foundPom = true
+			}
+		case file.Name == "META-INF/MANIFEST.MF":
+			manifest = file
+		}
+	}
+
+	if foundPom || manifest == nil {
+		return nil
+	}
+
+	attrs := readZipProperties(manifest, ":")
+	name := attrs["Implementation-Title"]
+	version := attrs["Implementation-Version"]
+	if name == "" {
+		name = attrs["Bundle-SymbolicName"]
+		version = attrs["Bundle-Version"]
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), ".jar")
+	}
+	inv.add("maven", name, version, path)
+	return nil
+}
+
+// readZipProperties parses key/value lines from a file inside an archive
+func readZipProperties(file *zip.File, separator string) map[string]string {
+	props := make(map[string]string)
+
+	reader, err := file.Open()
+	if err != nil {
+		return props
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, 1<<20))
+	if err != nil {
+		return props
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, separator, 2)
+		if len(parts) == 2 {
+			props[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return props
+}
+
+// walkLimited walks a directory tree up to maxDepth levels below root,
+// skipping hidden and dependency directories that would explode the search
+func walkLimited(root string, maxDepth int, fn func(path string, entry fs.DirEntry)) {
+	if _, err := os.Stat(root); err != nil {
+		return
+	}
+	baseDepth := strings.Count(filepath.Clean(root), string(filepath.Separator))
+
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fn(path, entry)
+
+		if entry.IsDir() && path != root {
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if strings.Count(path, string(filepath.Separator))-baseDepth >= maxDepth {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+}
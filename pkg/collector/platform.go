@@ -0,0 +1,356 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// Platform types
+const (
+	platformBareMetal = "bare-metal"
+	platformVirtual   = "virtual-machine"
+	platformContainer = "container"
+)
+
+// Cloud providers that have a metadata service client
+const (
+	cloudAWS       = "aws"
+	cloudGCP       = "gcp"
+	cloudAzure     = "azure"
+	cloudOpenStack = "openstack"
+)
+
+// DefaultMetadataEndpoint is the link-local address every supported cloud serves instance metadata on
+const DefaultMetadataEndpoint = "http://169.254.169.254"
+
+// metadataTimeout bounds each metadata request so hosts outside a cloud are not delayed
+const metadataTimeout = 2 * time.Second
+
+// dmiHypervisors maps DMI vendor or product substrings to the hypervisor they identify
+var dmiHypervisors = []struct {
+	match      string
+	hypervisor string
+}{
+	{"QEMU", "kvm"},
+	{"KVM", "kvm"},
+	{"Amazon EC2", "kvm"},
+	{"Google Compute Engine", "kvm"},
+	{"OpenStack", "kvm"},
+	{"DigitalOcean", "kvm"},
+	{"Hetzner", "kvm"},
+	{"VMware", "vmware"},
+	{"VirtualBox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"Virtual Machine", "hyperv"},
+	{"Xen", "xen"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+}
+
+// dmiCloudProviders maps DMI strings to the cloud provider whose images carry them
+var dmiCloudProviders = []struct {
+	match    string
+	provider string
+}{
+	{"Amazon EC2", cloudAWS},
+	{"amazon", cloudAWS},
+	{"Google", cloudGCP},
+	{"7783-7084-3265-9085-8269-3286-77", cloudAzure}, // Azure chassis asset tag
+	{"OpenStack", cloudOpenStack},
+	{"DigitalOcean", "digitalocean"},
+	{"Hetzner", "hetzner"},
+	{"OracleCloud.com", "oracle"},
+	{"Alibaba Cloud", "alibaba"},
+}
+
+// DetectPlatform determines whether the host is bare metal, a virtual machine
+// or a container, and which cloud provider runs it
+func DetectPlatform(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting virtualization and cloud platform")
+
+	dmi := readDMIFields()
+	platform := model.PlatformInfo{
+		Type:    platformBareMetal,
+		Vendor:  dmi["sys_vendor"],
+		Product: dmi["product_name"],
+	}
+
+	// DMI strings identify the hypervisor; cpuinfo only says there is one
+	dmiText := strings.Join([]string{dmi["sys_vendor"], dmi["product_name"], dmi["bios_vendor"], dmi["bios_version"], dmi["board_vendor"], dmi["chassis_asset_tag"]}, "\n")
+	for _, candidate := range dmiHypervisors {
+		if strings.Contains(dmiText, candidate.match) {
+			platform.Hypervisor = candidate.hypervisor
+			break
+		}
+	}
+	if platform.Hypervisor == "" {
+		if xen, err := os.ReadFile("/sys/hypervisor/type"); err == nil {
+			platform.Hypervisor = strings.TrimSpace(string(xen))
+		} else if cpuHasHypervisorFlag() {
+			platform.Hypervisor = "unknown"
+		}
+	}
+	if platform.Hypervisor != "" {
+		platform.Type = platformVirtual
+	}
+
+	for _, candidate := range dmiCloudProviders {
+		if strings.Contains(dmiText, candidate.match) {
+			platform.CloudProvider = candidate.provider
+			break
+		}
+	}
+
+	if container := detectContainerRuntime(); container != "" {
+		platform.Type = platformContainer
+		platform.Container = container
+	}
+
+	report.Platform = platform
+	logger.Printf("Detected platform: type=%s, hypervisor=%s, container=%s, cloud=%s",
+		platform.Type, platform.Hypervisor, platform.Container, platform.CloudProvider)
+}
+
+// readDMIFields reads the SMBIOS strings the kernel exposes; some are root-only
+func readDMIFields() map[string]string {
+	fields := make(map[string]string)
+	for _, name := range []string{"sys_vendor", "product_name", "product_version", "bios_vendor", "bios_version", "board_vendor", "chassis_asset_tag"} {
+		if data, err := os.ReadFile(path.Join("/sys/class/dmi/id", name)); err == nil {
+			fields[name] = strings.TrimSpace(string(data))
+		}
+	}
+	return fields
+}
+
+// cpuHasHypervisorFlag reports whether the CPU advertises it runs under a hypervisor
+func cpuHasHypervisorFlag() bool {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "flags") {
+			return strings.Contains(line+" ", " hypervisor ")
+		}
+	}
+	return false
+}
+
+// detectContainerRuntime identifies the container engine when running inside one
+func detectContainerRuntime() string {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	if pathExists("/.dockerenv") {
+		return "docker"
+	}
+	if pathExists("/run/.containerenv") {
+		return "podman"
+	}
+
+	// systemd-nspawn, LXC and others set container= in PID 1's environment
+	if environ, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, variable := range strings.Split(string(environ), "\x00") {
+			if value, ok := strings.CutPrefix(variable, "container="); ok && value != "" {
+				return value
+			}
+		}
+	}
+
+	if cgroups, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		text := string(cgroups)
+		switch {
+		case strings.Contains(text, "kubepods"):
+			return "kubernetes"
+		case strings.Contains(text, "/docker"):
+			return "docker"
+		case strings.Contains(text, "/lxc"):
+			return "lxc"
+		case strings.Contains(text, "containerd"):
+			return "containerd"
+		}
+	}
+	return ""
+}
+
+// QueryCloudMetadata asks the detected cloud provider's instance metadata
+// service for the instance ID, type, region and zone
+func QueryCloudMetadata(report *model.DiscoveryReport, endpoint string, logger *log.Logger) {
+	provider := report.Platform.CloudProvider
+	if provider == "" {
+		logger.Println("No cloud provider detected, skipping instance metadata query")
+		return
+	}
+
+	client := &http.Client{Timeout: metadataTimeout}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	var instance *model.CloudInstance
+	var err error
+	switch provider {
+	case cloudAWS:
+		instance, err = queryAWSMetadata(client, endpoint)
+	case cloudGCP:
+		instance, err = queryGCPMetadata(client, endpoint)
+	case cloudAzure:
+		instance, err = queryAzureMetadata(client, endpoint)
+	case cloudOpenStack:
+		instance, err = queryOpenStackMetadata(client, endpoint)
+	default:
+		logger.Printf("No metadata client for cloud provider %s", provider)
+		return
+	}
+	if err != nil {
+		logger.Printf("Error querying %s instance metadata: %v", provider, err)
+		return
+	}
+
+	report.Platform.Instance = instance
+	logger.Printf("Instance metadata: id=%s, type=%s, region=%s, zone=%s",
+		instance.ID, instance.Type, instance.Region, instance.Zone)
+}
+
+// fetchMetadata performs a metadata request and returns the trimmed response body
+func fetchMetadata(client *http.Client, method, url string, headers map[string]string) (string, error) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s returned %s", method, url, response.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// queryAWSMetadata uses IMDSv2: a session token from a PUT request authorises the reads
+func queryAWSMetadata(client *http.Client, endpoint string) (*model.CloudInstance, error) {
+	token, err := fetchMetadata(client, http.MethodPut, endpoint+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": token}
+
+	instance := &model.CloudInstance{}
+	fields := []struct {
+		path  string
+		value *string
+	}{
+		{"instance-id", &instance.ID},
+		{"instance-type", &instance.Type},
+		{"placement/region", &instance.Region},
+		{"placement/availability-zone", &instance.Zone},
+	}
+	for _, field := range fields {
+		value, err := fetchMetadata(client, http.MethodGet, endpoint+"/latest/meta-data/"+field.path, headers)
+		if err != nil {
+			return nil, err
+		}
+		*field.value = value
+	}
+	return instance, nil
+}
+
+// queryGCPMetadata reads the computeMetadata tree; machine type and zone are resource paths
+func queryGCPMetadata(client *http.Client, endpoint string) (*model.CloudInstance, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	base := endpoint + "/computeMetadata/v1/instance/"
+
+	id, err := fetchMetadata(client, http.MethodGet, base+"id", headers)
+	if err != nil {
+		return nil, err
+	}
+	machineType, err := fetchMetadata(client, http.MethodGet, base+"machine-type", headers)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := fetchMetadata(client, http.MethodGet, base+"zone", headers)
+	if err != nil {
+		return nil, err
+	}
+
+	// "projects/123/zones/europe-west1-b" is in region "europe-west1"
+	instance := &model.CloudInstance{
+		ID:   id,
+		Type: path.Base(machineType),
+		Zone: path.Base(zone),
+	}
+	if dash := strings.LastIndex(instance.Zone, "-"); dash > 0 {
+		instance.Region = instance.Zone[:dash]
+	}
+	return instance, nil
+}
+
+// queryAzureMetadata reads the compute section of the Azure instance metadata service
+func queryAzureMetadata(client *http.Client, endpoint string) (*model.CloudInstance, error) {
+	body, err := fetchMetadata(client, http.MethodGet, endpoint+"/metadata/instance/compute?api-version=2021-02-01",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var compute struct {
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return nil, err
+	}
+	return &model.CloudInstance{
+		ID:     compute.VMID,
+		Type:   compute.VMSize,
+		Region: compute.Location,
+		Zone:   compute.Zone,
+	}, nil
+}
+
+// queryOpenStackMetadata reads meta_data.json; the flavor is only exposed
+// through the EC2-compatible tree, which not every deployment enables
+func queryOpenStackMetadata(client *http.Client, endpoint string) (*model.CloudInstance, error) {
+	body, err := fetchMetadata(client, http.MethodGet, endpoint+"/openstack/latest/meta_data.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata struct {
+		UUID             string `json:"uuid"`
+		AvailabilityZone string `json:"availability_zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &metadata); err != nil {
+		return nil, err
+	}
+
+	instance := &model.CloudInstance{
+		ID:   metadata.UUID,
+		Zone: metadata.AvailabilityZone,
+	}
+	if flavor, err := fetchMetadata(client, http.MethodGet, endpoint+"/latest/meta-data/instance-type", nil); err == nil {
+		instance.Type = flavor
+	}
+	return instance, nil
+}
@@ -0,0 +1,169 @@
+package collector
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// awsMetadataHandler serves IMDSv2: reads need the token handed out by a PUT
+func awsMetadataHandler(t *testing.T) http.HandlerFunc {
+	values := map[string]string{
+		"/latest/meta-data/instance-id":                 "i-0123456789abcdef0",
+		"/latest/meta-data/instance-type":               "m5.large",
+		"/latest/meta-data/placement/region":            "eu-central-1",
+		"/latest/meta-data/placement/availability-zone": "eu-central-1a",
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				t.Errorf("token request: %s without TTL header", r.Method)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, "session-token")
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "session-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		value, ok := values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, value+"\n")
+	}
+}
+
+// gcpMetadataHandler serves the computeMetadata tree, which requires Metadata-Flavor
+func gcpMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/computeMetadata/v1/instance/id":
+		io.WriteString(w, "4520031799277581759")
+	case "/computeMetadata/v1/instance/machine-type":
+		io.WriteString(w, "projects/123456789/machineTypes/e2-medium")
+	case "/computeMetadata/v1/instance/zone":
+		io.WriteString(w, "projects/123456789/zones/europe-west1-b")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// azureMetadataHandler serves the compute section, which requires Metadata and api-version
+func azureMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path != "/metadata/instance/compute" {
+		http.NotFound(w, r)
+		return
+	}
+	io.WriteString(w, `{"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "vmSize": "Standard_D2s_v3", "location": "westeurope", "zone": "2"}`)
+}
+
+// openStackMetadataHandler serves meta_data.json and, when ec2 is set, the EC2-compatible tree
+func openStackMetadataHandler(ec2 bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/openstack/latest/meta_data.json":
+			io.WriteString(w, `{"uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38", "availability_zone": "nova", "name": "web-1"}`)
+		case r.URL.Path == "/latest/meta-data/instance-type" && ec2:
+			io.WriteString(w, "m1.small")
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestQueryCloudMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		handler  http.Handler
+		want     *model.CloudInstance
+	}{
+		{"aws", cloudAWS, awsMetadataHandler(t),
+			&model.CloudInstance{ID: "i-0123456789abcdef0", Type: "m5.large", Region: "eu-central-1", Zone: "eu-central-1a"}},
+		{"gcp", cloudGCP, http.HandlerFunc(gcpMetadataHandler),
+			&model.CloudInstance{ID: "4520031799277581759", Type: "e2-medium", Region: "europe-west1", Zone: "europe-west1-b"}},
+		{"azure", cloudAzure, http.HandlerFunc(azureMetadataHandler),
+			&model.CloudInstance{ID: "02aab8a4-74ef-476e-8182-f6d2ba4166a6", Type: "Standard_D2s_v3", Region: "westeurope", Zone: "2"}},
+		{"openstack", cloudOpenStack, openStackMetadataHandler(true),
+			&model.CloudInstance{ID: "d8e02d56-2648-49a3-bf97-6be8f1204f38", Type: "m1.small", Zone: "nova"}},
+		{"openstack without ec2 tree", cloudOpenStack, openStackMetadataHandler(false),
+			&model.CloudInstance{ID: "d8e02d56-2648-49a3-bf97-6be8f1204f38", Zone: "nova"}},
+		{"wrong provider", cloudGCP, awsMetadataHandler(t), nil},
+		{"no provider", "", http.HandlerFunc(gcpMetadataHandler), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			report := &model.DiscoveryReport{Platform: model.PlatformInfo{CloudProvider: tt.provider}}
+			// The trailing slash must not produce double slashes in request paths
+			QueryCloudMetadata(report, server.URL+"/", log.New(io.Discard, "", 0))
+
+			if !reflect.DeepEqual(report.Platform.Instance, tt.want) {
+				t.Errorf("instance = %+v, want %+v", report.Platform.Instance, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryCloudMetadataUnreachable(t *testing.T) {
+	// A closed listener refuses connections
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	// A service that accepts connections but never answers
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	allProviders := []string{cloudAWS, cloudGCP, cloudAzure, cloudOpenStack}
+	tests := []struct {
+		name      string
+		endpoint  string
+		providers []string
+	}{
+		{"connection refused", closed.URL, allProviders},
+		// Each provider waits out the full timeout, so only try one
+		{"no response", hanging.URL, []string{cloudAWS}},
+		{"invalid endpoint", "http://[::1", allProviders},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, provider := range tt.providers {
+				report := &model.DiscoveryReport{Platform: model.PlatformInfo{CloudProvider: provider}}
+
+				start := time.Now()
+				QueryCloudMetadata(report, tt.endpoint, log.New(io.Discard, "", 0))
+				if elapsed := time.Since(start); elapsed > metadataTimeout+time.Second {
+					t.Errorf("%s: query took %v, want at most %v", provider, elapsed, metadataTimeout)
+				}
+				if report.Platform.Instance != nil {
+					t.Errorf("%s: instance = %+v, want none", provider, report.Platform.Instance)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// runtimeDefinition describes where one language's interpreters are installed
+type runtimeDefinition struct {
+	language    string
+	commands    []string
+	binary      *regexp.Regexp
+	patterns    []string
+	versionArgs []string
+}
+
+// knownRuntimes lists the runtimes to inventory. Commands are looked up on
+// PATH and in the alternatives database to find the default; patterns find
+// side-by-side installs, with "~" expanded to every home directory.
+var knownRuntimes = []runtimeDefinition{
+	{
+		language: "PHP",
+		commands: []string{"php"},
+		binary:   regexp.MustCompile(`^php(\d+(\.\d+)?)?$`),
+		patterns: []string{
+			"/usr/bin/php*", "/usr/local/bin/php*", "/opt/remi/php*/root/usr/bin/php",
+			"/opt/php*/bin/php", "/usr/local/php*/bin/php", "~/.phpenv/versions/*/bin/php",
+		},
+		versionArgs: []string{"-v"},
+	},
+	{
+		language: "Python",
+		commands: []string{"python3", "python"},
+		binary:   regexp.MustCompile(`^python(\d(\.\d+)?)?$`),
+		patterns: []string{
+			"/usr/bin/python*", "/usr/local/bin/python*", "/opt/*/bin/python*",
+			"~/.pyenv/versions/*/bin/python*",
+		},
+		versionArgs: []string{"--version"},
+	},
+	{
+		language: "Node.js",
+		commands: []string{"node", "nodejs"},
+		binary:   regexp.MustCompile(`^(node|nodejs)$`),
+		patterns: []string{
+			"/usr/bin/node*", "/usr/local/bin/node", "/opt/*/bin/node", "/usr/local/n/versions/node/*/bin/node",
+			"~/.nvm/versions/node/*/bin/node", "~/.volta/tools/image/node/*/bin/node",
+		},
+		versionArgs: []string{"--version"},
+	},
+	{
+		language: "Java",
+		commands: []string{"java"},
+		binary:   regexp.MustCompile(`^java$`),
+		patterns: []string{
+			"/usr/lib/jvm/*/bin/java", "/usr/lib/jvm/*/jre/bin/java", "/usr/java/*/bin/java",
+			"/opt/*/bin/java", "/opt/java/*/bin/java", "/Library/Java/JavaVirtualMachines/*/Contents/Home/bin/java",
+			"~/.sdkman/candidates/java/*/bin/java",
+		},
+		versionArgs: []string{"-version"},
+	},
+	{
+		language: "Ruby",
+		commands: []string{"ruby"},
+		binary:   regexp.MustCompile(`^ruby(\d+(\.\d+)*)?$`),
+		patterns: []string{
+			"/usr/bin/ruby*", "/usr/local/bin/ruby", "/opt/rubies/*/bin/ruby", "/usr/local/rvm/rubies/*/bin/ruby",
+			"~/.rbenv/versions/*/bin/ruby", "~/.rvm/rubies/*/bin/ruby",
+		},
+		versionArgs: []string{"--version"},
+	},
+	{
+		language: "Go",
+		commands: []string{"go"},
+		binary:   regexp.MustCompile(`^go$`),
+		patterns: []string{
+			"/usr/local/go/bin/go", "/usr/lib/go-*/bin/go", "/usr/lib/golang/bin/go",
+			"~/sdk/go*/bin/go", "~/go/sdk/go*/bin/go",
+		},
+		versionArgs: []string{"version"},
+	},
+}
+
+// dotnetRoots are the usual .NET installation directories; "~" expands to every home directory
+var dotnetRoots = []string{"/usr/share/dotnet", "/usr/lib/dotnet", "/usr/lib64/dotnet", "/opt/dotnet", "/usr/local/share/dotnet", "~/.dotnet"}
+
+// managedVersionPattern reads the version from version manager directory
+// layouts such as ~/.pyenv/versions/3.11.7 or ~/.nvm/versions/node/v18.19.0
+var managedVersionPattern = regexp.MustCompile(`/(?:versions|versions/node|rubies|candidates/java|tools/image/node|sdk)/(?:ruby-|go)?v?(\d[^/]*)/`)
+
+// runtimeManagers maps install path fragments to the version manager that owns them
+var runtimeManagers = []struct{ fragment, name string }{
+	{"/.pyenv/", "pyenv"},
+	{"/.phpenv/", "phpenv"},
+	{"/.nvm/", "nvm"},
+	{"/.volta/", "volta"},
+	{"/n/versions/", "n"},
+	{"/.rbenv/", "rbenv"},
+	{"/rvm/", "rvm"},
+	{"/.rvm/", "rvm"},
+	{"/.sdkman/", "sdkman"},
+	{"/.asdf/", "asdf"},
+	{"/sdk/go", "golang.org/dl"},
+}
+
+// DetectRuntimes inventories language interpreters, JVMs and toolchains with their versions
+func DetectRuntimes(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting language runtimes")
+
+	homes := homeDirectories()
+	for _, definition := range knownRuntimes {
+		defaults := defaultRuntimePaths(definition.commands)
+		for _, path := range findRuntimeBinaries(definition, homes) {
+			runtime := model.Runtime{
+				Language: definition.language,
+				Path:     path,
+				Manager:  runtimeManager(path),
+				Default:  defaults[path],
+			}
+			runtime.Version, runtime.Implementation = runtimeVersion(definition, path)
+			if definition.language == "PHP" && !isUserOwnedPath(path) {
+				runtime.INIFile, runtime.Extensions = phpDetails(path)
+			}
+
+			report.Runtimes = append(report.Runtimes, runtime)
+			logger.Printf("Detected %s %s at %s (default=%t)", runtime.Language, runtime.Version, runtime.Path, runtime.Default)
+		}
+	}
+
+	report.Runtimes = append(report.Runtimes, collectDotnetRuntimes(homes, logger)...)
+
+	logger.Printf("Detected %d language runtimes", len(report.Runtimes))
+}
+
+// homeDirectories lists root's and users' home directories for version manager installs
+func homeDirectories() []string {
+	homes := []string{"/root"}
+	users, _ := filepath.Glob("/home/*")
+	return append(homes, users...)
+}
+
+// expandHomePatterns replaces a leading "~" with each home directory
+func expandHomePatterns(patterns, homes []string) []string {
+	var expanded []string
+	for _, pattern := range patterns {
+		rest, ok := strings.CutPrefix(pattern, "~/")
+		if !ok {
+			expanded = append(expanded, pattern)
+			continue
+		}
+		for _, home := range homes {
+			expanded = append(expanded, filepath.Join(home, rest))
+		}
+	}
+	return expanded
+}
+
+// findRuntimeBinaries gathers candidate executables from PATH, the
+// alternatives database and install patterns, deduplicated by their real path
+func findRuntimeBinaries(definition runtimeDefinition, homes []string) []string {
+	var candidates []string
+	for _, command := range definition.commands {
+		if path, err := exec.LookPath(command); err == nil {
+			candidates = append(candidates, path)
+		}
+		candidates = append(candidates, readAlternatives(command)...)
+	}
+	for _, pattern := range expandHomePatterns(definition.patterns, homes) {
+		matches, _ := filepath.Glob(pattern)
+		candidates = append(candidates, matches...)
+	}
+
+	seen := make(map[string]bool)
+	var binaries []string
+	for _, candidate := range candidates {
+		// pyenv and rbenv shims are scripts that dispatch to a real install
+		name := strings.TrimSuffix(filepath.Base(candidate), ".exe")
+		if !definition.binary.MatchString(name) || strings.Contains(candidate, "/shims/") {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		info, err := os.Stat(resolved)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		seen[resolved] = true
+		binaries = append(binaries, resolved)
+	}
+	sort.Strings(binaries)
+	return binaries
+}
+
+// readAlternatives lists the choices registered for a command in the Debian
+// or Red Hat alternatives database
+func readAlternatives(command string) []string {
+	var paths []string
+	for _, dir := range []string{"/var/lib/dpkg/alternatives", "/var/lib/alternatives"} {
+		data, err := os.ReadFile(filepath.Join(dir, command))
+		if err != nil {
+			continue
+		}
+		// The file lists the link, its slaves and every choice with its priority
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if filepath.IsAbs(line) && filepath.Base(line) == command {
+				paths = append(paths, line)
+			}
+		}
+	}
+	return paths
+}
+
+// defaultRuntimePaths resolves what the commands run by default, following
+// alternatives symlinks and pyenv/rbenv shims to the selected install
+func defaultRuntimePaths(commands []string) map[string]bool {
+	defaults := make(map[string]bool)
+	for _, command := range commands {
+		path, err := exec.LookPath(command)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(path, "/shims/") {
+			path = resolveShim(path)
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			defaults[resolved] = true
+		}
+	}
+	return defaults
+}
+
+// resolveShim maps a version manager shim to the binary of its global version
+func resolveShim(shim string) string {
+	root := filepath.Dir(filepath.Dir(shim))
+	data, err := os.ReadFile(filepath.Join(root, "version"))
+	if err != nil {
+		return ""
+	}
+	version := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	if version == "" || version == "system" {
+		return ""
+	}
+	return filepath.Join(root, "versions", version, "bin", filepath.Base(shim))
+}
+
+// runtimeManager names the version manager that installed a runtime, if any
+func runtimeManager(path string) string {
+	for _, manager := range runtimeManagers {
+		if strings.Contains(path, manager.fragment) {
+			return manager.name
+		}
+	}
+	return ""
+}
+
+// isUserOwnedPath reports whether a path lies in a home directory. Binaries
+// there can be replaced by their owner, so they are never executed.
+func isUserOwnedPath(path string) bool {
+	return strings.HasPrefix(path, "/root/") || strings.HasPrefix(path, "/home/")
+}
+
+// runtimeVersion reads the version from the install layout where possible
+// and only runs the binary as a last resort
+func runtimeVersion(definition runtimeDefinition, path string) (string, string) {
+	switch definition.language {
+	case "Java":
+		// JDKs ship a release file in their home directory, next to bin/ or above jre/
+		home := filepath.Dir(filepath.Dir(path))
+		if filepath.Base(home) == "jre" {
+			home = filepath.Dir(home)
+		}
+		release := parseShellAssignments(filepath.Join(home, "release"))
+		if release["JAVA_VERSION"] != "" {
+			return release["JAVA_VERSION"], release["IMPLEMENTOR"]
+		}
+	case "Go":
+		if data, err := os.ReadFile(filepath.Join(filepath.Dir(filepath.Dir(path)), "VERSION")); err == nil {
+			line := strings.SplitN(string(data), "\n", 2)[0]
+			return strings.TrimPrefix(strings.TrimSpace(line), "go"), ""
+		}
+	}
+
+	if match := managedVersionPattern.FindStringSubmatch(path); match != nil {
+		return match[1], ""
+	}
+	if isUserOwnedPath(path) {
+		return "", ""
+	}
+	return commandVersion(path, definition.versionArgs), ""
+}
+
+// phpDetails returns the loaded php.ini and the compiled and loaded extensions
+func phpDetails(binary string) (string, []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), serviceStatusTimeout)
+	defer cancel()
+
+	iniFile := ""
+	if output, err := exec.CommandContext(ctx, binary, "--ini").Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			if value, ok := strings.CutPrefix(line, "Loaded Configuration File:"); ok {
+				if value = strings.TrimSpace(value); value != "(none)" {
+					iniFile = value
+				}
+			}
+		}
+	}
+
+	var extensions []string
+	if output, err := exec.CommandContext(ctx, binary, "-m").Output(); err == nil {
+		// Output lists "[PHP Modules]" and then "[Zend Modules]"
+		for _, line := range strings.Split(string(output), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "[") {
+				extensions = appendUnique(extensions, line)
+			}
+		}
+	}
+	return iniFile, extensions
+}
+
+// collectDotnetRuntimes lists shared frameworks and SDKs in each .NET install
+// directory. A .NET host picks a framework per application, so every runtime of
+// the installation on PATH is marked as default.
+func collectDotnetRuntimes(homes []string, logger *log.Logger) []model.Runtime {
+	defaultRoot := ""
+	if path, err := exec.LookPath("dotnet"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			defaultRoot = filepath.Dir(resolved)
+		}
+	}
+
+	var runtimes []model.Runtime
+	seen := make(map[string]bool)
+	for _, root := range append(expandHomePatterns(dotnetRoots, homes), defaultRoot) {
+		if root == "" {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		// shared/Microsoft.NETCore.App/8.0.1 and sdk/8.0.101
+		installs, _ := filepath.Glob(filepath.Join(resolved, "shared", "*", "*"))
+		sdks, _ := filepath.Glob(filepath.Join(resolved, "sdk", "*"))
+		for _, install := range append(installs, sdks...) {
+			implementation := filepath.Base(filepath.Dir(install))
+			if implementation == "sdk" {
+				implementation = "SDK"
+			}
+			runtime := model.Runtime{
+				Language:       ".NET",
+				Version:        filepath.Base(install),
+				Path:           install,
+				Implementation: implementation,
+				Manager:        runtimeManager(install),
+				Default:        resolved == defaultRoot,
+			}
+			runtimes = append(runtimes, runtime)
+			logger.Printf("Detected .NET %s %s at %s", runtime.Implementation, runtime.Version, runtime.Path)
+		}
+	}
+	return runtimes
+}
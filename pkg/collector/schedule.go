@@ -0,0 +1,284 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros maps cron @-shortcuts to their five-field equivalents
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// calendarShorthands maps systemd OnCalendar shorthands to cron expressions
+var calendarShorthands = map[string]string{
+	"minutely":     "* * * * *",
+	"hourly":       "0 * * * *",
+	"daily":        "0 0 * * *",
+	"weekly":       "0 0 * * 1",
+	"monthly":      "0 0 1 * *",
+	"yearly":       "0 0 1 1 *",
+	"annually":     "0 0 1 1 *",
+	"quarterly":    "0 0 1 1,4,7,10 *",
+	"semiannually": "0 0 1 1,7 *",
+}
+
+// monthNames maps cron month abbreviations to month numbers
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// weekdayNames maps cron and systemd weekday abbreviations to day numbers
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronSchedule is a parsed five-field cron expression stored as bit sets
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+	// dayAnd requires both day fields to match (systemd) instead of either (cron)
+	dayAnd bool
+}
+
+// parseCronSchedule parses a five-field cron expression or @-macro
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, err
+	}
+
+	// Sunday may be written as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = !strings.HasPrefix(fields[2], "*")
+	s.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return &s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps into a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loText, hiText, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(loText, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiText, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end in steps of 15
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range in %q", part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a numeric or named cron field value
+func parseCronValue(text string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	return v, nil
+}
+
+// matchesDay reports whether the day-of-month and day-of-week fields accept t
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	// Classic cron: when both fields are restricted, either may match
+	if s.domRestricted && s.dowRestricted && !s.dayAnd {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// next returns the first time strictly after from that matches the schedule
+func (s *cronSchedule) next(from time.Time) (time.Time, bool) {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	limit := from.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// parseCalendarSpec converts a systemd OnCalendar expression into a schedule.
+// It understands shorthands and "[Weekday] [[Year-]Month-Day] [Hour:Minute[:Second]]"
+// forms, which covers the timers shipped by distributions; seconds, years and
+// time zones are ignored for the purpose of estimating the next run.
+func parseCalendarSpec(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := calendarShorthands[strings.ToLower(spec)]; ok {
+		return parseCronSchedule(expr)
+	}
+
+	weekdays, date, clock := "*", "*-*", "00:00"
+	for i, token := range strings.Fields(spec) {
+		switch {
+		case strings.Contains(token, ":"):
+			clock = token
+		case strings.Contains(token, "-") && !isAlphaToken(token):
+			date = token
+		case i == 0 && isAlphaToken(token):
+			weekdays = strings.ToLower(strings.ReplaceAll(token, "..", "-"))
+		default:
+			// Trailing time zone names
+		}
+	}
+
+	dateParts := strings.Split(date, "-")
+	if len(dateParts) < 2 {
+		return nil, fmt.Errorf("unsupported calendar date %q", date)
+	}
+	month := dateParts[len(dateParts)-2]
+	day := dateParts[len(dateParts)-1]
+	if strings.Contains(day, "~") {
+		return nil, fmt.Errorf("unsupported calendar date %q", date)
+	}
+
+	clockParts := strings.Split(clock, ":")
+	hour, minute := clockParts[0], clockParts[1]
+
+	expr := strings.Join([]string{minute, hour, day, month, weekdays}, " ")
+	expr = strings.ReplaceAll(expr, "..", "-")
+
+	schedule, err := parseCronSchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+	schedule.dayAnd = true
+	return schedule, nil
+}
+
+// isAlphaToken reports whether a calendar token is made of weekday names
+func isAlphaToken(token string) bool {
+	for _, r := range token {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != ',' && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// describeNextRun renders a next-run time as a human-readable estimate
+func describeNextRun(next, now time.Time) string {
+	return fmt.Sprintf("%s (in %s)", next.Format("Mon 2006-01-02 15:04 MST"), humanizeDuration(next.Sub(now)))
+}
+
+// humanizeDuration renders a duration as days, hours and minutes
+func humanizeDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 && days == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// estimateNextRun returns a human-readable next run for a cron expression
+func estimateNextRun(expr string, now time.Time) string {
+	if strings.EqualFold(expr, "@reboot") {
+		return "at next boot"
+	}
+	schedule, err := parseCronSchedule(expr)
+	if err != nil {
+		return ""
+	}
+	next, ok := schedule.next(now)
+	if !ok {
+		return ""
+	}
+	return describeNextRun(next, now)
+}
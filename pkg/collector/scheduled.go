@@ -0,0 +1,373 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// cronPeriodicDirs are the run-parts directories and the schedule they run on by default
+var cronPeriodicDirs = []struct {
+	dir      string
+	schedule string
+}{
+	{"/etc/cron.hourly", "@hourly"},
+	{"/etc/cron.daily", "@daily"},
+	{"/etc/cron.weekly", "@weekly"},
+	{"/etc/cron.monthly", "@monthly"},
+}
+
+// userCrontabDirs are per-user crontab spools on Debian, RHEL and SUSE
+var userCrontabDirs = []string{"/var/spool/cron/crontabs", "/var/spool/cron", "/var/spool/cron/tabs"}
+
+// atSpoolDirs are at job spools on Debian and RHEL
+var atSpoolDirs = []string{"/var/spool/cron/atjobs", "/var/spool/at"}
+
+// DetectScheduledJobs inventories cron jobs, systemd timers, anacron and at jobs
+func DetectScheduledJobs(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting scheduled jobs")
+
+	now := time.Now()
+
+	// System crontabs carry a user column
+	systemCrontabs := []string{"/etc/crontab"}
+	if entries, err := filepath.Glob("/etc/cron.d/*"); err == nil {
+		systemCrontabs = append(systemCrontabs, entries...)
+	}
+	for _, path := range systemCrontabs {
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+		report.ScheduledJobs = append(report.ScheduledJobs, parseCrontab(path, "", now, logger)...)
+	}
+
+	// Per-user crontabs are named after their owner
+	for _, dir := range userCrontabDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			report.ScheduledJobs = append(report.ScheduledJobs, parseCrontab(path, entry.Name(), now, logger)...)
+		}
+	}
+
+	report.ScheduledJobs = append(report.ScheduledJobs, collectPeriodicJobs(report.ScheduledJobs, now)...)
+	report.ScheduledJobs = append(report.ScheduledJobs, parseAnacrontab("/etc/anacrontab", now, logger)...)
+	report.ScheduledJobs = append(report.ScheduledJobs, collectSystemdTimers(now, logger)...)
+	report.ScheduledJobs = append(report.ScheduledJobs, collectAtJobs(now, logger)...)
+
+	logger.Printf("Detected %d scheduled jobs", len(report.ScheduledJobs))
+}
+
+// parseCrontab reads a crontab file. When owner is empty the file is a system
+// crontab whose sixth field names the user the command runs as.
+func parseCrontab(path, owner string, now time.Time, logger *log.Logger) []model.ScheduledJob {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading crontab %s: %v", path, err)
+		return nil
+	}
+
+	var jobs []model.ScheduledJob
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		// Skip environment assignments such as SHELL=/bin/sh
+		if !strings.HasPrefix(fields[0], "@") && strings.Contains(fields[0], "=") {
+			continue
+		}
+
+		scheduleFields := 5
+		if strings.HasPrefix(fields[0], "@") {
+			scheduleFields = 1
+		}
+		commandStart := scheduleFields
+		if owner == "" {
+			commandStart++
+		}
+		if len(fields) <= commandStart {
+			continue
+		}
+
+		job := model.ScheduledJob{
+			Type:     "cron",
+			Schedule: strings.Join(fields[:scheduleFields], " "),
+			User:     owner,
+			Command:  strings.Join(fields[commandStart:], " "),
+			Source:   path,
+		}
+		if owner == "" {
+			job.User = fields[scheduleFields]
+		}
+		job.NextRun = estimateNextRun(job.Schedule, now)
+
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// collectPeriodicJobs lists scripts in /etc/cron.{hourly,daily,weekly,monthly},
+// using the crontab entry that runs each directory for the schedule if there is one
+func collectPeriodicJobs(cronJobs []model.ScheduledJob, now time.Time) []model.ScheduledJob {
+	var jobs []model.ScheduledJob
+
+	for _, periodic := range cronPeriodicDirs {
+		dir := periodic.dir
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		schedule := periodic.schedule
+		for _, cronJob := range cronJobs {
+			if strings.Contains(cronJob.Command, dir) {
+				schedule = cronJob.Schedule
+				break
+			}
+		}
+
+		for _, entry := range entries {
+			// run-parts ignores dotfiles and names with dots, like package backups
+			if entry.IsDir() || strings.Contains(entry.Name(), ".") {
+				continue
+			}
+			jobs = append(jobs, model.ScheduledJob{
+				Type:     "cron",
+				Schedule: schedule,
+				User:     "root",
+				Command:  filepath.Join(dir, entry.Name()),
+				Source:   dir,
+				NextRun:  estimateNextRun(schedule, now),
+			})
+		}
+	}
+	return jobs
+}
+
+// parseAnacrontab reads anacron jobs, estimating the next run from the job's timestamp file
+func parseAnacrontab(path string, now time.Time, logger *log.Logger) []model.ScheduledJob {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var jobs []model.ScheduledJob
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Format: period delay job-identifier command
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.Contains(fields[0], "=") {
+			continue
+		}
+
+		job := model.ScheduledJob{
+			Type:     "anacron",
+			Schedule: fmt.Sprintf("every %s day(s), %s min delay", fields[0], fields[1]),
+			User:     "root",
+			Command:  strings.Join(fields[3:], " "),
+			Source:   path,
+		}
+
+		periodDays := 0
+		switch fields[0] {
+		case "@daily":
+			periodDays = 1
+		case "@weekly":
+			periodDays = 7
+		case "@monthly":
+			periodDays = 30
+		default:
+			periodDays, _ = strconv.Atoi(fields[0])
+		}
+		if strings.HasPrefix(fields[0], "@") {
+			job.Schedule = fmt.Sprintf("%s, %s min delay", fields[0], fields[1])
+		}
+
+		// anacron records the last run date (YYYYMMDD) per job identifier
+		if stamp, err := os.ReadFile(filepath.Join("/var/spool/anacron", fields[2])); err == nil && periodDays > 0 {
+			if last, err := time.ParseInLocation("20060102", strings.TrimSpace(string(stamp)), now.Location()); err == nil {
+				next := last.AddDate(0, 0, periodDays)
+				if next.Before(now) {
+					job.NextRun = "overdue, runs when anacron next starts"
+				} else {
+					job.NextRun = describeNextRun(next, now)
+				}
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+	logger.Printf("Found %d anacron jobs in %s", len(jobs), path)
+	return jobs
+}
+
+// collectSystemdTimers lists timer units together with the service they activate
+func collectSystemdTimers(now time.Time, logger *log.Logger) []model.ScheduledJob {
+	var jobs []model.ScheduledJob
+
+	for _, timer := range findSystemdUnits(".timer", logger) {
+		if timer.masked {
+			continue
+		}
+
+		target := timer.get("Timer", "Unit")
+		if target == "" {
+			target = strings.TrimSuffix(timer.name, ".timer") + ".service"
+		}
+
+		// Realtime (OnCalendar) and monotonic triggers can be combined
+		var triggers []string
+		for _, value := range timer.getAll("Timer", "OnCalendar") {
+			triggers = append(triggers, "OnCalendar="+value)
+		}
+		for _, key := range []string{"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec"} {
+			for _, value := range timer.getAll("Timer", key) {
+				triggers = append(triggers, key+"="+value)
+			}
+		}
+
+		job := model.ScheduledJob{
+			Type:     "systemd-timer",
+			Schedule: strings.Join(triggers, ", "),
+			User:     "root",
+			Target:   target,
+			Source:   timer.path,
+		}
+
+		if path := findSystemdUnitFile(target); path != "" {
+			if service, err := loadSystemdUnit(target, path); err == nil {
+				job.Command = strings.Join(service.getAll("Service", "ExecStart"), "; ")
+				if serviceUser := service.get("Service", "User"); serviceUser != "" {
+					job.User = serviceUser
+				}
+			}
+		}
+
+		// Use the earliest OnCalendar occurrence as the estimate
+		var earliest time.Time
+		for _, value := range timer.getAll("Timer", "OnCalendar") {
+			schedule, err := parseCalendarSpec(value)
+			if err != nil {
+				continue
+			}
+			if next, ok := schedule.next(now); ok && (earliest.IsZero() || next.Before(earliest)) {
+				earliest = next
+			}
+		}
+		if !earliest.IsZero() {
+			job.NextRun = describeNextRun(earliest, now)
+		}
+
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// findSystemdUnitFile returns the highest-precedence file defining a unit
+func findSystemdUnitFile(name string) string {
+	for _, dir := range systemdUnitDirs {
+		path := filepath.Join(dir, name)
+		if pathExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// collectAtJobs reads queued at jobs. Job file names encode the queue letter,
+// a five-digit hex job number and the run time in hex minutes since the epoch.
+func collectAtJobs(now time.Time, logger *log.Logger) []model.ScheduledJob {
+	var jobs []model.ScheduledJob
+
+	for _, dir := range atSpoolDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || len(name) != 14 || strings.HasPrefix(name, ".") {
+				continue
+			}
+			minutes, err := strconv.ParseInt(name[6:], 16, 64)
+			if err != nil {
+				continue
+			}
+			runAt := time.Unix(minutes*60, 0).In(now.Location())
+			path := filepath.Join(dir, name)
+
+			job := model.ScheduledJob{
+				Type:     "at",
+				Schedule: runAt.Format("2006-01-02 15:04"),
+				User:     fileOwner(path),
+				Command:  readAtJobCommand(path),
+				Source:   path,
+				NextRun:  describeNextRun(runAt, now),
+			}
+			if runAt.Before(now) {
+				job.NextRun = "pending"
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	if len(jobs) > 0 {
+		logger.Printf("Found %d queued at jobs", len(jobs))
+	}
+	return jobs
+}
+
+// readAtJobCommand extracts the user's commands from an at job script, which
+// follow the generated environment setup and "cd ... || { ... }" block
+func readAtJobCommand(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(data), "\n")
+	start := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "cd ") {
+			start = i + 1
+			// Skip the error handling block that follows the cd
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "}" {
+					start = j + 1
+					break
+				}
+			}
+			break
+		}
+	}
+
+	var commands []string
+	for _, line := range lines[start:] {
+		line = strings.TrimSpace(line)
+		// Some at implementations append a heredoc marker to the script
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "marcinDELIMITER") {
+			commands = append(commands, line)
+		}
+	}
+	return strings.Join(commands, "; ")
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// hardeningSysctls are security-relevant kernel parameters and the values a hardened host uses
+var hardeningSysctls = []struct {
+	name    string
+	allowed []string
+}{
+	{"kernel.randomize_va_space", []string{"2"}},
+	{"kernel.yama.ptrace_scope", []string{"1", "2", "3"}},
+	{"kernel.kptr_restrict", []string{"1", "2"}},
+	{"kernel.dmesg_restrict", []string{"1"}},
+	{"kernel.unprivileged_bpf_disabled", []string{"1", "2"}},
+	{"fs.protected_hardlinks", []string{"1"}},
+	{"fs.protected_symlinks", []string{"1"}},
+	{"fs.suid_dumpable", []string{"0"}},
+	{"net.ipv4.ip_forward", []string{"0"}},
+	{"net.ipv6.conf.all.forwarding", []string{"0"}},
+	{"net.ipv4.conf.all.rp_filter", []string{"1", "2"}},
+	{"net.ipv4.conf.default.rp_filter", []string{"1", "2"}},
+	{"net.ipv4.conf.all.accept_redirects", []string{"0"}},
+	{"net.ipv4.conf.all.send_redirects", []string{"0"}},
+	{"net.ipv4.conf.all.accept_source_route", []string{"0"}},
+	{"net.ipv4.tcp_syncookies", []string{"1"}},
+}
+
+// DetectSecurity reports SELinux and AppArmor status and kernel hardening sysctls
+func DetectSecurity(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting mandatory access control and kernel hardening")
+
+	report.Security = model.SecurityInfo{
+		SELinux:  collectSELinuxStatus(logger),
+		AppArmor: collectAppArmorStatus(logger),
+		Sysctls:  collectHardeningSysctls(),
+	}
+
+	nonCompliant := 0
+	for _, setting := range report.Security.Sysctls {
+		if !setting.Compliant {
+			nonCompliant++
+		}
+	}
+	logger.Printf("Checked %d sysctls, %d differ from the hardened value", len(report.Security.Sysctls), nonCompliant)
+}
+
+// collectSELinuxStatus reads the running mode from selinuxfs and the boot-time
+// mode and policy from /etc/selinux/config
+func collectSELinuxStatus(logger *log.Logger) *model.SELinuxStatus {
+	config := parseShellAssignments("/etc/selinux/config")
+	enforce, err := os.ReadFile("/sys/fs/selinux/enforce")
+	if err != nil && len(config) == 0 {
+		return nil
+	}
+
+	status := &model.SELinuxStatus{
+		Mode:           "disabled",
+		ConfiguredMode: config["SELINUX"],
+		Policy:         config["SELINUXTYPE"],
+	}
+	if err == nil {
+		status.Mode = "permissive"
+		if strings.TrimSpace(string(enforce)) == "1" {
+			status.Mode = "enforcing"
+		}
+	}
+	if version, err := os.ReadFile("/sys/fs/selinux/policyvers"); err == nil {
+		status.PolicyVersion = strings.TrimSpace(string(version))
+	}
+
+	logger.Printf("SELinux mode: %s (configured: %s)", status.Mode, status.ConfiguredMode)
+	return status
+}
+
+// collectAppArmorStatus lists loaded profiles from securityfs. Reading the
+// profile list requires root; the enabled flag is world-readable.
+func collectAppArmorStatus(logger *log.Logger) *model.AppArmorStatus {
+	enabled, err := os.ReadFile("/sys/module/apparmor/parameters/enabled")
+	if err != nil {
+		return nil
+	}
+
+	status := &model.AppArmorStatus{
+		Enabled:  strings.TrimSpace(string(enabled)) == "Y",
+		Profiles: []model.AppArmorProfile{},
+	}
+
+	file, err := os.Open("/sys/kernel/security/apparmor/profiles")
+	if err != nil {
+		if status.Enabled {
+			logger.Printf("Error reading AppArmor profiles: %v", err)
+		}
+		return status
+	}
+	defer file.Close()
+
+	// Each line is "profile-name (mode)"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		open := strings.LastIndex(line, " (")
+		if open < 0 || !strings.HasSuffix(line, ")") {
+			continue
+		}
+		status.Profiles = append(status.Profiles, model.AppArmorProfile{
+			Name: line[:open],
+			Mode: line[open+2 : len(line)-1],
+		})
+	}
+
+	logger.Printf("AppArmor enabled=%t with %d loaded profiles", status.Enabled, len(status.Profiles))
+	return status
+}
+
+// collectHardeningSysctls reads the curated sysctls from /proc/sys, skipping
+// parameters the running kernel does not provide
+func collectHardeningSysctls() []model.SysctlSetting {
+	settings := []model.SysctlSetting{}
+
+	for _, sysctl := range hardeningSysctls {
+		path := filepath.Join("/proc/sys", strings.ReplaceAll(sysctl.name, ".", "/"))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(data))
+		setting := model.SysctlSetting{
+			Name:     sysctl.name,
+			Value:    value,
+			Expected: strings.Join(sysctl.allowed, " or "),
+		}
+		for _, allowed := range sysctl.allowed {
+			if value == allowed {
+				setting.Compliant = true
+				break
+			}
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
@@ -0,0 +1,754 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// Service manager names as reported in model.Service.Manager
+const (
+	managerSystemd     = "systemd"
+	managerOpenRC      = "openrc"
+	managerSysVInit    = "sysvinit"
+	managerRunit       = "runit"
+	managerS6          = "s6"
+	managerSupervisord = "supervisord"
+)
+
+// serviceStatusTimeout bounds how long a single service status query may take
+const serviceStatusTimeout = 5 * time.Second
+
+// serviceManagers detects the host's service managers once for all status queries
+var serviceManagers = sync.OnceValue(func() []string {
+	return detectServiceManagers(detectInitSystem())
+})
+
+// runitServiceDirs are the directories runit service definitions live in
+var runitServiceDirs = []string{"/etc/sv", "/etc/runit/sv"}
+
+// runitEnabledDirs are the directories runsvdir supervises; links here enable a service
+var runitEnabledDirs = []string{"/var/service", "/etc/service", "/run/runit/service", "/etc/runit/runsvdir/default"}
+
+// s6ServiceDirs are s6 and s6-overlay service definition directories
+var s6ServiceDirs = []string{"/etc/s6-overlay/s6-rc.d", "/etc/services.d", "/etc/s6/sv", "/etc/s6-rc/source"}
+
+// supervisordConfigPaths are the usual supervisord configuration locations
+var supervisordConfigPaths = []string{
+	"/etc/supervisord.conf",
+	"/etc/supervisor/supervisord.conf",
+	"/usr/local/etc/supervisord.conf",
+	"/etc/supervisor.conf",
+}
+
+// DetectServices inventories services from every service manager present on the host
+func DetectServices(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting services")
+
+	report.SystemInfo.InitSystem = detectInitSystem()
+	managers := detectServiceManagers(report.SystemInfo.InitSystem)
+	logger.Printf("Init system: %s, service managers: %s", report.SystemInfo.InitSystem, strings.Join(managers, ", "))
+
+	for _, manager := range managers {
+		switch manager {
+		case managerSystemd:
+			collectSystemdServices(report, logger)
+		case managerOpenRC:
+			collectOpenRCServices(report, logger)
+		case managerSysVInit:
+			collectSysVServices(report, logger)
+		case managerRunit:
+			collectRunitServices(report, logger)
+		case managerS6:
+			collectS6Services(report, logger)
+		case managerSupervisord:
+			collectSupervisordPrograms(report, logger)
+		}
+	}
+
+	logger.Printf("Detected %d services", len(report.Services))
+}
+
+// detectInitSystem identifies the service manager running as PID 1
+func detectInitSystem() string {
+	if _, err := os.Stat("/run/systemd/system"); err == nil {
+		return managerSystemd
+	}
+
+	comm, err := os.ReadFile("/proc/1/comm")
+	if err != nil {
+		return "unknown"
+	}
+
+	switch name := strings.TrimSpace(string(comm)); {
+	case name == "systemd":
+		return managerSystemd
+	case name == "openrc-init":
+		return managerOpenRC
+	case name == "runit" || name == "runsvdir":
+		return managerRunit
+	case strings.HasPrefix(name, "s6-"):
+		return managerS6
+	case name == "supervisord":
+		return managerSupervisord
+	case name == "init":
+		// OpenRC commonly runs under sysvinit's /sbin/init
+		if _, err := os.Stat("/run/openrc"); err == nil {
+			return managerOpenRC
+		}
+		return managerSysVInit
+	default:
+		return name
+	}
+}
+
+// detectServiceManagers lists the service managers with configuration on this host.
+// Several can coexist, e.g. supervisord inside a systemd-managed machine.
+func detectServiceManagers(initSystem string) []string {
+	var managers []string
+
+	if initSystem == managerSystemd || anyPathExists(systemdUnitDirs) {
+		managers = append(managers, managerSystemd)
+	}
+
+	openRC := initSystem == managerOpenRC || pathExists("/etc/runlevels")
+	if openRC {
+		managers = append(managers, managerOpenRC)
+	}
+
+	// On systemd and OpenRC hosts /etc/init.d scripts are already covered
+	if !openRC && initSystem != managerSystemd && pathExists("/etc/init.d") {
+		managers = append(managers, managerSysVInit)
+	}
+
+	if initSystem == managerRunit || anyPathExists(runitServiceDirs) {
+		managers = append(managers, managerRunit)
+	}
+
+	if initSystem == managerS6 || anyPathExists(s6ServiceDirs) {
+		managers = append(managers, managerS6)
+	}
+
+	if findExistingPath(supervisordConfigPaths) != "" {
+		managers = append(managers, managerSupervisord)
+	}
+
+	return managers
+}
+
+// isRunningUnderServiceManager queries the given non-systemd service managers for a running service
+func isRunningUnderServiceManager(serviceName string, managers []string) bool {
+	for _, manager := range managers {
+		switch manager {
+		case managerOpenRC:
+			if runStatusCommand("rc-service", serviceName, "status") == nil {
+				return true
+			}
+		case managerRunit:
+			// sv prints "run: name: (pid 123) 45s" for running services
+			output, err := statusCommandOutput("sv", "status", serviceName)
+			if err == nil && strings.HasPrefix(string(output), "run:") {
+				return true
+			}
+		case managerS6:
+			for _, liveDir := range []string{"/run/service", "/var/run/s6/services"} {
+				output, err := statusCommandOutput("s6-svstat", "-o", "up", filepath.Join(liveDir, serviceName))
+				if err == nil && strings.TrimSpace(string(output)) == "true" {
+					return true
+				}
+			}
+		case managerSupervisord:
+			output, _ := statusCommandOutput("supervisorctl", "status", serviceName)
+			if strings.Contains(string(output), "RUNNING") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runStatusCommand runs a service status query, giving up after serviceStatusTimeout
+func runStatusCommand(name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), serviceStatusTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Run()
+}
+
+// statusCommandOutput runs a service status query and returns its output,
+// giving up after serviceStatusTimeout
+func statusCommandOutput(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), serviceStatusTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// collectOpenRCServices reads /etc/init.d scripts and their runlevel assignments
+func collectOpenRCServices(report *model.DiscoveryReport, logger *log.Logger) {
+	// Map services to the runlevels that start them
+	runlevels := make(map[string][]string)
+	levelDirs, _ := filepath.Glob("/etc/runlevels/*")
+	for _, levelDir := range levelDirs {
+		entries, err := os.ReadDir(levelDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			runlevels[entry.Name()] = append(runlevels[entry.Name()], filepath.Base(levelDir))
+		}
+	}
+
+	for _, script := range listInitScripts(logger) {
+		name := filepath.Base(script)
+		vars := parseShellAssignments(script)
+
+		service := model.Service{
+			Name:             name,
+			Manager:          managerOpenRC,
+			Description:      vars["description"],
+			UnitFile:         script,
+			ExecStart:        []string{},
+			WorkingDirectory: vars["directory"],
+			WantedBy:         runlevels[name],
+			EnabledState:     "disabled",
+		}
+		if command := vars["command"]; command != "" {
+			service.ExecStart = append(service.ExecStart, strings.TrimSpace(command+" "+vars["command_args"]))
+		}
+		// command_user is "user[:group]"
+		service.User, _, _ = strings.Cut(vars["command_user"], ":")
+		if len(service.WantedBy) > 0 {
+			service.EnabledState = "enabled"
+		}
+		if confd := filepath.Join("/etc/conf.d", name); pathExists(confd) {
+			service.EnvironmentFiles = []string{confd}
+		}
+
+		// OpenRC keeps state markers for started services
+		if pathExists("/run/openrc") {
+			service.ActiveState = "inactive"
+			if pathExists(filepath.Join("/run/openrc/started", name)) {
+				service.ActiveState = "active"
+			} else if pathExists(filepath.Join("/run/openrc/failed", name)) {
+				service.ActiveState = "failed"
+			}
+		}
+
+		report.Services = append(report.Services, service)
+	}
+}
+
+// collectSysVServices reads /etc/init.d scripts and their rc?.d start links
+func collectSysVServices(report *model.DiscoveryReport, logger *log.Logger) {
+	// S??name links in rc2.d..rc5.d start the service in that runlevel
+	runlevels := make(map[string][]string)
+	for _, level := range []string{"2", "3", "4", "5"} {
+		links, _ := filepath.Glob(filepath.Join("/etc/rc"+level+".d", "S*"))
+		for _, link := range links {
+			name := strings.TrimLeft(strings.TrimPrefix(filepath.Base(link), "S"), "0123456789")
+			runlevels[name] = append(runlevels[name], "runlevel"+level)
+		}
+	}
+
+	for _, script := range listInitScripts(logger) {
+		name := filepath.Base(script)
+		service := model.Service{
+			Name:         name,
+			Manager:      managerSysVInit,
+			Description:  readLSBDescription(script),
+			UnitFile:     script,
+			ExecStart:    []string{},
+			WantedBy:     runlevels[name],
+			EnabledState: "disabled",
+			ActiveState:  querySysVStatus(script),
+		}
+		if len(service.WantedBy) > 0 {
+			service.EnabledState = "enabled"
+		}
+		if defaults := filepath.Join("/etc/default", name); pathExists(defaults) {
+			service.EnvironmentFiles = []string{defaults}
+		}
+
+		report.Services = append(report.Services, service)
+	}
+}
+
+// listInitScripts returns the executable scripts in /etc/init.d
+func listInitScripts(logger *log.Logger) []string {
+	entries, err := os.ReadDir("/etc/init.d")
+	if err != nil {
+		logger.Printf("Error reading /etc/init.d: %v", err)
+		return nil
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "README") || name == "functions" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		scripts = append(scripts, filepath.Join("/etc/init.d", name))
+	}
+	return scripts
+}
+
+// readLSBDescription extracts the Short-Description from an LSB init script header
+func readLSBDescription(script string) string {
+	file, err := os.Open(script)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, ok := strings.CutPrefix(line, "# Short-Description:"); ok {
+			return strings.TrimSpace(value)
+		}
+		if strings.HasPrefix(line, "### END INIT INFO") {
+			break
+		}
+	}
+	return ""
+}
+
+// querySysVStatus determines whether an init script's daemon is running
+// without executing the script, which may have side effects when run as root.
+// The pid files the script names are checked first, then start-stop-daemon
+// looks for the daemon binary it names.
+func querySysVStatus(script string) string {
+	pidFiles, daemon := inspectInitScript(script)
+	name := filepath.Base(script)
+	for _, pidFile := range []string{"/run/" + name + ".pid", "/var/run/" + name + ".pid", "/run/" + name + "/" + name + ".pid"} {
+		pidFiles = appendUnique(pidFiles, pidFile)
+	}
+	for _, pidFile := range pidFiles {
+		if state := pidFileState(pidFile); state != "" {
+			return state
+		}
+	}
+	if daemon == "" {
+		return "unknown"
+	}
+
+	// start-stop-daemon exits 0 when a process runs the binary and 3 when none does
+	err := runStatusCommand("start-stop-daemon", "--status", "--exec", daemon)
+	if err == nil {
+		return "active"
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 3 {
+		return "inactive"
+	}
+	return "unknown"
+}
+
+// inspectInitScript returns the literal PIDFILE paths and DAEMON binary an init script assigns
+func inspectInitScript(script string) ([]string, string) {
+	file, err := os.Open(script)
+	if err != nil {
+		return nil, ""
+	}
+	defer file.Close()
+
+	var pidFiles []string
+	daemon := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		// Paths built from variables cannot be resolved without running the script
+		value = strings.Trim(value, `"'`)
+		if !filepath.IsAbs(value) || strings.ContainsAny(value, "$` ") {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimPrefix(key, "export ")) {
+		case "PIDFILE":
+			pidFiles = appendUnique(pidFiles, value)
+		case "DAEMON":
+			if daemon == "" {
+				daemon = value
+			}
+		}
+	}
+	return pidFiles, daemon
+}
+
+// pidFileState reports "active" when the process a pid file names is alive,
+// "inactive" when the pid file is stale and "" when there is no usable pid file
+func pidFileState(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return ""
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return ""
+	}
+	if pathExists(filepath.Join("/proc", strconv.Itoa(pid))) {
+		return "active"
+	}
+	return "inactive"
+}
+
+// collectRunitServices reads runit service directories and their supervise state
+func collectRunitServices(report *model.DiscoveryReport, logger *log.Logger) {
+	// A service is enabled when linked into a directory runsvdir supervises
+	enabled := make(map[string]string)
+	for _, dir := range runitEnabledDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if _, ok := enabled[entry.Name()]; !ok {
+				enabled[entry.Name()] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	for _, dir := range runitServiceDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			serviceDir := filepath.Join(dir, entry.Name())
+			runScript := filepath.Join(serviceDir, "run")
+			if !pathExists(runScript) {
+				continue
+			}
+
+			service := model.Service{
+				Name:         entry.Name(),
+				Manager:      managerRunit,
+				UnitFile:     runScript,
+				ExecStart:    []string{},
+				EnabledState: "disabled",
+			}
+			if command := findExecLine(runScript); command != "" {
+				service.ExecStart = append(service.ExecStart, command)
+			}
+			if pathExists(filepath.Join(serviceDir, "env")) {
+				service.EnvironmentFiles = []string{filepath.Join(serviceDir, "env")}
+			}
+
+			stateDir := serviceDir
+			if link, ok := enabled[entry.Name()]; ok {
+				service.EnabledState = "enabled"
+				stateDir = link
+			}
+			// runsv writes "run" or "down" to supervise/stat
+			if stat, err := os.ReadFile(filepath.Join(stateDir, "supervise", "stat")); err == nil {
+				service.ActiveState = runitActiveState(strings.TrimSpace(string(stat)))
+			}
+
+			report.Services = append(report.Services, service)
+		}
+	}
+}
+
+// runitActiveState maps runsv's supervise/stat contents to an active state
+func runitActiveState(stat string) string {
+	switch {
+	case strings.HasPrefix(stat, "run"):
+		return "active"
+	case strings.HasPrefix(stat, "down"):
+		return "inactive"
+	case strings.HasPrefix(stat, "finish"):
+		return "deactivating"
+	default:
+		return stat
+	}
+}
+
+// collectS6Services reads s6 and s6-overlay service definitions
+func collectS6Services(report *model.DiscoveryReport, logger *log.Logger) {
+	_, svstatErr := exec.LookPath("s6-svstat")
+
+	for _, dir := range s6ServiceDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			serviceDir := filepath.Join(dir, entry.Name())
+
+			// s6-rc bundles and oneshots have no run script
+			runScript := filepath.Join(serviceDir, "run")
+			if !pathExists(runScript) {
+				continue
+			}
+
+			service := model.Service{
+				Name:         entry.Name(),
+				Manager:      managerS6,
+				UnitFile:     runScript,
+				ExecStart:    []string{},
+				EnabledState: "enabled",
+			}
+			if command := findExecLine(runScript); command != "" {
+				service.ExecStart = append(service.ExecStart, command)
+			}
+			if pathExists(filepath.Join(serviceDir, "down")) {
+				service.EnabledState = "disabled"
+			}
+
+			// Live services are scanned from /run/service (s6-overlay v3) or /var/run/s6/services
+			if svstatErr == nil {
+				for _, liveDir := range []string{"/run/service", "/var/run/s6/services"} {
+					live := filepath.Join(liveDir, entry.Name())
+					if !pathExists(live) {
+						continue
+					}
+					if output, err := exec.Command("s6-svstat", "-o", "up", live).Output(); err == nil {
+						service.ActiveState = "inactive"
+						if strings.TrimSpace(string(output)) == "true" {
+							service.ActiveState = "active"
+						}
+					}
+					break
+				}
+			}
+
+			report.Services = append(report.Services, service)
+		}
+	}
+}
+
+// findExecLine returns the command a run script hands control to with exec
+func findExecLine(script string) string {
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return ""
+	}
+
+	command := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "exec "); ok {
+			command = strings.TrimSpace(rest)
+		}
+	}
+	// execline scripts have no exec keyword; fall back to the last line
+	if command == "" {
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); !strings.HasPrefix(last, "#") {
+			command = last
+		}
+	}
+	return command
+}
+
+// collectSupervisordPrograms reads [program:x] sections from supervisord configuration
+func collectSupervisordPrograms(report *model.DiscoveryReport, logger *log.Logger) {
+	configPath := findExistingPath(supervisordConfigPaths)
+	sections := parseINIFile(configPath)
+
+	// Follow [include] files= globs, relative to the main config file
+	configFiles := map[string]string{}
+	for section := range sections {
+		configFiles[section] = configPath
+	}
+	for _, pattern := range strings.Fields(sections["include"]["files"]) {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(configPath), pattern)
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			for section, values := range parseINIFile(match) {
+				sections[section] = values
+				configFiles[section] = match
+			}
+		}
+	}
+
+	states := getSupervisordStates(logger)
+
+	var names []string
+	for section := range sections {
+		if strings.HasPrefix(section, "program:") {
+			names = append(names, section)
+		}
+	}
+	sort.Strings(names)
+
+	for _, section := range names {
+		program := sections[section]
+		name := strings.TrimPrefix(section, "program:")
+
+		service := model.Service{
+			Name:             name,
+			Manager:          managerSupervisord,
+			UnitFile:         configFiles[section],
+			ExecStart:        []string{},
+			User:             program["user"],
+			WorkingDirectory: program["directory"],
+			EnabledState:     "enabled",
+			ActiveState:      states[name],
+		}
+		if command := program["command"]; command != "" {
+			service.ExecStart = append(service.ExecStart, command)
+		}
+		if strings.EqualFold(program["autostart"], "false") {
+			service.EnabledState = "disabled"
+		}
+
+		report.Services = append(report.Services, service)
+	}
+
+	logger.Printf("Found %d supervisord programs in %s", len(names), configPath)
+}
+
+// getSupervisordStates asks a running supervisord for the state of each program
+func getSupervisordStates(logger *log.Logger) map[string]string {
+	states := make(map[string]string)
+	if _, err := exec.LookPath("supervisorctl"); err != nil {
+		return states
+	}
+
+	// supervisorctl exits non-zero when any program is not running, so keep the output regardless
+	output, _ := exec.Command("supervisorctl", "status").Output()
+
+	// Each line is: NAME STATE details, with NAME possibly "group:name"
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name := fields[0]
+		if _, program, ok := strings.Cut(name, ":"); ok {
+			name = program
+		}
+		switch fields[1] {
+		case "RUNNING":
+			states[name] = "active"
+		case "STOPPED", "EXITED":
+			states[name] = "inactive"
+		case "FATAL", "BACKOFF":
+			states[name] = "failed"
+		default:
+			states[name] = strings.ToLower(fields[1])
+		}
+	}
+	return states
+}
+
+// parseINIFile reads a simple INI file into sections of key/value pairs.
+// Indented lines continue the previous value, as in Python's configparser.
+func parseINIFile(path string) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sections
+	}
+
+	section, lastKey := "", ""
+	for _, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			if sections[section] == nil {
+				sections[section] = make(map[string]string)
+			}
+			lastKey = ""
+			continue
+		}
+		if section == "" {
+			continue
+		}
+
+		if (raw[0] == ' ' || raw[0] == '\t') && lastKey != "" {
+			sections[section][lastKey] += " " + line
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		lastKey = strings.TrimSpace(key)
+		sections[section][lastKey] = strings.TrimSpace(value)
+	}
+
+	return sections
+}
+
+// parseShellAssignments extracts top-level NAME=value assignments from a shell script
+func parseShellAssignments(path string) map[string]string {
+	vars := make(map[string]string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return vars
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// Only unindented assignments are global variables
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.ContainsAny(key, " \t$(") {
+			continue
+		}
+		// Drop trailing comments on unquoted values
+		value = strings.TrimSpace(value)
+		if !strings.HasPrefix(value, "\"") && !strings.HasPrefix(value, "'") {
+			value, _, _ = strings.Cut(value, " #")
+		}
+		vars[key] = strings.Trim(value, "\"'")
+	}
+	return vars
+}
+
+// pathExists reports whether a file or directory exists
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// anyPathExists reports whether at least one of the paths exists
+func anyPathExists(paths []string) bool {
+	return findExistingPath(paths) != ""
+}
+
+// findExistingPath returns the first path that exists, without logging
+func findExistingPath(paths []string) string {
+	for _, path := range paths {
+		if pathExists(path) {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// weakSSHCiphers, weakSSHMACs and weakSSHKex are algorithms a hardened
+// configuration should not offer
+var (
+	weakSSHCiphers = []string{"3des-cbc", "aes128-cbc", "aes192-cbc", "aes256-cbc", "arcfour", "blowfish-cbc", "cast128-cbc", "rijndael-cbc@lysator.liu.se"}
+	weakSSHMACs    = []string{"hmac-md5", "hmac-sha1", "umac-64", "hmac-ripemd160"}
+	weakSSHKex     = []string{"diffie-hellman-group1-sha1", "diffie-hellman-group14-sha1", "diffie-hellman-group-exchange-sha1"}
+)
+
+// defaultSSHHostKeys are the host keys sshd loads when no HostKey is configured
+var defaultSSHHostKeys = []string{
+	"/etc/ssh/ssh_host_rsa_key",
+	"/etc/ssh/ssh_host_ecdsa_key",
+	"/etc/ssh/ssh_host_ed25519_key",
+}
+
+// defaultAuthorizedKeysFiles are the key files sshd reads when AuthorizedKeysFile
+// is not set, relative to the user's home directory
+var defaultAuthorizedKeysFiles = []string{".ssh/authorized_keys", ".ssh/authorized_keys2"}
+
+// sshdConfig is the parsed global section of sshd_config plus its Match blocks
+type sshdConfig struct {
+	global   map[string][]string
+	matches  []model.SSHMatchBlock
+	included []string
+}
+
+// first returns the effective value of a keyword; sshd uses the first one it reads
+func (c *sshdConfig) first(keyword string) string {
+	if values := c.global[keyword]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// DetectSSHServer describes the SSH daemon configuration and audits it against a hardened baseline
+func DetectSSHServer(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting SSH server")
+
+	configFilePaths := []string{
+		"/etc/ssh/sshd_config",           // Most Linux distros, macOS
+		"/usr/etc/ssh/sshd_config",       // openSUSE vendor config
+		"/usr/local/etc/ssh/sshd_config", // FreeBSD ports
+	}
+	configFile := findExistingFile(configFilePaths, logger)
+	if configFile == "" {
+		logger.Println("SSH server configuration not found")
+		return
+	}
+
+	config := &sshdConfig{global: make(map[string][]string)}
+	var currentMatch *model.SSHMatchBlock
+	parseSSHDConfig(configFile, config, &currentMatch, 0, logger)
+	if currentMatch != nil {
+		config.matches = append(config.matches, *currentMatch)
+	}
+
+	server := &model.SSHServer{
+		Status:                 getServiceStatus("ssh", "sshd", logger),
+		Version:                sshdVersion(),
+		ConfigFile:             configFile,
+		IncludedFiles:          config.included,
+		Ports:                  config.global["port"],
+		PermitRootLogin:        config.first("permitrootlogin"),
+		PasswordAuthentication: config.first("passwordauthentication"),
+		AuthorizedKeysFiles:    strings.Fields(config.first("authorizedkeysfile")),
+		Ciphers:                splitSSHList(config.first("ciphers")),
+		MACs:                   splitSSHList(config.first("macs")),
+		KexAlgorithms:          splitSSHList(config.first("kexalgorithms")),
+		MatchBlocks:            config.matches,
+		Deviations:             []string{},
+	}
+
+	// ListenAddress may carry its own port, which then replaces Port for that address
+	usesPort := len(config.global["listenaddress"]) == 0
+	var listenPorts []string
+	for _, value := range config.global["listenaddress"] {
+		host, port := splitSSHListenAddress(value)
+		server.ListenAddresses = appendUnique(server.ListenAddresses, host)
+		if port != "" {
+			listenPorts = appendUnique(listenPorts, port)
+		} else {
+			usesPort = true
+		}
+	}
+
+	// Fill in OpenSSH defaults for unset settings
+	if len(server.Ports) == 0 && usesPort {
+		server.Ports = []string{"22"}
+	}
+	for _, port := range listenPorts {
+		server.Ports = appendUnique(server.Ports, port)
+	}
+	if len(server.ListenAddresses) == 0 {
+		server.ListenAddresses = []string{"0.0.0.0", "::"}
+	}
+	if server.PermitRootLogin == "" {
+		server.PermitRootLogin = "prohibit-password"
+	}
+	if server.PasswordAuthentication == "" {
+		server.PasswordAuthentication = "yes"
+	}
+	if len(server.AuthorizedKeysFiles) == 0 {
+		server.AuthorizedKeysFiles = defaultAuthorizedKeysFiles
+	}
+
+	hostKeyPaths := config.global["hostkey"]
+	if len(hostKeyPaths) == 0 {
+		hostKeyPaths = defaultSSHHostKeys
+	}
+	for _, path := range hostKeyPaths {
+		if hostKey, ok := readSSHHostKey(path); ok {
+			server.HostKeys = append(server.HostKeys, hostKey)
+		}
+	}
+
+	server.Deviations = auditSSHServer(server, config)
+
+	report.SSHServer = server
+	logger.Printf("Detected SSH server: status=%s, config=%s, %d deviations from baseline",
+		server.Status, server.ConfigFile, len(server.Deviations))
+}
+
+// parseSSHDConfig reads an sshd_config file, expanding Include directives in place
+// and collecting the keywords that follow each Match line into its block
+func parseSSHDConfig(path string, config *sshdConfig, currentMatch **model.SSHMatchBlock, depth int, logger *log.Logger) {
+	if depth > 16 {
+		logger.Printf("Include nesting too deep at %s", path)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading SSH config file %s: %v", path, err)
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Keywords are case-insensitive and separated by whitespace or "="
+		keyword, value := splitSSHDirective(line)
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				// Relative includes are resolved against /etc/ssh
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join("/etc/ssh", pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, match := range matches {
+					config.included = append(config.included, match)
+					parseSSHDConfig(match, config, currentMatch, depth+1, logger)
+				}
+			}
+		case "match":
+			if *currentMatch != nil {
+				config.matches = append(config.matches, **currentMatch)
+			}
+			*currentMatch = &model.SSHMatchBlock{
+				Criteria: value,
+				Settings: make(map[string]string),
+				Source:   path,
+			}
+		default:
+			if *currentMatch != nil {
+				if _, exists := (*currentMatch).Settings[keyword]; !exists {
+					(*currentMatch).Settings[keyword] = value
+				}
+			} else {
+				config.global[keyword] = append(config.global[keyword], value)
+			}
+		}
+	}
+}
+
+// splitSSHDirective splits a config line into its lower-cased keyword and value
+func splitSSHDirective(line string) (string, string) {
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), ""
+	}
+	keyword := strings.ToLower(line[:end])
+	value := strings.TrimLeft(line[end:], " \t=")
+	return keyword, strings.Trim(value, "\"")
+}
+
+// splitSSHListenAddress splits a ListenAddress value such as "10.0.0.1:2222",
+// "[::1]:2222" or "::1 rdomain vrf0" into its address and optional port
+func splitSSHListenAddress(value string) (string, string) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	if host, port, err := net.SplitHostPort(fields[0]); err == nil {
+		return host, port
+	}
+	return strings.Trim(fields[0], "[]"), ""
+}
+
+// splitSSHList splits a comma-separated algorithm list
+func splitSSHList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// readSSHHostKey reads the public half of a host key for its type and fingerprint
+func readSSHHostKey(path string) (model.SSHHostKey, bool) {
+	data, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return model.SSHHostKey{}, false
+	}
+	key, ok := parseAuthorizedKey(strings.TrimSpace(string(data)))
+	if !ok {
+		return model.SSHHostKey{}, false
+	}
+	return model.SSHHostKey{
+		Path:        path,
+		Type:        key.Type,
+		Bits:        key.Bits,
+		Fingerprint: key.Fingerprint,
+	}, true
+}
+
+// auditSSHServer compares the configuration against a hardened baseline.
+// sshd accepts yes/no values in any case, so they are compared case-insensitively.
+func auditSSHServer(server *model.SSHServer, config *sshdConfig) []string {
+	deviations := []string{}
+
+	if strings.EqualFold(server.PermitRootLogin, "yes") {
+		deviations = append(deviations, "PermitRootLogin is yes; use no or prohibit-password")
+	}
+	if strings.EqualFold(server.PasswordAuthentication, "yes") {
+		deviations = append(deviations, "PasswordAuthentication is enabled; prefer public key authentication")
+	}
+	if strings.EqualFold(config.first("permitemptypasswords"), "yes") {
+		deviations = append(deviations, "PermitEmptyPasswords is yes")
+	}
+	if protocol := config.first("protocol"); strings.Contains(protocol, "1") {
+		deviations = append(deviations, "SSH protocol 1 is enabled")
+	}
+	if strings.EqualFold(config.first("x11forwarding"), "yes") {
+		deviations = append(deviations, "X11Forwarding is enabled")
+	}
+	if tries, err := strconv.Atoi(config.first("maxauthtries")); err == nil && tries > 4 {
+		deviations = append(deviations, fmt.Sprintf("MaxAuthTries is %d; 4 or fewer recommended", tries))
+	}
+
+	deviations = append(deviations, findWeakSSHAlgorithms("cipher", server.Ciphers, weakSSHCiphers)...)
+	deviations = append(deviations, findWeakSSHAlgorithms("MAC", server.MACs, weakSSHMACs)...)
+	deviations = append(deviations, findWeakSSHAlgorithms("key exchange", server.KexAlgorithms, weakSSHKex)...)
+
+	for _, hostKey := range server.HostKeys {
+		switch {
+		case hostKey.Type == "ssh-dss":
+			deviations = append(deviations, fmt.Sprintf("DSA host key %s is deprecated", hostKey.Path))
+		case hostKey.Type == "ssh-rsa" && hostKey.Bits > 0 && hostKey.Bits < 3072:
+			deviations = append(deviations, fmt.Sprintf("RSA host key %s is only %d bits", hostKey.Path, hostKey.Bits))
+		}
+	}
+
+	// Match blocks can re-enable what the global section disables
+	for _, match := range server.MatchBlocks {
+		if strings.EqualFold(match.Settings["permitrootlogin"], "yes") {
+			deviations = append(deviations, fmt.Sprintf("Match %s sets PermitRootLogin yes", match.Criteria))
+		}
+		if strings.EqualFold(match.Settings["passwordauthentication"], "yes") && !strings.EqualFold(server.PasswordAuthentication, "yes") {
+			deviations = append(deviations, fmt.Sprintf("Match %s enables PasswordAuthentication", match.Criteria))
+		}
+	}
+
+	return deviations
+}
+
+// findWeakSSHAlgorithms reports configured algorithms that match a weak prefix.
+// Lists starting with "-" remove algorithms and cannot weaken the configuration.
+func findWeakSSHAlgorithms(kind string, configured, weak []string) []string {
+	var deviations []string
+	for _, algorithm := range configured {
+		if strings.HasPrefix(algorithm, "-") {
+			continue
+		}
+		name := strings.TrimLeft(algorithm, "+^")
+		for _, weakName := range weak {
+			if strings.HasPrefix(name, weakName) {
+				deviations = append(deviations, fmt.Sprintf("Weak %s %s is allowed", kind, name))
+				break
+			}
+		}
+	}
+	return deviations
+}
+
+// sshdVersion asks the installed sshd binary for its version banner
+func sshdVersion() string {
+	if _, err := exec.LookPath("sshd"); err != nil {
+		return ""
+	}
+	// sshd -V prints e.g. "OpenSSH_9.2p1 Debian-2, OpenSSL 3.0.11" to stderr
+	output, _ := exec.Command("sshd", "-V").CombinedOutput()
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), ",")
+	if !strings.HasPrefix(version, "OpenSSH") {
+		return ""
+	}
+	return version
+}
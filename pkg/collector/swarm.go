@@ -0,0 +1,205 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// dockerSwarm holds the Swarm section of docker info
+type dockerSwarm struct {
+	NodeID           string `json:"NodeID"`
+	NodeAddr         string `json:"NodeAddr"`
+	LocalNodeState   string `json:"LocalNodeState"`
+	ControlAvailable bool   `json:"ControlAvailable"`
+	Error            string `json:"Error"`
+	Nodes            int    `json:"Nodes"`
+	Managers         int    `json:"Managers"`
+	Cluster          *struct {
+		ID string `json:"ID"`
+	} `json:"Cluster"`
+}
+
+// dockerService holds the parts of a docker service inspect payload the collector uses
+type dockerService struct {
+	ID   string `json:"ID"`
+	Spec struct {
+		Name   string            `json:"Name"`
+		Labels map[string]string `json:"Labels"`
+		Mode   struct {
+			Replicated    *struct{} `json:"Replicated"`
+			Global        *struct{} `json:"Global"`
+			ReplicatedJob *struct{} `json:"ReplicatedJob"`
+			GlobalJob     *struct{} `json:"GlobalJob"`
+		} `json:"Mode"`
+		TaskTemplate struct {
+			ContainerSpec struct {
+				Image   string `json:"Image"`
+				Configs []struct {
+					ConfigName string `json:"ConfigName"`
+				} `json:"Configs"`
+				Secrets []struct {
+					SecretName string `json:"SecretName"`
+				} `json:"Secrets"`
+			} `json:"ContainerSpec"`
+		} `json:"TaskTemplate"`
+	} `json:"Spec"`
+	Endpoint struct {
+		Ports []struct {
+			Protocol      string `json:"Protocol"`
+			TargetPort    int    `json:"TargetPort"`
+			PublishedPort int    `json:"PublishedPort"`
+			PublishMode   string `json:"PublishMode"`
+		} `json:"Ports"`
+	} `json:"Endpoint"`
+}
+
+// detectSwarm records whether the Docker engine is part of a Swarm and its
+// role. Only managers can list services, so workers report membership only.
+func detectSwarm(report *model.DiscoveryReport, logger *log.Logger) {
+	output, err := exec.Command("docker", "info", "--format", "{{json .Swarm}}").Output()
+	if err != nil {
+		logger.Printf("Error reading Swarm state: %v", err)
+		return
+	}
+	var swarm dockerSwarm
+	if err := json.Unmarshal(output, &swarm); err != nil {
+		logger.Printf("Error parsing Swarm state: %v", err)
+		return
+	}
+	if swarm.LocalNodeState == "" || swarm.LocalNodeState == "inactive" {
+		logger.Println("Docker is not part of a Swarm")
+		return
+	}
+
+	info := &model.SwarmInfo{
+		NodeID:      swarm.NodeID,
+		NodeAddress: swarm.NodeAddr,
+		State:       swarm.LocalNodeState,
+		Role:        "worker",
+	}
+	if swarm.Cluster != nil {
+		info.ClusterID = swarm.Cluster.ID
+	}
+	if swarm.Error != "" {
+		logger.Printf("Swarm reports an error: %s", swarm.Error)
+	}
+	report.Swarm = info
+
+	if !swarm.ControlAvailable {
+		logger.Printf("Detected Swarm worker node %s (state %s)", info.NodeID, info.State)
+		return
+	}
+	info.Role = "manager"
+	info.Nodes = swarm.Nodes
+	info.Managers = swarm.Managers
+	info.Services = listSwarmServices(logger)
+	info.Stacks = swarmStacks(info.Services)
+
+	logger.Printf("Detected Swarm manager node %s: %d nodes, %d services, %d stacks",
+		info.NodeID, info.Nodes, len(info.Services), len(info.Stacks))
+}
+
+// listSwarmServices returns every service in the Swarm with its replica
+// counts from docker service ls and its specification from docker service inspect
+func listSwarmServices(logger *log.Logger) []model.SwarmService {
+	output, err := exec.Command("docker", "service", "ls", "--format", "{{.Name}}\t{{.Replicas}}").Output()
+	if err != nil {
+		logger.Printf("Error listing Swarm services: %v", err)
+		return nil
+	}
+
+	replicas := make(map[string]string)
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, count, _ := strings.Cut(line, "\t")
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+			replicas[name] = strings.TrimSpace(count)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	output, err = exec.Command("docker", append([]string{"service", "inspect"}, names...)...).Output()
+	if err != nil {
+		logger.Printf("Error inspecting Swarm services: %v", err)
+		return nil
+	}
+	var payload []dockerService
+	if err := json.Unmarshal(output, &payload); err != nil {
+		logger.Printf("Error parsing Swarm services: %v", err)
+		return nil
+	}
+
+	var services []model.SwarmService
+	for _, entry := range payload {
+		spec := entry.Spec
+		service := model.SwarmService{
+			ID:       entry.ID,
+			Name:     spec.Name,
+			Stack:    spec.Labels["com.docker.stack.namespace"],
+			Image:    spec.TaskTemplate.ContainerSpec.Image,
+			Mode:     swarmServiceMode(entry),
+			Replicas: replicas[spec.Name],
+		}
+		for _, port := range entry.Endpoint.Ports {
+			service.Ports = append(service.Ports, model.SwarmPort{
+				TargetPort:    port.TargetPort,
+				PublishedPort: port.PublishedPort,
+				Protocol:      port.Protocol,
+				PublishMode:   port.PublishMode,
+			})
+		}
+		for _, config := range spec.TaskTemplate.ContainerSpec.Configs {
+			service.Configs = appendUnique(service.Configs, config.ConfigName)
+		}
+		for _, secret := range spec.TaskTemplate.ContainerSpec.Secrets {
+			service.Secrets = appendUnique(service.Secrets, secret.SecretName)
+		}
+		services = append(services, service)
+		logger.Printf("Detected Swarm service %s: mode=%s, replicas=%s, image=%s", service.Name, service.Mode, service.Replicas, service.Image)
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// swarmServiceMode names the scheduling mode of a service
+func swarmServiceMode(service dockerService) string {
+	mode := service.Spec.Mode
+	switch {
+	case mode.Global != nil:
+		return "global"
+	case mode.ReplicatedJob != nil:
+		return "replicated-job"
+	case mode.GlobalJob != nil:
+		return "global-job"
+	}
+	return "replicated"
+}
+
+// swarmStacks groups services by the stack they were deployed with
+func swarmStacks(services []model.SwarmService) []model.SwarmStack {
+	var stacks []model.SwarmStack
+	index := make(map[string]int)
+	for _, service := range services {
+		if service.Stack == "" {
+			continue
+		}
+		i, ok := index[service.Stack]
+		if !ok {
+			i = len(stacks)
+			index[service.Stack] = i
+			stacks = append(stacks, model.SwarmStack{Name: service.Stack})
+		}
+		stacks[i].Services = append(stacks[i].Services, service.Name)
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	return stacks
+}
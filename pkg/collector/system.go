@@ -4,8 +4,10 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/marolt/go-discovery/pkg/model"
 )
@@ -39,55 +41,113 @@ func CollectSystemInfo(report *model.DiscoveryReport, logger *log.Logger) {
 		}
 	}
 
-	logger.Printf("Collected system info: OS=%s, Version=%s, Kernel=%s",
-		report.SystemInfo.OSName, report.SystemInfo.OSVersion, report.SystemInfo.Kernel)
+	// Architecture and timezone apply everywhere; boot details come from /proc
+	collectPlatformDetails(&report.SystemInfo)
+	if runtime.GOOS == "linux" {
+		collectLinuxBootDetails(&report.SystemInfo, logger)
+	}
+
+	logger.Printf("Collected system info: OS=%s, Version=%s, Kernel=%s, Arch=%s",
+		report.SystemInfo.OSName, report.SystemInfo.OSVersion, report.SystemInfo.Kernel, report.SystemInfo.Architecture)
 }
 
 // collectLinuxInfo gathers information specific to Linux systems
 func collectLinuxInfo(report *model.DiscoveryReport, logger *log.Logger) {
 	// Initialize with defaults
-	osName := "Linux"
-	osVersion := "unknown"
-
-	// Try to get distribution info from /etc/os-release (systemd standard)
-	if data, err := os.ReadFile("/etc/os-release"); err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) != 2 {
-				continue
-			}
-			key := parts[0]
-			value := strings.Trim(parts[1], "\"'")
+	info := model.SystemInfo{
+		OSName:    "Linux",
+		OSVersion: "unknown",
+	}
 
-			switch key {
-			case "NAME":
-				osName = value
-			case "VERSION_ID":
-				osVersion = value
-			}
-		}
+	// Try to get distribution info from os-release (systemd standard); /etc
+	// takes precedence over the vendor copy in /usr/lib
+	if release, err := readOSRelease("/"); err == nil {
+		applyOSRelease(&info, release)
 	} else {
-		logger.Printf("Failed to read /etc/os-release: %v", err)
+		logger.Printf("Failed to read os-release: %v", err)
 
 		// Try alternative files for distribution identification
 		osInfo := tryAlternativeDistroFiles(logger)
 		if osInfo.name != "" {
-			osName = osInfo.name
+			info.OSName = osInfo.name
 		}
 		if osInfo.version != "" {
-			osVersion = osInfo.version
+			info.OSVersion = osInfo.version
 		}
 	}
 
+	// Look up the support window for the release
+	lookupDistroSupport(&info, time.Now(), logger)
+
 	// Get kernel version
-	kernel := getKernelVersion(logger)
+	info.Kernel = getKernelVersion(logger)
 
-	report.SystemInfo = model.SystemInfo{
-		OSName:    osName,
-		OSVersion: osVersion,
-		Kernel:    kernel,
+	report.SystemInfo = info
+}
+
+// applyOSRelease copies the distribution fields of os-release into the system info
+func applyOSRelease(info *model.SystemInfo, release map[string]string) {
+	if release["NAME"] != "" {
+		info.OSName = release["NAME"]
+	}
+	if release["VERSION_ID"] != "" {
+		info.OSVersion = release["VERSION_ID"]
+	}
+	info.OSID = release["ID"]
+	info.OSIDLike = strings.Fields(release["ID_LIKE"])
+	info.PrettyName = release["PRETTY_NAME"]
+	info.VersionCodename = release["VERSION_CODENAME"]
+	info.VariantID = release["VARIANT_ID"]
+	info.BuildID = release["BUILD_ID"]
+	info.CPEName = release["CPE_NAME"]
+}
+
+// readOSRelease parses os-release below the given root, which uses
+// shell-compatible quoting and allows comment lines starting with "#"
+func readOSRelease(root string) (map[string]string, error) {
+	var data []byte
+	var err error
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		if data, err = os.ReadFile(filepath.Join(root, path)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	release := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		release[strings.TrimSpace(key)] = unquoteOSReleaseValue(strings.TrimSpace(value))
+	}
+	return release, nil
+}
+
+// unquoteOSReleaseValue removes shell quoting and backslash escapes from a value
+func unquoteOSReleaseValue(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+
+	var unquoted strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		unquoted.WriteByte(value[i])
 	}
+	return unquoted.String()
 }
 
 // distroInfo holds distribution name and version
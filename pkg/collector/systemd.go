@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// systemdUnitDirs lists systemd unit search paths in order of precedence
+var systemdUnitDirs = []string{
+	"/etc/systemd/system.control",
+	"/run/systemd/transient",
+	"/run/systemd/generator.early",
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/run/systemd/generator",
+	"/usr/local/lib/systemd/system",
+	"/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/run/systemd/generator.late",
+}
+
+// systemdUnit is a parsed unit file merged with its drop-ins
+type systemdUnit struct {
+	name     string
+	path     string
+	dropIns  []string
+	masked   bool
+	sections map[string]map[string][]string
+}
+
+// get returns the effective value of a single-valued directive
+func (u *systemdUnit) get(section, key string) string {
+	values := u.sections[section][key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// getAll returns all values of a list directive
+func (u *systemdUnit) getAll(section, key string) []string {
+	return u.sections[section][key]
+}
+
+// collectSystemdServices inventories systemd service units from the unit files on disk
+func collectSystemdServices(report *model.DiscoveryReport, logger *log.Logger) {
+	units := findSystemdUnits(".service", logger)
+	if len(units) == 0 {
+		logger.Println("No systemd service units found")
+		return
+	}
+
+	enabledBy := findSystemdEnablement()
+	activeStates := getSystemdActiveStates(logger)
+
+	for _, unit := range units {
+		service := model.Service{
+			Name:             unit.name,
+			Manager:          managerSystemd,
+			Description:      unit.get("Unit", "Description"),
+			UnitFile:         unit.path,
+			DropIns:          unit.dropIns,
+			ExecStart:        unit.getAll("Service", "ExecStart"),
+			User:             unit.get("Service", "User"),
+			WorkingDirectory: unit.get("Service", "WorkingDirectory"),
+			EnvironmentFiles: unit.getAll("Service", "EnvironmentFile"),
+			WantedBy:         unit.getAll("Install", "WantedBy"),
+			EnabledState:     systemdEnabledState(unit, enabledBy),
+			ActiveState:      activeStates[unit.name],
+		}
+		if service.ExecStart == nil {
+			service.ExecStart = []string{}
+		}
+
+		report.Services = append(report.Services, service)
+	}
+
+	logger.Printf("Detected %d systemd services", len(units))
+}
+
+// findSystemdUnits loads every unit with the given suffix, honouring search path precedence
+func findSystemdUnits(suffix string, logger *log.Logger) []*systemdUnit {
+	seen := make(map[string]bool)
+	var units []*systemdUnit
+
+	for _, dir := range systemdUnitDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasSuffix(name, suffix) || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			unit, err := loadSystemdUnit(name, filepath.Join(dir, name))
+			if err != nil {
+				logger.Printf("Error reading unit file %s: %v", filepath.Join(dir, name), err)
+				continue
+			}
+			units = append(units, unit)
+		}
+	}
+
+	sort.Slice(units, func(i, j int) bool { return units[i].name < units[j].name })
+	return units
+}
+
+// loadSystemdUnit parses a unit file and applies its drop-in overrides
+func loadSystemdUnit(name, path string) (*systemdUnit, error) {
+	unit := &systemdUnit{
+		name:     name,
+		path:     path,
+		sections: make(map[string]map[string][]string),
+	}
+
+	// Units linked to /dev/null are masked and have no configuration
+	if target, err := filepath.EvalSymlinks(path); err == nil && target == os.DevNull {
+		unit.masked = true
+		return unit, nil
+	}
+
+	if err := parseSystemdUnitFile(path, unit.sections); err != nil {
+		return nil, err
+	}
+
+	// Drop-ins from every search path apply in file name order,
+	// with a higher-precedence directory shadowing same-named files
+	dropIns := make(map[string]string)
+	for _, dir := range systemdUnitDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, name+".d", "*.conf"))
+		for _, match := range matches {
+			if _, ok := dropIns[filepath.Base(match)]; !ok {
+				dropIns[filepath.Base(match)] = match
+			}
+		}
+	}
+	names := make([]string, 0, len(dropIns))
+	for base := range dropIns {
+		names = append(names, base)
+	}
+	sort.Strings(names)
+	for _, base := range names {
+		if err := parseSystemdUnitFile(dropIns[base], unit.sections); err == nil {
+			unit.dropIns = append(unit.dropIns, dropIns[base])
+		}
+	}
+
+	return unit, nil
+}
+
+// parseSystemdUnitFile merges the directives of one unit file into sections.
+// An empty assignment resets a list directive, as systemd does for ExecStart=.
+func parseSystemdUnitFile(path string, sections map[string]map[string][]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	continued := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Join lines ending with a backslash
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = continued + line
+		continued = ""
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			if sections[section] == nil {
+				sections[section] = make(map[string][]string)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			delete(sections[section], key)
+			continue
+		}
+		if key == "WantedBy" || key == "RequiredBy" || key == "EnvironmentFile" {
+			// These accept space-separated lists
+			sections[section][key] = append(sections[section][key], strings.Fields(value)...)
+		} else {
+			sections[section][key] = append(sections[section][key], value)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// findSystemdEnablement maps unit names to the targets whose .wants/.requires
+// directories link to them in the administrator and runtime configuration
+func findSystemdEnablement() map[string][]string {
+	enabledBy := make(map[string][]string)
+
+	for _, dir := range []string{"/etc/systemd/system", "/run/systemd/system"} {
+		for _, pattern := range []string{"*.wants", "*.requires"} {
+			linkDirs, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, linkDir := range linkDirs {
+				target := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(linkDir), ".wants"), ".requires")
+				entries, err := os.ReadDir(linkDir)
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					enabledBy[entry.Name()] = append(enabledBy[entry.Name()], target)
+				}
+			}
+		}
+	}
+
+	return enabledBy
+}
+
+// systemdEnabledState approximates `systemctl is-enabled` from the files on disk
+func systemdEnabledState(unit *systemdUnit, enabledBy map[string][]string) string {
+	switch {
+	case unit.masked:
+		return "masked"
+	case strings.HasPrefix(unit.path, "/run/systemd/generator"):
+		return "generated"
+	case strings.HasPrefix(unit.path, "/run/systemd/transient"):
+		return "transient"
+	}
+
+	if len(enabledBy[unit.name]) > 0 {
+		return "enabled"
+	}
+
+	install := unit.sections["Install"]
+	if len(install["WantedBy"]) == 0 && len(install["RequiredBy"]) == 0 &&
+		len(install["Alias"]) == 0 && len(install["Also"]) == 0 {
+		return "static"
+	}
+	if strings.Contains(unit.name, "@.") {
+		// Template instances are enabled under their instance name
+		prefix := strings.SplitN(unit.name, "@", 2)[0] + "@"
+		for name := range enabledBy {
+			if strings.HasPrefix(name, prefix) {
+				return "enabled"
+			}
+		}
+	}
+	return "disabled"
+}
+
+// getSystemdActiveStates asks a running systemd for the active state of every service
+func getSystemdActiveStates(logger *log.Logger) map[string]string {
+	states := make(map[string]string)
+
+	// Only query when systemd is the running init system
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return states
+	}
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return states
+	}
+
+	cmd := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-legend", "--plain", "--no-pager")
+	output, err := cmd.Output()
+	if err != nil {
+		logger.Printf("Error listing systemd units: %v", err)
+		return states
+	}
+
+	// Each line is: UNIT LOAD ACTIVE SUB DESCRIPTION
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 {
+			states[fields[0]] = fields[2]
+		}
+	}
+
+	return states
+}
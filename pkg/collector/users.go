@@ -0,0 +1,567 @@
+package collector
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// nonLoginShells are shells that refuse interactive logins
+var nonLoginShells = map[string]bool{
+	"/usr/sbin/nologin": true,
+	"/sbin/nologin":     true,
+	"/bin/false":        true,
+	"/usr/bin/false":    true,
+	"/bin/sync":         true,
+}
+
+// sshKeyTypes are the public key algorithms accepted in authorized_keys files
+var sshKeyTypes = map[string]bool{
+	"ssh-rsa":                            true,
+	"ssh-dss":                            true,
+	"ssh-ed25519":                        true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+// shadowEntry holds the non-secret parts of an /etc/shadow line
+type shadowEntry struct {
+	state         string
+	lastChanged   string
+	expires       string
+	accountExpiry string
+	expired       bool
+}
+
+// DetectUsers inventories local accounts, groups, sudo rules and SSH authorized keys.
+// Password hashes and private key material are never copied into the report.
+func DetectUsers(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Detecting local users")
+
+	groups, memberships := parseGroupFile("/etc/group", logger)
+	shadow := parseShadowFile("/etc/shadow", logger)
+
+	sudoRules := []model.SudoRule{}
+	for _, path := range []string{"/etc/sudoers", "/usr/local/etc/sudoers"} {
+		if pathExists(path) {
+			sudoRules = append(sudoRules, parseSudoers(path, 0, logger)...)
+		}
+	}
+
+	aliases := sudoUserAliases(sudoRules)
+
+	// sshd reads the key files named by AuthorizedKeysFile
+	keyFiles := defaultAuthorizedKeysFiles
+	if report.SSHServer != nil {
+		keyFiles = report.SSHServer.AuthorizedKeysFiles
+	}
+
+	accounts := parsePasswdFile("/etc/passwd", logger)
+	for i := range accounts {
+		account := &accounts[i]
+
+		// Supplementary groups plus the primary group
+		account.Groups = memberships[account.Name]
+		for _, group := range groups {
+			if group.GID == account.GID {
+				account.Groups = append([]string{group.Name}, account.Groups...)
+				break
+			}
+		}
+
+		expired := false
+		if entry, ok := shadow[account.Name]; ok {
+			account.PasswordState = entry.state
+			account.PasswordLastChanged = entry.lastChanged
+			account.PasswordExpires = entry.expires
+			account.AccountExpires = entry.accountExpiry
+			expired = entry.expired
+		} else {
+			account.PasswordState = "unknown"
+		}
+
+		account.AuthorizedKeys = readAuthorizedKeys(account, keyFiles, logger)
+		account.SudoAccess = hasSudoAccess(account, sudoRules, aliases)
+
+		validShell := !nonLoginShells[account.Shell]
+		hasCredential := account.PasswordState == "set" || account.PasswordState == "empty" ||
+			account.PasswordState == "unknown" || len(account.AuthorizedKeys) > 0
+		account.CanLogin = validShell && hasCredential && !expired
+	}
+
+	report.Users = model.UserInventory{
+		Accounts:  accounts,
+		Groups:    groups,
+		SudoRules: sudoRules,
+	}
+	logger.Printf("Detected %d users, %d groups and %d sudo rules", len(accounts), len(groups), len(sudoRules))
+}
+
+// parsePasswdFile reads accounts from /etc/passwd
+func parsePasswdFile(path string, logger *log.Logger) []model.UserAccount {
+	accounts := []model.UserAccount{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading %s: %v", path, err)
+		return accounts
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uid, _ := strconv.Atoi(fields[2])
+		gid, _ := strconv.Atoi(fields[3])
+		accounts = append(accounts, model.UserAccount{
+			Name:  fields[0],
+			UID:   uid,
+			GID:   gid,
+			Gecos: fields[4],
+			Home:  fields[5],
+			Shell: fields[6],
+		})
+	}
+	return accounts
+}
+
+// parseGroupFile reads groups and returns them with a user-to-groups index
+func parseGroupFile(path string, logger *log.Logger) ([]model.Group, map[string][]string) {
+	groups := []model.Group{}
+	memberships := make(map[string][]string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Printf("Error reading %s: %v", path, err)
+		return groups, memberships
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// name:password:gid:members
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		gid, _ := strconv.Atoi(fields[2])
+		group := model.Group{Name: fields[0], GID: gid, Members: []string{}}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				group.Members = append(group.Members, member)
+				memberships[member] = append(memberships[member], group.Name)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, memberships
+}
+
+// parseShadowFile reads password metadata from /etc/shadow without keeping hashes
+func parseShadowFile(path string, logger *log.Logger) map[string]shadowEntry {
+	entries := make(map[string]shadowEntry)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Usually only readable by root
+		logger.Printf("Error reading %s, password status unavailable: %v", path, err)
+		return entries
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// name:hash:lastchg:min:max:warn:inactive:expire:reserved
+		fields := strings.Split(line, ":")
+		if len(fields) < 8 {
+			continue
+		}
+
+		entry := shadowEntry{state: shadowPasswordState(fields[1])}
+		lastChanged, err := strconv.Atoi(fields[2])
+		if err == nil && lastChanged > 0 {
+			entry.lastChanged = shadowDate(lastChanged)
+			if maxDays, err := strconv.Atoi(fields[4]); err == nil && maxDays < 99999 {
+				entry.expires = shadowDate(lastChanged + maxDays)
+			}
+		} else if fields[2] == "0" {
+			// Zero forces a password change at next login
+			entry.expires = "change required"
+		}
+		if expire, err := strconv.Atoi(fields[7]); err == nil {
+			if expire == 0 {
+				// Day zero would be 1970-01-01; pam_unix treats it as expired
+				entry.accountExpiry = "expired"
+				entry.expired = true
+			} else {
+				entry.accountExpiry = shadowDate(expire)
+				entry.expired = time.Now().Unix()/86400 >= int64(expire)
+			}
+		}
+
+		entries[fields[0]] = entry
+	}
+	return entries
+}
+
+// shadowPasswordState classifies the password field of a shadow entry
+func shadowPasswordState(hash string) string {
+	switch {
+	case hash == "":
+		return "empty"
+	case hash == "*" || hash == "!" || hash == "!!" || hash == "!*":
+		return "none"
+	case strings.HasPrefix(hash, "!"):
+		// usermod -L prefixes the existing hash with "!"
+		return "locked"
+	case strings.HasPrefix(hash, "*"):
+		return "none"
+	default:
+		return "set"
+	}
+}
+
+// shadowDate converts days since the epoch into a date
+func shadowDate(days int) string {
+	return time.Unix(int64(days)*86400, 0).UTC().Format("2006-01-02")
+}
+
+// parseSudoers reads sudo rules, following @include/#include and @includedir/#includedir
+func parseSudoers(path string, depth int, logger *log.Logger) []model.SudoRule {
+	var rules []model.SudoRule
+
+	// sudo itself limits include nesting
+	if depth > 8 {
+		return rules
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Printf("Error reading sudoers file %s: %v", path, err)
+		return rules
+	}
+	defer file.Close()
+
+	continued := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = strings.TrimSpace(continued + line)
+		continued = ""
+
+		// Include directives may be written with @ or the legacy # prefix
+		if strings.HasPrefix(line, "@include") || strings.HasPrefix(line, "#include") {
+			directive, target, _ := strings.Cut(line[1:], " ")
+			target = strings.Trim(strings.TrimSpace(target), "\"")
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			switch directive {
+			case "include":
+				rules = append(rules, parseSudoers(target, depth+1, logger)...)
+				continue
+			case "includedir":
+				entries, err := os.ReadDir(target)
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					// sudo skips files ending in ~ or containing a dot
+					if entry.IsDir() || strings.HasSuffix(entry.Name(), "~") || strings.Contains(entry.Name(), ".") {
+						continue
+					}
+					rules = append(rules, parseSudoers(filepath.Join(target, entry.Name()), depth+1, logger)...)
+				}
+				continue
+			}
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "Defaults") {
+			continue
+		}
+
+		principal, rule, ok := splitSudoUserList(line)
+		if !ok {
+			continue
+		}
+		rules = append(rules, model.SudoRule{
+			Principal: principal,
+			Rule:      strings.TrimSpace(rule),
+			Source:    path,
+		})
+	}
+
+	return rules
+}
+
+// splitSudoUserList splits a sudoers line into its comma-separated user list
+// and the rest, allowing spaces around the commas as in "%admin, !bob ALL=(ALL) ALL"
+func splitSudoUserList(line string) (string, string, bool) {
+	i := 0
+	for {
+		end := strings.IndexAny(line[i:], " \t")
+		if end < 0 {
+			return "", "", false
+		}
+		i += end
+		rest := strings.TrimLeft(line[i:], " \t")
+		if !strings.HasSuffix(line[:i], ",") && !strings.HasPrefix(rest, ",") {
+			return line[:i], strings.TrimSpace(rest), true
+		}
+		i = len(line) - len(rest)
+		if strings.HasPrefix(rest, ",") {
+			i++
+		}
+	}
+}
+
+// sudoUserAliases collects User_Alias definitions, written as
+// "NAME = member, ..." with several definitions separated by ":"
+func sudoUserAliases(rules []model.SudoRule) map[string][]string {
+	aliases := make(map[string][]string)
+	for _, rule := range rules {
+		if rule.Principal != "User_Alias" {
+			continue
+		}
+		for _, definition := range strings.Split(rule.Rule, ":") {
+			name, members, ok := strings.Cut(definition, "=")
+			if !ok {
+				continue
+			}
+			name = strings.TrimSpace(name)
+			for _, member := range strings.Split(members, ",") {
+				if member = strings.TrimSpace(member); member != "" {
+					aliases[name] = append(aliases[name], member)
+				}
+			}
+		}
+	}
+	return aliases
+}
+
+// hasSudoAccess reports whether any sudo rule's user list grants the account
+// access by naming it, one of its groups or a User_Alias that contains either
+func hasSudoAccess(account *model.UserAccount, rules []model.SudoRule, aliases map[string][]string) bool {
+	for _, rule := range rules {
+		// Alias definitions are not grants themselves; Host_Alias, Runas_Alias
+		// and Cmnd_Alias only qualify where and what a user may run
+		if strings.HasSuffix(rule.Principal, "_Alias") {
+			continue
+		}
+		if allowed, _ := sudoListMatch(account, strings.Split(rule.Principal, ","), aliases, 0); allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// sudoListMatch applies sudo's rule for user lists: the last member that
+// covers the account decides, and a "!" before a member negates it. matched
+// is false when no member covers the account.
+func sudoListMatch(account *model.UserAccount, members []string, aliases map[string][]string, depth int) (allowed, matched bool) {
+	for i := len(members) - 1; i >= 0; i-- {
+		member := strings.TrimSpace(members[i])
+		negated := false
+		for strings.HasPrefix(member, "!") {
+			negated = !negated
+			member = strings.TrimSpace(member[1:])
+		}
+		if allowed, matched := sudoMemberMatch(account, member, aliases, depth); matched {
+			return allowed != negated, true
+		}
+	}
+	return false, false
+}
+
+// sudoMemberMatch reports whether a user, %group or alias name covers the
+// account; an alias can cover it and still deny it through a negated member
+func sudoMemberMatch(account *model.UserAccount, member string, aliases map[string][]string, depth int) (allowed, matched bool) {
+	if member == account.Name || member == "ALL" {
+		return true, true
+	}
+	if group, ok := strings.CutPrefix(member, "%"); ok {
+		for _, name := range account.Groups {
+			if name == group {
+				return true, true
+			}
+		}
+		return false, false
+	}
+	// Aliases may contain other aliases; the depth limit guards against cycles
+	if members, ok := aliases[member]; ok && depth < 8 {
+		return sudoListMatch(account, members, aliases, depth+1)
+	}
+	return false, false
+}
+
+// readAuthorizedKeys parses the authorized keys files sshd reads for an account
+// into key metadata. Paths may use the %h, %u and %U tokens and are relative to
+// the home directory unless absolute.
+func readAuthorizedKeys(account *model.UserAccount, keyFiles []string, logger *log.Logger) []model.AuthorizedKey {
+	home := account.Home
+	tokens := strings.NewReplacer("%%", "%", "%h", home, "%u", account.Name, "%U", strconv.Itoa(account.UID))
+
+	var keys []model.AuthorizedKey
+	for _, keyFile := range keyFiles {
+		if keyFile == "none" {
+			continue
+		}
+		path := tokens.Replace(keyFile)
+		if !filepath.IsAbs(path) {
+			if home == "" || home == "/" || home == "/nonexistent" {
+				continue
+			}
+			path = filepath.Join(home, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.Printf("Error reading %s: %v", path, err)
+			}
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if key, ok := parseAuthorizedKey(line); ok {
+				key.Source = path
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Source < keys[j].Source })
+	return keys
+}
+
+// parseAuthorizedKey parses "[options] keytype base64 [comment]"
+func parseAuthorizedKey(line string) (model.AuthorizedKey, bool) {
+	var key model.AuthorizedKey
+
+	// Options precede the key type and may contain quoted spaces
+	fields := splitAuthorizedKeyLine(line)
+	if len(fields) > 0 && !sshKeyTypes[fields[0]] {
+		key.Options = authorizedKeyOptionNames(fields[0])
+		fields = fields[1:]
+	}
+	if len(fields) < 2 || !sshKeyTypes[fields[0]] {
+		return key, false
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return key, false
+	}
+
+	sum := sha256.Sum256(blob)
+	key.Type = fields[0]
+	key.Bits = sshKeyBits(fields[0], blob)
+	key.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	key.Comment = strings.Join(fields[2:], " ")
+	return key, true
+}
+
+// authorizedKeyOptionNames reduces an options list such as
+// `command="/usr/bin/backup --token x",no-pty` to its names, "command,no-pty",
+// since option values may carry credentials
+func authorizedKeyOptionNames(options string) string {
+	var names []string
+	quoted := false
+	start := 0
+	for i := 0; i <= len(options); i++ {
+		if i < len(options) {
+			if options[i] == '\\' && quoted {
+				i++
+				continue
+			}
+			if options[i] == '"' {
+				quoted = !quoted
+			}
+			if quoted || options[i] != ',' {
+				continue
+			}
+		}
+		name, _, _ := strings.Cut(options[start:i], "=")
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+		start = i + 1
+	}
+	return strings.Join(names, ",")
+}
+
+// splitAuthorizedKeyLine splits on whitespace outside double quotes
+func splitAuthorizedKeyLine(line string) []string {
+	var fields []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// sshKeyBits returns the key size, reading the RSA modulus from the wire-format blob
+func sshKeyBits(keyType string, blob []byte) int {
+	switch keyType {
+	case "ssh-ed25519", "sk-ssh-ed25519@openssh.com":
+		return 256
+	case "ecdsa-sha2-nistp256", "sk-ecdsa-sha2-nistp256@openssh.com":
+		return 256
+	case "ecdsa-sha2-nistp384":
+		return 384
+	case "ecdsa-sha2-nistp521":
+		return 521
+	case "ssh-dss":
+		return 1024
+	case "ssh-rsa":
+		// string "ssh-rsa", mpint e, mpint n
+		var parts [][]byte
+		for len(blob) >= 4 && len(parts) < 3 {
+			size := binary.BigEndian.Uint32(blob)
+			if uint32(len(blob)-4) < size {
+				return 0
+			}
+			parts = append(parts, blob[4:4+size])
+			blob = blob[4+size:]
+		}
+		if len(parts) == 3 {
+			return new(big.Int).SetBytes(parts[2]).BitLen()
+		}
+	}
+	return 0
+}
@@ -11,6 +11,37 @@ import (
 	"github.com/marolt/go-discovery/pkg/model"
 )
 
+// apacheConfigPaths are the usual locations of the main Apache configuration file
+var apacheConfigPaths = []string{
+	"/etc/apache2/apache2.conf",          // Debian/Ubuntu
+	"/etc/apache2/httpd.conf",            // SUSE
+	"/etc/httpd/conf/httpd.conf",         // RHEL/CentOS/Fedora
+	"/usr/local/etc/apache24/httpd.conf", // FreeBSD
+	"/opt/homebrew/etc/httpd/httpd.conf", // macOS (Homebrew ARM64)
+	"/usr/local/etc/httpd/httpd.conf",    // macOS (Homebrew Intel)
+	"/usr/local/apache2/conf/httpd.conf", // Official httpd container image
+}
+
+// nginxConfigPaths are the usual locations of the main Nginx configuration file
+var nginxConfigPaths = []string{
+	"/etc/nginx/nginx.conf",              // Most Linux distros
+	"/usr/local/etc/nginx/nginx.conf",    // FreeBSD, macOS (Homebrew Intel)
+	"/opt/homebrew/etc/nginx/nginx.conf", // macOS (Homebrew ARM64)
+}
+
+// lighttpdConfigPaths are the usual locations of the Lighttpd configuration file
+var lighttpdConfigPaths = []string{
+	"/etc/lighttpd/lighttpd.conf",           // Most Linux distros
+	"/usr/local/etc/lighttpd/lighttpd.conf", // FreeBSD, macOS (Homebrew)
+}
+
+// caddyConfigPaths are the usual locations of the Caddyfile
+var caddyConfigPaths = []string{
+	"/etc/caddy/Caddyfile",           // Most Linux distros
+	"/usr/local/etc/caddy/Caddyfile", // FreeBSD, macOS (Homebrew)
+	"/etc/caddy/caddy.conf",          // Alternative name
+}
+
 // DetectWebServers identifies installed web servers
 func DetectWebServers(report *model.DiscoveryReport, logger *log.Logger) {
 	logger.Println("Detecting web servers")
@@ -27,6 +58,11 @@ func DetectWebServers(report *model.DiscoveryReport, logger *log.Logger) {
 	// Detect Caddy web server
 	detectCaddy(report, logger)
 
+	// Fingerprint the applications deployed in each document root
+	for i := range report.WebServers {
+		report.WebServers[i].Applications = fingerprintDocumentRoots(report.WebServers[i].DocumentRoots, logger)
+	}
+
 	logger.Printf("Detected %d web servers", len(report.WebServers))
 }
 
@@ -62,17 +98,7 @@ func detectApache(report *model.DiscoveryReport, logger *log.Logger) {
 	webServer.ConfigFile = getWebServerConfigFromCommand("apache", logger)
 
 	if webServer.ConfigFile == "" {
-		configFilePaths := []string{
-			"/etc/apache2/apache2.conf",          // Debian/Ubuntu
-			"/etc/apache2/httpd.conf",            // SUSE
-			"/etc/httpd/conf/httpd.conf",         // RHEL/CentOS/Fedora
-			"/usr/local/etc/apache24/httpd.conf", // FreeBSD
-			"/opt/homebrew/etc/httpd/httpd.conf", // macOS (Homebrew ARM64)
-			"/usr/local/etc/httpd/httpd.conf",    // macOS (Homebrew Intel)
-			"/etc/apache2/httpd.conf",            // macOS (Built-in)
-		}
-
-		webServer.ConfigFile = findExistingFile(configFilePaths, logger)
+		webServer.ConfigFile = findExistingFile(apacheConfigPaths, logger)
 	}
 
 	// Extract document roots from config
@@ -81,7 +107,7 @@ func detectApache(report *model.DiscoveryReport, logger *log.Logger) {
 		webServer.DocumentRoots = append(webServer.DocumentRoots, docRoots...)
 
 		// Look for included config files with DocumentRoot directives
-		includeFiles := extractApacheIncludeFiles(webServer.ConfigFile, logger)
+		includeFiles := extractApacheIncludeFiles("/", webServer.ConfigFile, logger)
 		for _, includeFile := range includeFiles {
 			additionalDocRoots := extractApacheDocumentRoots(includeFile, logger)
 			webServer.DocumentRoots = append(webServer.DocumentRoots, additionalDocRoots...)
@@ -115,13 +141,7 @@ func detectNginx(report *model.DiscoveryReport, logger *log.Logger) {
 	webServer.ConfigFile = getWebServerConfigFromCommand("nginx", logger)
 
 	if webServer.ConfigFile == "" {
-		configFilePaths := []string{
-			"/etc/nginx/nginx.conf",              // Most Linux distros
-			"/usr/local/etc/nginx/nginx.conf",    // FreeBSD, macOS (Homebrew Intel)
-			"/opt/homebrew/etc/nginx/nginx.conf", // macOS (Homebrew ARM64)
-		}
-
-		webServer.ConfigFile = findExistingFile(configFilePaths, logger)
+		webServer.ConfigFile = findExistingFile(nginxConfigPaths, logger)
 	}
 
 	// Extract document roots (root directives) from nginx config
@@ -130,7 +150,7 @@ func detectNginx(report *model.DiscoveryReport, logger *log.Logger) {
 		webServer.DocumentRoots = append(webServer.DocumentRoots, docRoots...)
 
 		// Look for included config files with root directives
-		includeFiles := extractNginxIncludeFiles(webServer.ConfigFile, logger)
+		includeFiles := extractNginxIncludeFiles("/", webServer.ConfigFile, logger)
 		for _, includeFile := range includeFiles {
 			additionalDocRoots := extractNginxDocumentRoots(includeFile, logger)
 			webServer.DocumentRoots = append(webServer.DocumentRoots, additionalDocRoots...)
@@ -165,31 +185,12 @@ func detectLighttpd(report *model.DiscoveryReport, logger *log.Logger) {
 	webServer.ConfigFile = getWebServerConfigFromCommand("lighttpd", logger)
 
 	if webServer.ConfigFile == "" {
-		configFilePaths := []string{
-			"/etc/lighttpd/lighttpd.conf",           // Most Linux distros
-			"/usr/local/etc/lighttpd/lighttpd.conf", // FreeBSD, macOS (Homebrew)
-		}
-
-		webServer.ConfigFile = findExistingFile(configFilePaths, logger)
+		webServer.ConfigFile = findExistingFile(lighttpdConfigPaths, logger)
 	}
 
 	// Extract document roots from Lighttpd config
 	if webServer.ConfigFile != "" {
-		if data, err := os.ReadFile(webServer.ConfigFile); err == nil {
-			content := string(data)
-			// Extract "server.document-root" values
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "server.document-root") {
-					parts := strings.SplitN(line, "=", 2)
-					if len(parts) == 2 {
-						docRoot := strings.Trim(strings.TrimSpace(parts[1]), "\"'")
-						webServer.DocumentRoots = append(webServer.DocumentRoots, docRoot)
-					}
-				}
-			}
-		}
+		webServer.DocumentRoots = append(webServer.DocumentRoots, extractLighttpdDocumentRoots(webServer.ConfigFile)...)
 	}
 
 	// Add Lighttpd to the report
@@ -220,32 +221,12 @@ func detectCaddy(report *model.DiscoveryReport, logger *log.Logger) {
 	webServer.ConfigFile = getWebServerConfigFromCommand("caddy", logger)
 
 	if webServer.ConfigFile == "" {
-		configFilePaths := []string{
-			"/etc/caddy/Caddyfile",           // Most Linux distros
-			"/usr/local/etc/caddy/Caddyfile", // FreeBSD, macOS (Homebrew)
-			"/etc/caddy/caddy.conf",          // Alternative name
-		}
-
-		webServer.ConfigFile = findExistingFile(configFilePaths, logger)
+		webServer.ConfigFile = findExistingFile(caddyConfigPaths, logger)
 	}
 
 	// Extract document roots from Caddy config
 	if webServer.ConfigFile != "" {
-		if data, err := os.ReadFile(webServer.ConfigFile); err == nil {
-			content := string(data)
-			// Look for root directives
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "root") {
-					parts := strings.Fields(line)
-					if len(parts) >= 2 {
-						docRoot := parts[1]
-						webServer.DocumentRoots = append(webServer.DocumentRoots, docRoot)
-					}
-				}
-			}
-		}
+		webServer.DocumentRoots = append(webServer.DocumentRoots, extractCaddyDocumentRoots(webServer.ConfigFile)...)
 	}
 
 	// Add Caddy to the report
@@ -274,6 +255,15 @@ func getServiceStatus(serviceName string, alternativeServiceName string, logger
 			}
 		}
 
+		// Ask OpenRC, runit, s6 or supervisord when one of them manages services
+		managers := serviceManagers()
+		if isRunningUnderServiceManager(serviceName, managers) {
+			return "Running"
+		}
+		if alternativeServiceName != "" && isRunningUnderServiceManager(alternativeServiceName, managers) {
+			return "Running"
+		}
+
 		// Then try service command
 		cmd = exec.Command("service", serviceName, "status")
 		if err := cmd.Run(); err == nil {
@@ -500,8 +490,51 @@ func extractApacheDocumentRoots(configFile string, logger *log.Logger) []string
 	return docRoots
 }
 
-// extractApacheIncludeFiles finds all included/imported configuration files in Apache config
-func extractApacheIncludeFiles(configFile string, logger *log.Logger) []string {
+// extractLighttpdDocumentRoots extracts server.document-root values from Lighttpd config
+func extractLighttpdDocumentRoots(configFile string) []string {
+	var docRoots []string
+	if data, err := os.ReadFile(configFile); err == nil {
+		content := string(data)
+		// Extract "server.document-root" values
+		lines := strings.Split(content, "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "server.document-root") {
+				parts := strings.SplitN(line, "=", 2)
+				if len(parts) == 2 {
+					docRoot := strings.Trim(strings.TrimSpace(parts[1]), "\"'")
+					docRoots = append(docRoots, docRoot)
+				}
+			}
+		}
+	}
+	return docRoots
+}
+
+// extractCaddyDocumentRoots extracts root directives from a Caddyfile
+func extractCaddyDocumentRoots(configFile string) []string {
+	var docRoots []string
+	if data, err := os.ReadFile(configFile); err == nil {
+		content := string(data)
+		// Look for root directives; "root * /srv" puts a matcher before the path
+		lines := strings.Split(content, "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "root") {
+				parts := strings.Fields(line)
+				if len(parts) >= 2 {
+					docRoot := parts[len(parts)-1]
+					docRoots = append(docRoots, docRoot)
+				}
+			}
+		}
+	}
+	return docRoots
+}
+
+// extractApacheIncludeFiles finds all included/imported configuration files in Apache config.
+// Absolute include paths are resolved below root.
+func extractApacheIncludeFiles(root string, configFile string, logger *log.Logger) []string {
 	var includeFiles []string
 
 	// Read the configuration file
@@ -523,6 +556,8 @@ func extractApacheIncludeFiles(configFile string, logger *log.Logger) []string {
 				// Handle relative paths
 				if !filepath.IsAbs(includePattern) {
 					includePattern = filepath.Join(filepath.Dir(configFile), includePattern)
+				} else {
+					includePattern = filepath.Join(root, includePattern)
 				}
 
 				// If it's a directory, find all .conf files
@@ -574,8 +609,9 @@ func extractNginxDocumentRoots(configFile string, logger *log.Logger) []string {
 	return docRoots
 }
 
-// extractNginxIncludeFiles finds all included configuration files in Nginx config
-func extractNginxIncludeFiles(configFile string, logger *log.Logger) []string {
+// extractNginxIncludeFiles finds all included configuration files in Nginx config.
+// Absolute include paths are resolved below root.
+func extractNginxIncludeFiles(root string, configFile string, logger *log.Logger) []string {
 	var includeFiles []string
 
 	// Read the configuration file
@@ -598,6 +634,8 @@ func extractNginxIncludeFiles(configFile string, logger *log.Logger) []string {
 				// Handle relative paths
 				if !filepath.IsAbs(includePattern) {
 					includePattern = filepath.Join(filepath.Dir(configFile), includePattern)
+				} else {
+					includePattern = filepath.Join(root, includePattern)
 				}
 
 				// Handle wildcards
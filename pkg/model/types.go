@@ -4,17 +4,153 @@ import "time"
 
 // SystemInfo contains basic information about the system
 type SystemInfo struct {
-	OSName    string `json:"os_name" yaml:"os_name"`
-	OSVersion string `json:"os_version" yaml:"os_version"`
-	Kernel    string `json:"kernel" yaml:"kernel"`
+	OSName          string         `json:"os_name" yaml:"os_name"`
+	OSVersion       string         `json:"os_version" yaml:"os_version"`
+	OSID            string         `json:"os_id,omitempty" yaml:"os_id,omitempty"`
+	OSIDLike        []string       `json:"os_id_like,omitempty" yaml:"os_id_like,omitempty"`
+	PrettyName      string         `json:"pretty_name,omitempty" yaml:"pretty_name,omitempty"`
+	VersionCodename string         `json:"version_codename,omitempty" yaml:"version_codename,omitempty"`
+	VariantID       string         `json:"variant_id,omitempty" yaml:"variant_id,omitempty"`
+	BuildID         string         `json:"build_id,omitempty" yaml:"build_id,omitempty"`
+	CPEName         string         `json:"cpe_name,omitempty" yaml:"cpe_name,omitempty"`
+	SupportStatus   string         `json:"support_status,omitempty" yaml:"support_status,omitempty"`
+	EndOfLife       string         `json:"end_of_life,omitempty" yaml:"end_of_life,omitempty"`
+	ExtendedSupport string         `json:"extended_support_end,omitempty" yaml:"extended_support_end,omitempty"`
+	Kernel          string         `json:"kernel" yaml:"kernel"`
+	InitSystem      string         `json:"init_system,omitempty" yaml:"init_system,omitempty"`
+	Architecture    string         `json:"architecture" yaml:"architecture"`
+	Timezone        string         `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	BootTime        string         `json:"boot_time,omitempty" yaml:"boot_time,omitempty"`
+	UptimeSeconds   int64          `json:"uptime_seconds,omitempty" yaml:"uptime_seconds,omitempty"`
+	Uptime          string         `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	NTPSynchronized string         `json:"ntp_synchronized,omitempty" yaml:"ntp_synchronized,omitempty"`
+	RebootRequired  bool           `json:"reboot_required" yaml:"reboot_required"`
+	RebootReasons   []string       `json:"reboot_reasons,omitempty" yaml:"reboot_reasons,omitempty"`
+	KernelCmdline   string         `json:"kernel_cmdline,omitempty" yaml:"kernel_cmdline,omitempty"`
+	KernelModules   []KernelModule `json:"kernel_modules,omitempty" yaml:"kernel_modules,omitempty"`
+}
+
+// KernelModule represents a loaded kernel module
+type KernelModule struct {
+	Name   string   `json:"name" yaml:"name"`
+	Size   int      `json:"size" yaml:"size"`
+	UsedBy []string `json:"used_by,omitempty" yaml:"used_by,omitempty"`
+	State  string   `json:"state" yaml:"state"`
 }
 
 // WebServer represents a detected web server
 type WebServer struct {
-	Type          string   `json:"type" yaml:"type"`
-	Status        string   `json:"status" yaml:"status"`
-	ConfigFile    string   `json:"config_file" yaml:"config_file"`
-	DocumentRoots []string `json:"document_roots" yaml:"document_roots"`
+	Type          string           `json:"type" yaml:"type"`
+	Status        string           `json:"status" yaml:"status"`
+	ConfigFile    string           `json:"config_file" yaml:"config_file"`
+	DocumentRoots []string         `json:"document_roots" yaml:"document_roots"`
+	Applications  []WebApplication `json:"applications,omitempty" yaml:"applications,omitempty"`
+}
+
+// WebApplication represents an application found in a web server's document root
+type WebApplication struct {
+	Type    string       `json:"type" yaml:"type"`
+	Version string       `json:"version,omitempty" yaml:"version,omitempty"`
+	Path    string       `json:"path" yaml:"path"`
+	Git     *GitCheckout `json:"git,omitempty" yaml:"git,omitempty"`
+}
+
+// GitCheckout describes a git working tree deployed on the host
+type GitCheckout struct {
+	Path   string `json:"path" yaml:"path"`
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Commit string `json:"commit" yaml:"commit"`
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+}
+
+// SSHServer represents the SSH daemon configuration and its baseline audit.
+// Empty algorithm lists mean the OpenSSH built-in defaults are in effect.
+type SSHServer struct {
+	Status                 string          `json:"status" yaml:"status"`
+	Version                string          `json:"version,omitempty" yaml:"version,omitempty"`
+	ConfigFile             string          `json:"config_file" yaml:"config_file"`
+	IncludedFiles          []string        `json:"included_files,omitempty" yaml:"included_files,omitempty"`
+	Ports                  []string        `json:"ports" yaml:"ports"`
+	ListenAddresses        []string        `json:"listen_addresses" yaml:"listen_addresses"`
+	PermitRootLogin        string          `json:"permit_root_login" yaml:"permit_root_login"`
+	PasswordAuthentication string          `json:"password_authentication" yaml:"password_authentication"`
+	AuthorizedKeysFiles    []string        `json:"authorized_keys_files" yaml:"authorized_keys_files"`
+	Ciphers                []string        `json:"ciphers,omitempty" yaml:"ciphers,omitempty"`
+	MACs                   []string        `json:"macs,omitempty" yaml:"macs,omitempty"`
+	KexAlgorithms          []string        `json:"kex_algorithms,omitempty" yaml:"kex_algorithms,omitempty"`
+	HostKeys               []SSHHostKey    `json:"host_keys" yaml:"host_keys"`
+	MatchBlocks            []SSHMatchBlock `json:"match_blocks,omitempty" yaml:"match_blocks,omitempty"`
+	Deviations             []string        `json:"deviations" yaml:"deviations"`
+}
+
+// SSHHostKey represents a host key served by the SSH daemon
+type SSHHostKey struct {
+	Path        string `json:"path" yaml:"path"`
+	Type        string `json:"type" yaml:"type"`
+	Bits        int    `json:"bits,omitempty" yaml:"bits,omitempty"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// SSHMatchBlock represents a conditional Match section of sshd_config
+type SSHMatchBlock struct {
+	Criteria string            `json:"criteria" yaml:"criteria"`
+	Settings map[string]string `json:"settings" yaml:"settings"`
+	Source   string            `json:"source" yaml:"source"`
+}
+
+// MailServer represents a detected MTA or IMAP/POP3 server
+type MailServer struct {
+	Type            string   `json:"type" yaml:"type"`
+	Status          string   `json:"status" yaml:"status"`
+	ConfigFile      string   `json:"config_file" yaml:"config_file"`
+	Hostname        string   `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	RelayHost       string   `json:"relay_host,omitempty" yaml:"relay_host,omitempty"`
+	ListenAddresses []string `json:"listen_addresses,omitempty" yaml:"listen_addresses,omitempty"`
+	Protocols       []string `json:"protocols,omitempty" yaml:"protocols,omitempty"`
+	Listeners       []string `json:"listeners,omitempty" yaml:"listeners,omitempty"`
+	MailLocation    string   `json:"mail_location,omitempty" yaml:"mail_location,omitempty"`
+	TLSCertificates []string `json:"tls_certificates,omitempty" yaml:"tls_certificates,omitempty"`
+}
+
+// InfrastructureService represents a detected DNS, DHCP, directory or file sharing server
+type InfrastructureService struct {
+	Type       string           `json:"type" yaml:"type"`
+	Status     string           `json:"status" yaml:"status"`
+	ConfigFile string           `json:"config_file" yaml:"config_file"`
+	Role       string           `json:"role,omitempty" yaml:"role,omitempty"`
+	Listeners  []string         `json:"listeners,omitempty" yaml:"listeners,omitempty"`
+	Resources  []ServedResource `json:"resources" yaml:"resources"`
+}
+
+// ServedResource represents a zone, share, directory suffix or address pool served by an infrastructure service
+type ServedResource struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Name   string `json:"name" yaml:"name"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// MessageBroker represents a detected message broker or streaming platform node
+type MessageBroker struct {
+	Type            string   `json:"type" yaml:"type"`
+	Status          string   `json:"status" yaml:"status"`
+	Version         string   `json:"version,omitempty" yaml:"version,omitempty"`
+	ConfigFile      string   `json:"config_file" yaml:"config_file"`
+	ConfigSource    string   `json:"config_source,omitempty" yaml:"config_source,omitempty"`
+	BrokerID        string   `json:"broker_id,omitempty" yaml:"broker_id,omitempty"`
+	Listeners       []string `json:"listeners" yaml:"listeners"`
+	DataDirectories []string `json:"data_directories,omitempty" yaml:"data_directories,omitempty"`
+	Plugins         []string `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Cluster         []string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+}
+
+// Agent represents a monitoring, logging or telemetry agent installed on the host
+type Agent struct {
+	Name       string   `json:"name" yaml:"name"`
+	Binary     string   `json:"binary" yaml:"binary"`
+	Version    string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Status     string   `json:"status" yaml:"status"`
+	ConfigFile string   `json:"config_file,omitempty" yaml:"config_file,omitempty"`
+	Endpoints  []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
 }
 
 // Database represents a detected database server
@@ -28,26 +164,371 @@ type Database struct {
 
 // DockerContainer represents a detected docker container
 type DockerContainer struct {
-	ContainerID    string   `json:"container_id" yaml:"container_id"`
+	ContainerID    string               `json:"container_id" yaml:"container_id"`
+	Name           string               `json:"name" yaml:"name"`
+	Image          string               `json:"image" yaml:"image"`
+	Entrypoint     []string             `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	Command        []string             `json:"command,omitempty" yaml:"command,omitempty"`
+	WorkingDir     string               `json:"working_dir,omitempty" yaml:"working_dir,omitempty"`
+	User           string               `json:"user,omitempty" yaml:"user,omitempty"`
+	Environment    map[string]string    `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Labels         map[string]string    `json:"labels,omitempty" yaml:"labels,omitempty"`
+	PortMappings   []ContainerPort      `json:"port_mappings" yaml:"port_mappings"`
+	Mounts         []ContainerMount     `json:"mounts" yaml:"mounts"`
+	Ports          []string             `json:"ports" yaml:"ports"`
+	Volumes        []string             `json:"volumes" yaml:"volumes"`
+	Networks       []string             `json:"networks" yaml:"networks"`
+	NetworkMode    string               `json:"network_mode,omitempty" yaml:"network_mode,omitempty"`
+	PidMode        string               `json:"pid_mode,omitempty" yaml:"pid_mode,omitempty"`
+	Privileged     bool                 `json:"privileged" yaml:"privileged"`
+	CapAdd         []string             `json:"cap_add,omitempty" yaml:"cap_add,omitempty"`
+	CapDrop        []string             `json:"cap_drop,omitempty" yaml:"cap_drop,omitempty"`
+	SecurityOpt    []string             `json:"security_opt,omitempty" yaml:"security_opt,omitempty"`
+	Resources      ContainerResources   `json:"resources" yaml:"resources"`
+	LogDriver      string               `json:"log_driver,omitempty" yaml:"log_driver,omitempty"`
+	LogOptions     map[string]string    `json:"log_options,omitempty" yaml:"log_options,omitempty"`
+	ManagedBy      string               `json:"managed_by" yaml:"managed_by"`
+	ComposeProject string               `json:"compose_project" yaml:"compose_project"`
+	ComposeService string               `json:"compose_service" yaml:"compose_service"`
+	ComposeFile    string               `json:"compose_file" yaml:"compose_file"`
+	ComposeFiles   []string             `json:"compose_files,omitempty" yaml:"compose_files,omitempty"`
+	SwarmService   string               `json:"swarm_service,omitempty" yaml:"swarm_service,omitempty"`
+	SwarmServiceID string               `json:"swarm_service_id,omitempty" yaml:"swarm_service_id,omitempty"`
+	SwarmTask      string               `json:"swarm_task,omitempty" yaml:"swarm_task,omitempty"`
+	SwarmTaskID    string               `json:"swarm_task_id,omitempty" yaml:"swarm_task_id,omitempty"`
+	SwarmStack     string               `json:"swarm_stack,omitempty" yaml:"swarm_stack,omitempty"`
+	Filesystem     *ContainerFilesystem `json:"filesystem,omitempty" yaml:"filesystem,omitempty"`
+}
+
+// ComposeProject represents a Docker Compose project with the files it was
+// created from and, when requested, its fully resolved configuration
+type ComposeProject struct {
 	Name           string   `json:"name" yaml:"name"`
-	Image          string   `json:"image" yaml:"image"`
-	Ports          []string `json:"ports" yaml:"ports"`
-	Volumes        []string `json:"volumes" yaml:"volumes"`
-	Networks       []string `json:"networks" yaml:"networks"`
-	ManagedBy      string   `json:"managed_by" yaml:"managed_by"`
-	ComposeProject string   `json:"compose_project" yaml:"compose_project"`
-	ComposeService string   `json:"compose_service" yaml:"compose_service"`
-	ComposeFile    string   `json:"compose_file" yaml:"compose_file"`
+	Status         string   `json:"status,omitempty" yaml:"status,omitempty"`
+	WorkingDir     string   `json:"working_dir,omitempty" yaml:"working_dir,omitempty"`
+	ConfigFiles    []string `json:"config_files" yaml:"config_files"`
+	Services       []string `json:"services,omitempty" yaml:"services,omitempty"`
+	ResolvedConfig string   `json:"resolved_config,omitempty" yaml:"resolved_config,omitempty"`
+}
+
+// SwarmInfo describes this node's Docker Swarm membership and, on managers,
+// the services and stacks running in the cluster
+type SwarmInfo struct {
+	NodeID      string         `json:"node_id" yaml:"node_id"`
+	NodeAddress string         `json:"node_address,omitempty" yaml:"node_address,omitempty"`
+	State       string         `json:"state" yaml:"state"`
+	Role        string         `json:"role" yaml:"role"`
+	ClusterID   string         `json:"cluster_id,omitempty" yaml:"cluster_id,omitempty"`
+	Nodes       int            `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Managers    int            `json:"managers,omitempty" yaml:"managers,omitempty"`
+	Services    []SwarmService `json:"services,omitempty" yaml:"services,omitempty"`
+	Stacks      []SwarmStack   `json:"stacks,omitempty" yaml:"stacks,omitempty"`
+}
+
+// SwarmService represents a Swarm service. Configs and secrets are listed by
+// name only; their values are never read.
+type SwarmService struct {
+	ID       string      `json:"id" yaml:"id"`
+	Name     string      `json:"name" yaml:"name"`
+	Stack    string      `json:"stack,omitempty" yaml:"stack,omitempty"`
+	Image    string      `json:"image" yaml:"image"`
+	Mode     string      `json:"mode" yaml:"mode"`
+	Replicas string      `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	Ports    []SwarmPort `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Configs  []string    `json:"configs,omitempty" yaml:"configs,omitempty"`
+	Secrets  []string    `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+}
+
+// SwarmPort is a service port published through the routing mesh or on the host
+type SwarmPort struct {
+	TargetPort    int    `json:"target_port" yaml:"target_port"`
+	PublishedPort int    `json:"published_port,omitempty" yaml:"published_port,omitempty"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+	PublishMode   string `json:"publish_mode" yaml:"publish_mode"`
+}
+
+// SwarmStack is a group of services deployed together with docker stack deploy
+type SwarmStack struct {
+	Name     string   `json:"name" yaml:"name"`
+	Services []string `json:"services" yaml:"services"`
+}
+
+// ContainerPort is a container port and one host address it is published on.
+// Ports that are exposed but not published have no host IP or port.
+type ContainerPort struct {
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+	HostIP        string `json:"host_ip,omitempty" yaml:"host_ip,omitempty"`
+	HostPort      int    `json:"host_port,omitempty" yaml:"host_port,omitempty"`
+}
+
+// ContainerMount is a bind mount, named volume or tmpfs attached to a container
+type ContainerMount struct {
+	Type        string `json:"type" yaml:"type"`
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination string `json:"destination" yaml:"destination"`
+	ReadOnly    bool   `json:"read_only" yaml:"read_only"`
+	Propagation string `json:"propagation,omitempty" yaml:"propagation,omitempty"`
+}
+
+// ContainerResources holds the CPU, memory and process limits of a container;
+// zero means unlimited
+type ContainerResources struct {
+	CPUs              float64 `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	CPUShares         int64   `json:"cpu_shares,omitempty" yaml:"cpu_shares,omitempty"`
+	CPUQuota          int64   `json:"cpu_quota,omitempty" yaml:"cpu_quota,omitempty"`
+	CPUPeriod         int64   `json:"cpu_period,omitempty" yaml:"cpu_period,omitempty"`
+	CPUSet            string  `json:"cpuset,omitempty" yaml:"cpuset,omitempty"`
+	MemoryBytes       int64   `json:"memory_bytes,omitempty" yaml:"memory_bytes,omitempty"`
+	MemoryReservation int64   `json:"memory_reservation_bytes,omitempty" yaml:"memory_reservation_bytes,omitempty"`
+	MemorySwapBytes   int64   `json:"memory_swap_bytes,omitempty" yaml:"memory_swap_bytes,omitempty"`
+	PidsLimit         int64   `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+}
+
+// ContainerFilesystem holds the results of running the OS, web server and
+// package collectors against a container's root filesystem. Paths are as
+// seen inside the container.
+type ContainerFilesystem struct {
+	Method     string      `json:"method" yaml:"method"`
+	RootPath   string      `json:"root_path,omitempty" yaml:"root_path,omitempty"`
+	SystemInfo SystemInfo  `json:"system_info" yaml:"system_info"`
+	WebServers []WebServer `json:"web_servers" yaml:"web_servers"`
+	Components []Component `json:"components" yaml:"components"`
+	OSPackages []Component `json:"os_packages" yaml:"os_packages"`
+}
+
+// Service represents a service managed by systemd, OpenRC, SysV init, runit, s6 or supervisord
+type Service struct {
+	Name             string   `json:"name" yaml:"name"`
+	Manager          string   `json:"manager" yaml:"manager"`
+	Description      string   `json:"description,omitempty" yaml:"description,omitempty"`
+	UnitFile         string   `json:"unit_file" yaml:"unit_file"`
+	DropIns          []string `json:"drop_ins,omitempty" yaml:"drop_ins,omitempty"`
+	ExecStart        []string `json:"exec_start" yaml:"exec_start"`
+	User             string   `json:"user,omitempty" yaml:"user,omitempty"`
+	WorkingDirectory string   `json:"working_directory,omitempty" yaml:"working_directory,omitempty"`
+	EnvironmentFiles []string `json:"environment_files,omitempty" yaml:"environment_files,omitempty"`
+	WantedBy         []string `json:"wanted_by,omitempty" yaml:"wanted_by,omitempty"`
+	EnabledState     string   `json:"enabled_state" yaml:"enabled_state"`
+	ActiveState      string   `json:"active_state,omitempty" yaml:"active_state,omitempty"`
+}
+
+// ScheduledJob represents a cron, anacron or at job or a systemd timer
+type ScheduledJob struct {
+	Type     string `json:"type" yaml:"type"`
+	Schedule string `json:"schedule" yaml:"schedule"`
+	User     string `json:"user" yaml:"user"`
+	Command  string `json:"command" yaml:"command"`
+	Target   string `json:"target,omitempty" yaml:"target,omitempty"`
+	Source   string `json:"source" yaml:"source"`
+	NextRun  string `json:"next_run,omitempty" yaml:"next_run,omitempty"`
+}
+
+// UserAccount represents a local account with its password status and SSH keys
+type UserAccount struct {
+	Name                string          `json:"name" yaml:"name"`
+	UID                 int             `json:"uid" yaml:"uid"`
+	GID                 int             `json:"gid" yaml:"gid"`
+	Gecos               string          `json:"gecos,omitempty" yaml:"gecos,omitempty"`
+	Home                string          `json:"home" yaml:"home"`
+	Shell               string          `json:"shell" yaml:"shell"`
+	Groups              []string        `json:"groups,omitempty" yaml:"groups,omitempty"`
+	PasswordState       string          `json:"password_state" yaml:"password_state"`
+	PasswordLastChanged string          `json:"password_last_changed,omitempty" yaml:"password_last_changed,omitempty"`
+	PasswordExpires     string          `json:"password_expires,omitempty" yaml:"password_expires,omitempty"`
+	AccountExpires      string          `json:"account_expires,omitempty" yaml:"account_expires,omitempty"`
+	SudoAccess          bool            `json:"sudo_access" yaml:"sudo_access"`
+	CanLogin            bool            `json:"can_login" yaml:"can_login"`
+	AuthorizedKeys      []AuthorizedKey `json:"authorized_keys,omitempty" yaml:"authorized_keys,omitempty"`
+}
+
+// AuthorizedKey represents a public key allowed to log in to an account
+type AuthorizedKey struct {
+	Type        string `json:"type" yaml:"type"`
+	Bits        int    `json:"bits,omitempty" yaml:"bits,omitempty"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Comment     string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Options     string `json:"options,omitempty" yaml:"options,omitempty"` // option names only; values such as command="..." may hold credentials
+	Source      string `json:"source" yaml:"source"`
+}
+
+// Group represents a local group
+type Group struct {
+	Name    string   `json:"name" yaml:"name"`
+	GID     int      `json:"gid" yaml:"gid"`
+	Members []string `json:"members" yaml:"members"`
+}
+
+// SudoRule represents a user specification or alias line from sudoers
+type SudoRule struct {
+	Principal string `json:"principal" yaml:"principal"`
+	Rule      string `json:"rule" yaml:"rule"`
+	Source    string `json:"source" yaml:"source"`
+}
+
+// UserInventory groups local accounts, groups and sudo rules for access reviews
+type UserInventory struct {
+	Accounts  []UserAccount `json:"accounts" yaml:"accounts"`
+	Groups    []Group       `json:"groups" yaml:"groups"`
+	SudoRules []SudoRule    `json:"sudo_rules" yaml:"sudo_rules"`
+}
+
+// Runtime represents an installed language interpreter, virtual machine or toolchain
+type Runtime struct {
+	Language       string   `json:"language" yaml:"language"`
+	Version        string   `json:"version" yaml:"version"`
+	Path           string   `json:"path" yaml:"path"`
+	Implementation string   `json:"implementation,omitempty" yaml:"implementation,omitempty"`
+	Manager        string   `json:"manager,omitempty" yaml:"manager,omitempty"`
+	Default        bool     `json:"default" yaml:"default"`
+	INIFile        string   `json:"ini_file,omitempty" yaml:"ini_file,omitempty"`
+	Extensions     []string `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+// Component represents a package found on the system. OS packages record
+// the source package they were built from, which advisories refer to.
+type Component struct {
+	Ecosystem     string `json:"ecosystem" yaml:"ecosystem"`
+	Name          string `json:"name" yaml:"name"`
+	Version       string `json:"version" yaml:"version"`
+	Source        string `json:"source,omitempty" yaml:"source,omitempty"`
+	SourceVersion string `json:"source_version,omitempty" yaml:"source_version,omitempty"`
+	Architecture  string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Location      string `json:"location" yaml:"location"`
+}
+
+// Finding represents a known vulnerability affecting a discovered package
+type Finding struct {
+	ID               string   `json:"id" yaml:"id"`
+	Aliases          []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Severity         string   `json:"severity" yaml:"severity"`
+	Summary          string   `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Ecosystem        string   `json:"ecosystem" yaml:"ecosystem"`
+	Package          string   `json:"package" yaml:"package"`
+	InstalledVersion string   `json:"installed_version" yaml:"installed_version"`
+	FixedVersion     string   `json:"fixed_version,omitempty" yaml:"fixed_version,omitempty"`
+	Location         string   `json:"location" yaml:"location"`
+}
+
+// Listener represents a socket accepting TCP connections or UDP datagrams
+type Listener struct {
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Address  string `json:"address" yaml:"address"`
+	Port     int    `json:"port" yaml:"port"`
+	Process  string `json:"process,omitempty" yaml:"process,omitempty"`
+	Exposure string `json:"exposure,omitempty" yaml:"exposure,omitempty"`
+}
+
+// FirewallBackend represents a firewall implementation with active input rules.
+// Saved backends were read from rule files rather than the running kernel.
+type FirewallBackend struct {
+	Name        string `json:"name" yaml:"name"`
+	InputPolicy string `json:"input_policy,omitempty" yaml:"input_policy,omitempty"`
+	Saved       bool   `json:"saved,omitempty" yaml:"saved,omitempty"`
+}
+
+// FirewallRule represents an inbound rule reduced to protocol, port and source.
+// Jump rules name the chain evaluation continues in as their target. Conditions
+// lists matches that are not modelled, such as interfaces or destinations.
+type FirewallRule struct {
+	Backend    string   `json:"backend" yaml:"backend"`
+	Chain      string   `json:"chain" yaml:"chain"`
+	Action     string   `json:"action" yaml:"action"`
+	Target     string   `json:"target,omitempty" yaml:"target,omitempty"`
+	Protocol   string   `json:"protocol" yaml:"protocol"`
+	Port       string   `json:"port" yaml:"port"`
+	Source     string   `json:"source" yaml:"source"`
+	Conditions []string `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Saved      bool     `json:"saved,omitempty" yaml:"saved,omitempty"`
+}
+
+// FirewallInfo represents the host firewall configuration
+type FirewallInfo struct {
+	Backends []FirewallBackend `json:"backends" yaml:"backends"`
+	Rules    []FirewallRule    `json:"rules" yaml:"rules"`
+}
+
+// SELinuxStatus represents the running and configured SELinux mode
+type SELinuxStatus struct {
+	Mode           string `json:"mode" yaml:"mode"`
+	ConfiguredMode string `json:"configured_mode,omitempty" yaml:"configured_mode,omitempty"`
+	Policy         string `json:"policy,omitempty" yaml:"policy,omitempty"`
+	PolicyVersion  string `json:"policy_version,omitempty" yaml:"policy_version,omitempty"`
+}
+
+// AppArmorProfile represents a loaded AppArmor profile
+type AppArmorProfile struct {
+	Name string `json:"name" yaml:"name"`
+	Mode string `json:"mode" yaml:"mode"`
+}
+
+// AppArmorStatus represents the AppArmor LSM state and loaded profiles
+type AppArmorStatus struct {
+	Enabled  bool              `json:"enabled" yaml:"enabled"`
+	Profiles []AppArmorProfile `json:"profiles" yaml:"profiles"`
+}
+
+// SysctlSetting represents a security-relevant kernel parameter
+type SysctlSetting struct {
+	Name      string `json:"name" yaml:"name"`
+	Value     string `json:"value" yaml:"value"`
+	Expected  string `json:"expected" yaml:"expected"`
+	Compliant bool   `json:"compliant" yaml:"compliant"`
+}
+
+// SecurityInfo represents mandatory access control and kernel hardening status
+type SecurityInfo struct {
+	SELinux  *SELinuxStatus  `json:"selinux,omitempty" yaml:"selinux,omitempty"`
+	AppArmor *AppArmorStatus `json:"apparmor,omitempty" yaml:"apparmor,omitempty"`
+	Sysctls  []SysctlSetting `json:"sysctls" yaml:"sysctls"`
+}
+
+// CloudInstance represents instance details reported by a cloud metadata service
+type CloudInstance struct {
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Type   string `json:"type,omitempty" yaml:"type,omitempty"`
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	Zone   string `json:"zone,omitempty" yaml:"zone,omitempty"`
+}
+
+// PlatformInfo represents the hardware, hypervisor, container and cloud environment of the host
+type PlatformInfo struct {
+	Type          string         `json:"type" yaml:"type"`
+	Hypervisor    string         `json:"hypervisor,omitempty" yaml:"hypervisor,omitempty"`
+	Container     string         `json:"container,omitempty" yaml:"container,omitempty"`
+	CloudProvider string         `json:"cloud_provider,omitempty" yaml:"cloud_provider,omitempty"`
+	Vendor        string         `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Product       string         `json:"product,omitempty" yaml:"product,omitempty"`
+	Instance      *CloudInstance `json:"instance,omitempty" yaml:"instance,omitempty"`
 }
 
 // DiscoveryReport represents the complete system discovery report
 type DiscoveryReport struct {
-	Timestamp        string            `json:"timestamp" yaml:"timestamp"`
-	Hostname         string            `json:"hostname" yaml:"hostname"`
-	SystemInfo       SystemInfo        `json:"system_info" yaml:"system_info"`
-	WebServers       []WebServer       `json:"web_servers" yaml:"web_servers"`
-	Databases        []Database        `json:"databases" yaml:"databases"`
-	DockerContainers []DockerContainer `json:"docker_containers" yaml:"docker_containers"`
+	Timestamp        string                  `json:"timestamp" yaml:"timestamp"`
+	Hostname         string                  `json:"hostname" yaml:"hostname"`
+	SystemInfo       SystemInfo              `json:"system_info" yaml:"system_info"`
+	Platform         PlatformInfo            `json:"platform" yaml:"platform"`
+	WebServers       []WebServer             `json:"web_servers" yaml:"web_servers"`
+	SSHServer        *SSHServer              `json:"ssh_server,omitempty" yaml:"ssh_server,omitempty"`
+	MailServers      []MailServer            `json:"mail_servers" yaml:"mail_servers"`
+	Databases        []Database              `json:"databases" yaml:"databases"`
+	Infrastructure   []InfrastructureService `json:"infrastructure_services" yaml:"infrastructure_services"`
+	MessageBrokers   []MessageBroker         `json:"message_brokers" yaml:"message_brokers"`
+	Agents           []Agent                 `json:"agents" yaml:"agents"`
+	DockerContainers []DockerContainer       `json:"docker_containers" yaml:"docker_containers"`
+	ComposeProjects  []ComposeProject        `json:"compose_projects,omitempty" yaml:"compose_projects,omitempty"`
+	Swarm            *SwarmInfo              `json:"swarm,omitempty" yaml:"swarm,omitempty"`
+	Services         []Service               `json:"services" yaml:"services"`
+	ScheduledJobs    []ScheduledJob          `json:"scheduled_jobs" yaml:"scheduled_jobs"`
+	Users            UserInventory           `json:"users" yaml:"users"`
+	Listeners        []Listener              `json:"listeners" yaml:"listeners"`
+	Firewall         FirewallInfo            `json:"firewall" yaml:"firewall"`
+	Security         SecurityInfo            `json:"security" yaml:"security"`
+	Runtimes         []Runtime               `json:"runtimes" yaml:"runtimes"`
+	Components       []Component             `json:"components" yaml:"components"`
+	OSPackages       []Component             `json:"os_packages" yaml:"os_packages"`
+	Findings         []Finding               `json:"findings,omitempty" yaml:"findings,omitempty"`
 }
 
 // NewDiscoveryReport creates a new discovery report with timestamp set
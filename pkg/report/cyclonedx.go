@@ -0,0 +1,108 @@
+package report
+
+import (
+	"encoding/json"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// cycloneDXBOM is the subset of the CycloneDX 1.5 JSON document we emit
+type cycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+// cycloneDXMetadata describes when, by what and for which host the BOM was produced
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+// cycloneDXTools lists the tools that produced the BOM
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDXComponent is a single CycloneDX component
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	CPE        string              `json:"cpe,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+// cycloneDXProperty is a name/value annotation on a component
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cycloneDXDependency records the components a reference depends on
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// renderCycloneDX converts the discovery report into a CycloneDX JSON SBOM
+func renderCycloneDX(report *model.DiscoveryReport) ([]byte, error) {
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: report.Timestamp,
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{{Type: "application", Name: "go-discovery"}},
+			},
+			Component: cycloneDXComponent{
+				Type:   "device",
+				BOMRef: hostRef,
+				Name:   report.Hostname,
+			},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	// The host depends on what was found on it directly, and each container
+	// image on what was found inside it
+	bom.Dependencies = []cycloneDXDependency{{Ref: hostRef}}
+	dependencies := map[string]int{hostRef: 0}
+	for _, component := range collectSBOMComponents(report) {
+		entry := cycloneDXComponent{
+			Type:    component.Kind,
+			BOMRef:  component.Ref,
+			Name:    component.Name,
+			Version: component.Version,
+			PURL:    component.PURL,
+			CPE:     component.CPE,
+		}
+		if component.Location != "" {
+			entry.Properties = []cycloneDXProperty{{Name: "go-discovery:location", Value: component.Location}}
+		}
+		bom.Components = append(bom.Components, entry)
+
+		parent := component.Parent
+		if parent == "" {
+			parent = hostRef
+		}
+		i, ok := dependencies[parent]
+		if !ok {
+			i = len(bom.Dependencies)
+			dependencies[parent] = i
+			bom.Dependencies = append(bom.Dependencies, cycloneDXDependency{Ref: parent})
+		}
+		bom.Dependencies[i].DependsOn = append(bom.Dependencies[i].DependsOn, component.Ref)
+	}
+
+	return json.MarshalIndent(bom, "", "  ")
+}
@@ -0,0 +1,282 @@
+package report
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// sbomComponent is the format-neutral view of an SBOM entry built from the report
+type sbomComponent struct {
+	Ref      string
+	Parent   string // Ref of the image the component was found in, empty for the host
+	Kind     string // operating-system, application, container or library
+	Name     string
+	Version  string
+	PURL     string
+	CPE      string
+	Location string
+}
+
+// hostRef is the reference of the root component describing the scanned host
+const hostRef = "host"
+
+// osCPEProducts are the CPE vendor and product of distributions whose
+// os-release carries no CPE_NAME
+var osCPEProducts = map[string]string{
+	"debian": "debian:debian_linux",
+	"ubuntu": "canonical:ubuntu_linux",
+	"alpine": "alpinelinux:alpine_linux",
+}
+
+// collectSBOMComponents flattens the discovery report into SBOM components.
+// Everything found inside a container filesystem is parented to its image.
+func collectSBOMComponents(report *model.DiscoveryReport) []sbomComponent {
+	var components []sbomComponent
+
+	// Operating system
+	if component, ok := osComponent("os", "", report.SystemInfo); ok {
+		components = append(components, component)
+	}
+
+	// Web servers and databases; their versions are unknown, so they get no purl
+	for i, webServer := range report.WebServers {
+		components = append(components, sbomComponent{
+			Ref:      fmt.Sprintf("webserver-%d", i),
+			Kind:     "application",
+			Name:     webServer.Type,
+			Location: webServer.ConfigFile,
+		})
+	}
+	for i, database := range report.Databases {
+		components = append(components, sbomComponent{
+			Ref:      fmt.Sprintf("database-%d", i),
+			Kind:     "application",
+			Name:     database.Type,
+			Location: database.ConfigFile,
+		})
+	}
+
+	// Packages installed by the package manager and by language tooling
+	components = append(components, packageComponents("ospackage", "", "", report.SystemInfo, report.OSPackages)...)
+	components = append(components, packageComponents("component", "", "", report.SystemInfo, report.Components)...)
+
+	// Container images, listed once per distinct image with the packages of
+	// the first inspected container that runs it
+	imageRefs := make(map[string]string)
+	inspected := make(map[string]bool)
+	for _, container := range report.DockerContainers {
+		known := container.Image != "" && container.Image != "unknown"
+		key := container.Image
+		if !known {
+			// Without an image name only an inspected filesystem is worth listing
+			if container.Filesystem == nil {
+				continue
+			}
+			key = "container:" + container.Name
+		}
+
+		ref, ok := imageRefs[key]
+		if !ok {
+			ref = fmt.Sprintf("image-%d", len(imageRefs))
+			imageRefs[key] = ref
+			image := sbomComponent{Ref: ref, Kind: "container", Name: container.Name}
+			if known {
+				image.Name, image.Version = splitImageReference(container.Image)
+				image.PURL = imagePURL(image.Name, image.Version)
+			}
+			components = append(components, image)
+		}
+
+		if container.Filesystem == nil || inspected[ref] {
+			continue
+		}
+		inspected[ref] = true
+		filesystem := container.Filesystem
+		if component, ok := osComponent(ref+"-os", ref, filesystem.SystemInfo); ok {
+			components = append(components, component)
+		}
+		components = append(components, packageComponents(ref+"-ospackage", ref, container.Name, filesystem.SystemInfo, filesystem.OSPackages)...)
+		components = append(components, packageComponents(ref+"-component", ref, container.Name, filesystem.SystemInfo, filesystem.Components)...)
+	}
+
+	return components
+}
+
+// osComponent describes an operating system, identified by a CPE
+func osComponent(ref, parent string, system model.SystemInfo) (sbomComponent, bool) {
+	if system.OSName == "" {
+		return sbomComponent{}, false
+	}
+	return sbomComponent{
+		Ref:     ref,
+		Parent:  parent,
+		Kind:    "operating-system",
+		Name:    system.OSName,
+		Version: system.OSVersion,
+		CPE:     osCPE(system),
+	}, true
+}
+
+// osCPE returns the distribution's own CPE_NAME, or builds a CPE 2.3 name
+// from its ID and version
+func osCPE(system model.SystemInfo) string {
+	if system.CPEName != "" {
+		return system.CPEName
+	}
+	if system.OSID == "" {
+		return ""
+	}
+	product, ok := osCPEProducts[system.OSID]
+	if !ok {
+		product = system.OSID + ":" + system.OSID
+	}
+	version := system.OSVersion
+	if version == "" {
+		version = "*"
+	}
+	return "cpe:2.3:o:" + product + ":" + version + ":*:*:*:*:*:*:*"
+}
+
+// packageComponents converts packages into library components. Packages found
+// in a container are located as container:path.
+func packageComponents(prefix, parent, container string, system model.SystemInfo, packages []model.Component) []sbomComponent {
+	var components []sbomComponent
+	for i, pkg := range packages {
+		component := sbomComponent{
+			Ref:      fmt.Sprintf("%s-%d", prefix, i),
+			Parent:   parent,
+			Kind:     "library",
+			Name:     pkg.Name,
+			Version:  pkg.Version,
+			Location: pkg.Location,
+		}
+		switch pkg.Ecosystem {
+		case "deb", "rpm", "apk":
+			component.PURL = osPackagePURL(pkg, system)
+		default:
+			component.PURL = componentPURL(pkg)
+		}
+		if container != "" {
+			component.Location = container + ":" + pkg.Location
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// splitImageReference splits "registry/repo:tag" into repository and tag
+func splitImageReference(image string) (string, string) {
+	image, _, _ = strings.Cut(image, "@")
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, ""
+}
+
+// imagePURL builds a docker package URL, moving any registry host to repository_url
+func imagePURL(name, version string) string {
+	registry := ""
+	if first, rest, ok := strings.Cut(name, "/"); ok && strings.ContainsAny(first, ".:") {
+		registry = first
+		name = rest
+	}
+
+	purl := "pkg:docker/" + escapePURLPath(name)
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+	if registry != "" {
+		purl += "?repository_url=" + url.QueryEscape(registry)
+	}
+	return purl
+}
+
+// componentPURL builds the package URL for a language package
+func componentPURL(component model.Component) string {
+	var purlType, name string
+
+	switch component.Ecosystem {
+	case "pypi":
+		// PyPI names are case-insensitive and treat _ and - alike
+		purlType = "pypi"
+		name = strings.ReplaceAll(strings.ToLower(component.Name), "_", "-")
+	case "maven":
+		purlType = "maven"
+		name = strings.Replace(component.Name, ":", "/", 1)
+	default:
+		purlType = component.Ecosystem
+		name = component.Name
+	}
+
+	purl := "pkg:" + purlType + "/" + escapePURLPath(name)
+	if component.Version != "" {
+		purl += "@" + url.PathEscape(component.Version)
+	}
+	return purl
+}
+
+// osPackagePURL builds the package URL for a dpkg, rpm or apk package, using
+// the distribution ID as namespace, e.g. pkg:deb/debian/curl@7.88.1-10?arch=amd64&distro=debian-12
+func osPackagePURL(pkg model.Component, system model.SystemInfo) string {
+	version := pkg.Version
+	qualifiers := url.Values{}
+	if pkg.Architecture != "" {
+		qualifiers.Set("arch", pkg.Architecture)
+	}
+	if system.OSID != "" && system.OSVersion != "" {
+		qualifiers.Set("distro", system.OSID+"-"+system.OSVersion)
+	}
+	switch pkg.Ecosystem {
+	case "rpm":
+		// rpm purls carry the epoch as a qualifier rather than in the version
+		if epoch, rest, ok := strings.Cut(version, ":"); ok {
+			version = rest
+			qualifiers.Set("epoch", epoch)
+		}
+		if pkg.Source != "" {
+			qualifiers.Set("upstream", pkg.Source)
+		}
+	case "deb":
+		if pkg.Source != "" {
+			qualifiers.Set("upstream", pkg.Source)
+		}
+	}
+
+	purl := "pkg:" + pkg.Ecosystem + "/"
+	if system.OSID != "" {
+		purl += escapePURLPath(strings.ToLower(system.OSID)) + "/"
+	}
+	purl += escapePURLPath(pkg.Name)
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+	if len(qualifiers) > 0 {
+		// Encode sorts the qualifiers by key, as the purl spec requires
+		purl += "?" + qualifiers.Encode()
+	}
+	return purl
+}
+
+// escapePURLPath percent-encodes each segment of a package URL namespace/name
+func escapePURLPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		// PathEscape leaves @ alone, but purl reserves it for the version
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "@", "%40")
+	}
+	return strings.Join(segments, "/")
+}
+
+// newUUID generates a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,196 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// sbomFixtureReport is a host with one OS package, one language package, a web
+// server and two containers of the same inspected Alpine image
+func sbomFixtureReport() *model.DiscoveryReport {
+	alpine := &model.ContainerFilesystem{
+		Method:     "overlay2",
+		SystemInfo: model.SystemInfo{OSName: "Alpine Linux", OSVersion: "3.19.1", OSID: "alpine"},
+		OSPackages: []model.Component{
+			{Ecosystem: "apk", Name: "musl", Version: "1.2.4_git20230717-r4", Architecture: "x86_64", Location: "/lib/apk/db/installed"},
+		},
+		Components: []model.Component{
+			{Ecosystem: "npm", Name: "express", Version: "4.18.2", Location: "/app/node_modules/express"},
+		},
+	}
+	return &model.DiscoveryReport{
+		Hostname:   "web-1",
+		Timestamp:  "2026-10-18T12:00:00Z",
+		SystemInfo: model.SystemInfo{OSName: "Debian GNU/Linux", OSVersion: "12", OSID: "debian"},
+		WebServers: []model.WebServer{{Type: "Nginx", ConfigFile: "/etc/nginx/nginx.conf"}},
+		OSPackages: []model.Component{
+			{Ecosystem: "deb", Name: "libssl3", Version: "3.0.11-1~deb12u2", Source: "openssl", Architecture: "amd64", Location: "/var/lib/dpkg/status"},
+		},
+		Components: []model.Component{
+			{Ecosystem: "pypi", Name: "Django", Version: "4.2.7", Location: "/usr/lib/python3/dist-packages"},
+		},
+		DockerContainers: []model.DockerContainer{
+			{Name: "api-1", Image: "registry.example.com/api:1.4", Filesystem: alpine},
+			{Name: "api-2", Image: "registry.example.com/api:1.4", Filesystem: alpine},
+		},
+	}
+}
+
+func TestOSPackagePURL(t *testing.T) {
+	debian := model.SystemInfo{OSID: "debian", OSVersion: "12"}
+	tests := []struct {
+		name   string
+		pkg    model.Component
+		system model.SystemInfo
+		want   string
+	}{
+		{"deb with source", model.Component{Ecosystem: "deb", Name: "libssl3", Version: "3.0.11-1~deb12u2", Source: "openssl", Architecture: "amd64"}, debian,
+			"pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl"},
+		{"deb epoch stays in version", model.Component{Ecosystem: "deb", Name: "perl", Version: "1:5.36.0-7", Architecture: "amd64"}, debian,
+			"pkg:deb/debian/perl@1:5.36.0-7?arch=amd64&distro=debian-12"},
+		{"rpm epoch becomes qualifier", model.Component{Ecosystem: "rpm", Name: "openssl-libs", Version: "1:3.0.7-24.el9", Source: "openssl", Architecture: "x86_64"},
+			model.SystemInfo{OSID: "rocky", OSVersion: "9.3"},
+			"pkg:rpm/rocky/openssl-libs@3.0.7-24.el9?arch=x86_64&distro=rocky-9.3&epoch=1&upstream=openssl"},
+		{"apk", model.Component{Ecosystem: "apk", Name: "musl", Version: "1.2.4-r2", Architecture: "aarch64"},
+			model.SystemInfo{OSID: "alpine", OSVersion: "3.19.1"},
+			"pkg:apk/alpine/musl@1.2.4-r2?arch=aarch64&distro=alpine-3.19.1"},
+		{"unknown distribution", model.Component{Ecosystem: "deb", Name: "curl", Version: "7.88.1-10"}, model.SystemInfo{},
+			"pkg:deb/curl@7.88.1-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := osPackagePURL(tt.pkg, tt.system); got != tt.want {
+				t.Errorf("osPackagePURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSCPE(t *testing.T) {
+	tests := []struct {
+		name   string
+		system model.SystemInfo
+		want   string
+	}{
+		{"os-release CPE_NAME", model.SystemInfo{OSID: "rocky", OSVersion: "9.3", CPEName: "cpe:/o:rocky:rocky:9::baseos"}, "cpe:/o:rocky:rocky:9::baseos"},
+		{"known distribution", model.SystemInfo{OSID: "debian", OSVersion: "12"}, "cpe:2.3:o:debian:debian_linux:12:*:*:*:*:*:*:*"},
+		{"other distribution", model.SystemInfo{OSID: "arch"}, "cpe:2.3:o:arch:arch:*:*:*:*:*:*:*:*"},
+		{"no ID", model.SystemInfo{OSName: "Linux"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := osCPE(tt.system); got != tt.want {
+				t.Errorf("osCPE() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCycloneDX(t *testing.T) {
+	output, err := renderCycloneDX(sbomFixtureReport())
+	if err != nil {
+		t.Fatalf("renderCycloneDX() error = %v", err)
+	}
+	var bom cycloneDXBOM
+	if err := json.Unmarshal(output, &bom); err != nil {
+		t.Fatalf("decoding CycloneDX output: %v", err)
+	}
+
+	components := make(map[string]cycloneDXComponent)
+	for _, component := range bom.Components {
+		components[component.BOMRef] = component
+	}
+	wantComponents := []struct {
+		ref, kind, purl, cpe string
+	}{
+		{"os", "operating-system", "", "cpe:2.3:o:debian:debian_linux:12:*:*:*:*:*:*:*"},
+		{"webserver-0", "application", "", ""},
+		{"ospackage-0", "library", "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl", ""},
+		{"component-0", "library", "pkg:pypi/django@4.2.7", ""},
+		{"image-0", "container", "pkg:docker/api@1.4?repository_url=registry.example.com", ""},
+		{"image-0-os", "operating-system", "", "cpe:2.3:o:alpinelinux:alpine_linux:3.19.1:*:*:*:*:*:*:*"},
+		{"image-0-ospackage-0", "library", "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1", ""},
+		{"image-0-component-0", "library", "pkg:npm/express@4.18.2", ""},
+	}
+	if len(bom.Components) != len(wantComponents) {
+		t.Errorf("got %d components, want %d", len(bom.Components), len(wantComponents))
+	}
+	for _, want := range wantComponents {
+		got, ok := components[want.ref]
+		if !ok {
+			t.Errorf("component %s missing", want.ref)
+			continue
+		}
+		if got.Type != want.kind || got.PURL != want.purl || got.CPE != want.cpe {
+			t.Errorf("component %s = {%s %q %q}, want {%s %q %q}", want.ref, got.Type, got.PURL, got.CPE, want.kind, want.purl, want.cpe)
+		}
+	}
+	if location := components["image-0-component-0"].Properties; len(location) != 1 || location[0].Value != "api-1:/app/node_modules/express" {
+		t.Errorf("container component properties = %+v, want location api-1:/app/node_modules/express", location)
+	}
+
+	dependencies := make(map[string][]string)
+	for _, dependency := range bom.Dependencies {
+		dependencies[dependency.Ref] = dependency.DependsOn
+	}
+	wantDependencies := map[string][]string{
+		hostRef:   {"os", "webserver-0", "ospackage-0", "component-0", "image-0"},
+		"image-0": {"image-0-os", "image-0-ospackage-0", "image-0-component-0"},
+	}
+	if !reflect.DeepEqual(dependencies, wantDependencies) {
+		t.Errorf("dependencies = %v, want %v", dependencies, wantDependencies)
+	}
+}
+
+func TestRenderSPDX(t *testing.T) {
+	output, err := renderSPDX(sbomFixtureReport())
+	if err != nil {
+		t.Fatalf("renderSPDX() error = %v", err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(output, &doc); err != nil {
+		t.Fatalf("decoding SPDX output: %v", err)
+	}
+
+	refs := make(map[string][]spdxExternalRef)
+	for _, pkg := range doc.Packages {
+		refs[pkg.SPDXID] = pkg.ExternalRefs
+	}
+	// The host plus eight components
+	if len(doc.Packages) != 9 {
+		t.Errorf("got %d packages, want 9", len(doc.Packages))
+	}
+	if got := refs["SPDXRef-ospackage-0"]; len(got) != 1 || got[0].ReferenceType != "purl" ||
+		got[0].ReferenceLocator != "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl" {
+		t.Errorf("ospackage-0 external refs = %+v, want its deb purl", got)
+	}
+	if got := refs["SPDXRef-os"]; len(got) != 1 || got[0].ReferenceCategory != "SECURITY" || got[0].ReferenceType != "cpe23Type" {
+		t.Errorf("os external refs = %+v, want a cpe23Type reference", got)
+	}
+	if got := refs["SPDXRef-webserver-0"]; len(got) != 0 {
+		t.Errorf("webserver-0 external refs = %+v, want none", got)
+	}
+
+	contains := make(map[string][]string)
+	for _, relationship := range doc.Relationships {
+		if relationship.RelationshipType == "CONTAINS" {
+			contains[relationship.SPDXElementID] = append(contains[relationship.SPDXElementID], relationship.RelatedSPDXElement)
+		}
+	}
+	for _, related := range contains {
+		sort.Strings(related)
+	}
+	wantContains := map[string][]string{
+		"SPDXRef-host":    {"SPDXRef-component-0", "SPDXRef-image-0", "SPDXRef-os", "SPDXRef-ospackage-0", "SPDXRef-webserver-0"},
+		"SPDXRef-image-0": {"SPDXRef-image-0-component-0", "SPDXRef-image-0-os", "SPDXRef-image-0-ospackage-0"},
+	}
+	if !reflect.DeepEqual(contains, wantContains) {
+		t.Errorf("CONTAINS relationships = %v, want %v", contains, wantContains)
+	}
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// spdxDocument is the subset of the SPDX 2.3 JSON document we emit
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+// spdxCreationInfo records when and by which tool the document was created
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// spdxPackage is a single SPDX package
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+// spdxExternalRef links a package to an external identifier such as a purl
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// spdxRelationship relates two SPDX elements
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxInvalidIDChars matches characters not allowed in SPDX identifiers
+var spdxInvalidIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// spdxPurposes maps SBOM component kinds to SPDX primary package purposes
+var spdxPurposes = map[string]string{
+	"operating-system": "OPERATING-SYSTEM",
+	"application":      "APPLICATION",
+	"container":        "CONTAINER",
+	"library":          "LIBRARY",
+}
+
+// renderSPDX converts the discovery report into an SPDX JSON SBOM
+func renderSPDX(report *model.DiscoveryReport) ([]byte, error) {
+	hostID := spdxID(hostRef)
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              "go-discovery-" + report.Hostname,
+		DocumentNamespace: "https://github.com/marolt/go-discovery/spdx/" + spdxInvalidIDChars.ReplaceAllString(report.Hostname, "-") + "-" + newUUID(),
+		CreationInfo: spdxCreationInfo{
+			Created:  report.Timestamp,
+			Creators: []string{"Tool: go-discovery"},
+		},
+		Packages: []spdxPackage{{
+			Name:                  report.Hostname,
+			SPDXID:                hostID,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "DEVICE",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: hostID,
+		}},
+	}
+
+	for _, component := range collectSBOMComponents(report) {
+		pkg := spdxPackage{
+			Name:                  component.Name,
+			SPDXID:                spdxID(component.Ref),
+			VersionInfo:           component.Version,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: spdxPurposes[component.Kind],
+		}
+		if component.Location != "" {
+			pkg.Comment = "Found at " + component.Location
+		}
+		if component.PURL != "" {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  component.PURL,
+			})
+		}
+		if component.CPE != "" {
+			// Distributions publish CPE_NAME in either the 2.2 or the 2.3 format
+			cpeType := "cpe23Type"
+			if strings.HasPrefix(component.CPE, "cpe:/") {
+				cpeType = "cpe22Type"
+			}
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "SECURITY",
+				ReferenceType:     cpeType,
+				ReferenceLocator:  component.CPE,
+			})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		// Packages found in a container image belong to that image
+		container := hostID
+		if component.Parent != "" {
+			container = spdxID(component.Parent)
+		}
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      container,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// spdxID builds a valid SPDX element identifier from an SBOM reference
+func spdxID(ref string) string {
+	return "SPDXRef-" + spdxInvalidIDChars.ReplaceAllString(ref, "-")
+}
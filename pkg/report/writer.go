@@ -23,6 +23,10 @@ func WriteReport(report *model.DiscoveryReport, outputFile, format string, logge
 		data, err = json.MarshalIndent(report, "", "  ")
 	case "yaml":
 		data, err = yaml.Marshal(report)
+	case "cyclonedx":
+		data, err = renderCycloneDX(report)
+	case "spdx":
+		data, err = renderSPDX(report)
 	default:
 		return fmt.Errorf("unsupported output format: %s", format)
 	}
@@ -37,3 +41,15 @@ func WriteReport(report *model.DiscoveryReport, outputFile, format string, logge
 
 	return nil
 }
+
+// DefaultFileName returns the default report file name for an output format
+func DefaultFileName(format string) string {
+	switch format {
+	case "cyclonedx":
+		return "system_discovery_report.cdx.json"
+	case "spdx":
+		return "system_discovery_report.spdx.json"
+	default:
+		return fmt.Sprintf("system_discovery_report.%s", format)
+	}
+}
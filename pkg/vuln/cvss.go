@@ -0,0 +1,102 @@
+package vuln
+
+import (
+	"math"
+	"strings"
+)
+
+// cvss3Weights holds the CVSS v3.x base metric weights by metric and value
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.x vector such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"
+func cvss3BaseScore(vector string) (float64, bool) {
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/") {
+		if key, value, ok := strings.Cut(part, ":"); ok {
+			metrics[key] = value
+		}
+	}
+	if !strings.HasPrefix(metrics["CVSS"], "3") {
+		return 0, false
+	}
+
+	scopeChanged := metrics["S"] == "C"
+
+	// Privileges Required weighs differently when scope changes
+	var pr float64
+	switch metrics["PR"] {
+	case "N":
+		pr = 0.85
+	case "L":
+		pr = 0.62
+		if scopeChanged {
+			pr = 0.68
+		}
+	case "H":
+		pr = 0.27
+		if scopeChanged {
+			pr = 0.5
+		}
+	default:
+		return 0, false
+	}
+
+	weights := make(map[string]float64)
+	for metric, values := range cvss3Weights {
+		weight, ok := values[metrics[metric]]
+		if !ok {
+			return 0, false
+		}
+		weights[metric] = weight
+	}
+
+	iss := 1 - (1-weights["C"])*(1-weights["I"])*(1-weights["A"])
+	var impact float64
+	if scopeChanged {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	exploitability := 8.22 * weights["AV"] * weights["AC"] * pr * weights["UI"]
+
+	if impact <= 0 {
+		return 0, true
+	}
+	if scopeChanged {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp rounds to one decimal place upwards as defined by the CVSS v3.1 spec
+func roundUp(value float64) float64 {
+	scaled := int(math.Round(value * 100000))
+	if scaled%10000 == 0 {
+		return float64(scaled) / 100000
+	}
+	return float64(scaled/10000+1) / 10
+}
+
+// cvssRating converts a CVSS score into its qualitative severity rating
+func cvssRating(score float64) string {
+	switch {
+	case score >= 9.0:
+		return "CRITICAL"
+	case score >= 7.0:
+		return "HIGH"
+	case score >= 4.0:
+		return "MEDIUM"
+	case score > 0:
+		return "LOW"
+	default:
+		return "NONE"
+	}
+}
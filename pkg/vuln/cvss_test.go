@@ -0,0 +1,56 @@
+package vuln
+
+import "testing"
+
+func TestCVSS3BaseScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		vector string
+		want   float64
+		ok     bool
+	}{
+		{"critical", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8, true},
+		{"scope changed", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10.0, true},
+		{"reflected xss", "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1, true},
+		{"local privilege escalation", "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8, true},
+		{"high privileges", "CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:H/I:N/A:N", 4.4, true},
+		{"version 3.0", "CVSS:3.0/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", 5.9, true},
+		{"no impact", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0, true},
+		{"version 2", "CVSS:2.0/AV:N/AC:L/Au:N/C:P/I:P/A:P", 0, false},
+		{"missing prefix", "AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 0, false},
+		{"missing metric", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H", 0, false},
+		{"invalid value", "CVSS:3.1/AV:N/AC:L/PR:X/UI:N/S:U/C:H/I:H/A:H", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cvss3BaseScore(tt.vector)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("cvss3BaseScore(%q) = %v, %v, want %v, %v", tt.vector, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCVSSRating(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{10.0, "CRITICAL"},
+		{9.0, "CRITICAL"},
+		{8.9, "HIGH"},
+		{7.0, "HIGH"},
+		{6.9, "MEDIUM"},
+		{4.0, "MEDIUM"},
+		{3.9, "LOW"},
+		{0.1, "LOW"},
+		{0, "NONE"},
+	}
+
+	for _, tt := range tests {
+		if got := cvssRating(tt.score); got != tt.want {
+			t.Errorf("cvssRating(%v) = %q, want %q", tt.score, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package vuln
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// advisory is the subset of the OSV schema used for matching
+type advisory struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected         []affected `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// affected describes the versions of one package an advisory applies to
+type affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string  `json:"type"`
+		Events []event `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+// event is a single introduced/fixed/last_affected boundary of an OSV range
+type event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// Database is an in-memory index of OSV advisories keyed by ecosystem and package name
+type Database struct {
+	advisories map[string]map[string][]*advisory
+	count      int
+}
+
+// LoadDatabase reads every OSV JSON advisory found under dir, including
+// advisories packed in the per-ecosystem zip archives OSV publishes
+func LoadDatabase(dir string, logger *log.Logger) (*Database, error) {
+	logger.Printf("Loading vulnerability database from %s", dir)
+
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error opening vulnerability database: %v", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("vulnerability database %s is not a directory", dir)
+	}
+
+	db := &Database{advisories: make(map[string]map[string][]*advisory)}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Printf("Error reading %s: %v", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				logger.Printf("Error reading advisory %s: %v", path, err)
+				return nil
+			}
+			db.addAdvisory(path, data, logger)
+		case ".zip":
+			db.loadArchive(path, logger)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading vulnerability database: %v", err)
+	}
+
+	logger.Printf("Loaded %d advisories", db.count)
+	return db, nil
+}
+
+// loadArchive adds every JSON advisory contained in a zip archive
+func (db *Database) loadArchive(path string, logger *log.Logger) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		logger.Printf("Error opening advisory archive %s: %v", path, err)
+		return
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".json") {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			logger.Printf("Error reading %s in %s: %v", file.Name, path, err)
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			logger.Printf("Error reading %s in %s: %v", file.Name, path, err)
+			continue
+		}
+		db.addAdvisory(path+":"+file.Name, data, logger)
+	}
+}
+
+// addAdvisory parses one OSV document and indexes it by each affected package
+func (db *Database) addAdvisory(source string, data []byte, logger *log.Logger) {
+	var adv advisory
+	if err := json.Unmarshal(data, &adv); err != nil {
+		logger.Printf("Skipping %s: not an OSV advisory: %v", source, err)
+		return
+	}
+	if adv.ID == "" || len(adv.Affected) == 0 {
+		return
+	}
+
+	indexed := make(map[string]bool)
+	for _, aff := range adv.Affected {
+		ecosystem := normalizeEcosystem(aff.Package.Ecosystem)
+		name := normalizeName(ecosystem, aff.Package.Name)
+		key := ecosystem + "|" + name
+		if indexed[key] {
+			continue
+		}
+		indexed[key] = true
+
+		if db.advisories[ecosystem] == nil {
+			db.advisories[ecosystem] = make(map[string][]*advisory)
+		}
+		db.advisories[ecosystem][name] = append(db.advisories[ecosystem][name], &adv)
+	}
+	db.count++
+}
+
+// normalizeEcosystem drops OSV ecosystem suffixes such as "Debian:12"
+func normalizeEcosystem(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+// normalizeName canonicalises package names where the ecosystem is case-insensitive
+func normalizeName(ecosystem, name string) string {
+	if ecosystem == "PyPI" {
+		// PEP 503 normalisation
+		name = strings.ToLower(name)
+		return strings.NewReplacer("_", "-", ".", "-").Replace(name)
+	}
+	return name
+}
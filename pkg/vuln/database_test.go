@@ -0,0 +1,166 @@
+package vuln
+
+import (
+	"archive/zip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fixture advisories covering a Debian source package with per-release
+// entries, an Alpine aport and two language packages
+const (
+	debianAdvisory = `{
+		"id": "DSA-0001-1",
+		"aliases": ["CVE-2024-0001"],
+		"summary": "openssl security update",
+		"affected": [
+			{"package": {"ecosystem": "Debian:12", "name": "openssl"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.11-1~deb12u2"}]}]},
+			{"package": {"ecosystem": "Debian:11", "name": "openssl"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1w-0+deb11u1"}]}]}
+		],
+		"database_specific": {"severity": "high"}
+	}`
+	oldReleaseAdvisory = `{
+		"id": "DLA-0002-1",
+		"affected": [
+			{"package": {"ecosystem": "Debian:11", "name": "openssl"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1n-0+deb11u6"}]}]}
+		]
+	}`
+	alpineAdvisory = `{
+		"id": "ALPINE-CVE-2024-0003",
+		"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+		"affected": [
+			{"package": {"ecosystem": "Alpine:v3.19", "name": "openssl"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.1.4-r5"}]}]}
+		]
+	}`
+	djangoAdvisory = `{
+		"id": "GHSA-0004",
+		"summary": "Django denial of service",
+		"affected": [
+			{"package": {"ecosystem": "PyPI", "name": "Django"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "4.2"}, {"fixed": "4.2.8"}]}]}
+		],
+		"database_specific": {"severity": "MODERATE"}
+	}`
+	lodashAdvisory = `{
+		"id": "GHSA-0005",
+		"affected": [
+			{"package": {"ecosystem": "npm", "name": "lodash"},
+			 "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.21"}]}]}
+		]
+	}`
+)
+
+// writeFixtureDatabase lays out the fixture advisories as an OSV dump: plain
+// JSON files, a per-ecosystem zip archive and files that are not advisories
+func writeFixtureDatabase(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"Debian/DSA-0001-1.json":           debianAdvisory,
+		"Debian/DLA-0002-1.json":           oldReleaseAdvisory,
+		"Alpine/ALPINE-CVE-2024-0003.json": alpineAdvisory,
+		"Debian/README.json":               `["not", "an", "advisory"]`,
+		"Debian/notes.txt":                 `{"id": "IGNORED"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := os.Create(filepath.Join(dir, "all.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(archive)
+	for name, content := range map[string]string{
+		"GHSA-0004.json": djangoAdvisory,
+		"GHSA-0005.json": lodashAdvisory,
+		"LICENSE":        "not an advisory",
+	} {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(entry, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// loadFixtureDatabase loads the fixture advisories into a Database
+func loadFixtureDatabase(t *testing.T) *Database {
+	t.Helper()
+	db, err := LoadDatabase(writeFixtureDatabase(t), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("LoadDatabase failed: %v", err)
+	}
+	return db
+}
+
+func TestLoadDatabase(t *testing.T) {
+	db := loadFixtureDatabase(t)
+
+	if db.count != 5 {
+		t.Errorf("loaded %d advisories, want 5", db.count)
+	}
+
+	tests := []struct {
+		ecosystem string
+		name      string
+		want      int
+	}{
+		{"Debian", "openssl", 2},
+		{"Alpine", "openssl", 1},
+		{"PyPI", "django", 1},
+		{"npm", "lodash", 1},
+		{"Debian:12", "openssl", 0},
+		{"PyPI", "Django", 0},
+	}
+	for _, tt := range tests {
+		if got := len(db.advisories[tt.ecosystem][tt.name]); got != tt.want {
+			t.Errorf("advisories[%q][%q] has %d entries, want %d", tt.ecosystem, tt.name, got, tt.want)
+		}
+	}
+
+	// An advisory listing the same package for two releases is indexed once
+	if advisories := db.advisories["Debian"]["openssl"]; len(advisories) == 2 && advisories[0].ID == advisories[1].ID {
+		t.Errorf("advisory %s indexed twice", advisories[0].ID)
+	}
+}
+
+func TestLoadDatabaseErrors(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	dir := t.TempDir()
+
+	if _, err := LoadDatabase(filepath.Join(dir, "missing"), logger); err == nil {
+		t.Error("LoadDatabase accepted a missing directory")
+	}
+
+	file := filepath.Join(dir, "advisory.json")
+	if err := os.WriteFile(file, []byte(lodashAdvisory), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDatabase(file, logger); err == nil {
+		t.Error("LoadDatabase accepted a file")
+	}
+}
@@ -0,0 +1,258 @@
+package vuln
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// osvEcosystems maps component ecosystems to their OSV ecosystem names
+var osvEcosystems = map[string]string{
+	"pypi":     "PyPI",
+	"npm":      "npm",
+	"gem":      "RubyGems",
+	"composer": "Packagist",
+	"golang":   "Go",
+	"maven":    "Maven",
+}
+
+// distroEcosystems maps os-release IDs to the OSV ecosystem their packages
+// are filed under and the package format the distribution installs
+var distroEcosystems = map[string]struct{ ecosystem, packageType string }{
+	"debian":    {"Debian", "deb"},
+	"ubuntu":    {"Ubuntu", "deb"},
+	"alpine":    {"Alpine", "apk"},
+	"almalinux": {"AlmaLinux", "rpm"},
+	"rocky":     {"Rocky Linux", "rpm"},
+}
+
+// distro identifies the OSV ecosystem and release OS packages are matched in
+type distro struct {
+	ecosystem   string
+	release     string
+	packageType string
+}
+
+// newDistro derives the OSV ecosystem and release from os-release data, such
+// as "Debian:12", "Ubuntu:22.04" or "Alpine:v3.19"
+func newDistro(info model.SystemInfo) distro {
+	entry, ok := distroEcosystems[info.OSID]
+	if !ok || info.OSVersion == "" {
+		return distro{}
+	}
+
+	release := info.OSVersion
+	parts := strings.Split(release, ".")
+	switch entry.ecosystem {
+	case "Debian", "AlmaLinux", "Rocky Linux":
+		release = parts[0]
+	case "Alpine":
+		if len(parts) >= 2 {
+			release = "v" + parts[0] + "." + parts[1]
+		}
+	}
+	return distro{ecosystem: entry.ecosystem, release: release, packageType: entry.packageType}
+}
+
+// Scan matches the OS and language packages in a report, including those
+// found inside containers, against the database and appends a finding for
+// every advisory affecting an installed version. Web servers and databases
+// are covered through the OS packages that installed them.
+func (db *Database) Scan(report *model.DiscoveryReport, logger *log.Logger) {
+	logger.Println("Matching packages against vulnerability database")
+
+	host := newDistro(report.SystemInfo)
+	report.Findings = append(report.Findings, db.match(report.OSPackages, host, "")...)
+	report.Findings = append(report.Findings, db.match(report.Components, host, "")...)
+	for _, container := range report.DockerContainers {
+		if container.Filesystem == nil {
+			continue
+		}
+		// Locations use docker cp's container:path notation
+		image := newDistro(container.Filesystem.SystemInfo)
+		report.Findings = append(report.Findings, db.match(container.Filesystem.OSPackages, image, container.Name+":")...)
+		report.Findings = append(report.Findings, db.match(container.Filesystem.Components, image, container.Name+":")...)
+	}
+
+	logger.Printf("Found %d vulnerabilities", len(report.Findings))
+}
+
+// match returns a finding for every advisory affecting one of the components.
+// OS packages are looked up by binary and source package name in the
+// ecosystem and release of the distribution they were installed on.
+func (db *Database) match(components []model.Component, system distro, locationPrefix string) []model.Finding {
+	var findings []model.Finding
+	for _, component := range components {
+		ecosystem, release := osvEcosystems[component.Ecosystem], ""
+		if component.Ecosystem == system.packageType {
+			ecosystem, release = system.ecosystem, system.release
+		}
+		if ecosystem == "" || component.Version == "" {
+			continue
+		}
+
+		type lookup struct{ name, version string }
+		lookups := []lookup{{component.Name, component.Version}}
+		if component.Source != "" && component.Source != component.Name {
+			version := component.Version
+			if component.SourceVersion != "" {
+				version = component.SourceVersion
+			}
+			lookups = append(lookups, lookup{component.Source, version})
+		}
+
+		reported := make(map[string]bool)
+		for _, l := range lookups {
+			for _, adv := range db.advisories[ecosystem][normalizeName(ecosystem, l.name)] {
+				if reported[adv.ID] {
+					continue
+				}
+				fixed, affected := adv.affects(ecosystem, release, l.name, l.version)
+				if !affected {
+					continue
+				}
+				reported[adv.ID] = true
+
+				findings = append(findings, model.Finding{
+					ID:               adv.ID,
+					Aliases:          adv.Aliases,
+					Severity:         adv.severity(),
+					Summary:          adv.Summary,
+					Ecosystem:        component.Ecosystem,
+					Package:          component.Name,
+					InstalledVersion: component.Version,
+					FixedVersion:     fixed,
+					Location:         locationPrefix + component.Location,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// affects reports whether the advisory covers the given package version and,
+// if so, the earliest version that fixes it. A non-empty release restricts
+// matching to entries for that distribution release.
+func (adv *advisory) affects(ecosystem, release, name, version string) (string, bool) {
+	name = normalizeName(ecosystem, name)
+	compare := versionComparer(ecosystem)
+
+	for _, aff := range adv.Affected {
+		if normalizeEcosystem(aff.Package.Ecosystem) != ecosystem ||
+			normalizeName(ecosystem, aff.Package.Name) != name {
+			continue
+		}
+		if scope := ecosystemRelease(aff.Package.Ecosystem); release != "" && scope != "" && scope != release {
+			continue
+		}
+
+		// Explicitly enumerated versions
+		for _, v := range aff.Versions {
+			if compare(v, version) == 0 {
+				return firstFixedAfter(aff, version, compare), true
+			}
+		}
+
+		for _, r := range aff.Ranges {
+			// GIT ranges refer to commits, which we cannot relate to installed versions
+			if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+				continue
+			}
+			if rangeAffects(r.Events, version, compare) {
+				return firstFixedAfter(aff, version, compare), true
+			}
+		}
+	}
+	return "", false
+}
+
+// ecosystemRelease returns the release an OSV ecosystem such as "Debian:12"
+// or "Ubuntu:22.04:LTS" is scoped to, or "" when it covers every release
+func ecosystemRelease(ecosystem string) string {
+	_, scope, _ := strings.Cut(ecosystem, ":")
+	release, _, _ := strings.Cut(scope, ":")
+	return release
+}
+
+// rangeAffects evaluates OSV range events in version order: an introduced
+// event at or below the version opens the range, and a later fixed or
+// last_affected boundary at or below it closes it again
+func rangeAffects(events []event, version string, compare func(a, b string) int) bool {
+	sorted := make([]event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compare(eventVersion(sorted[i]), eventVersion(sorted[j])) < 0
+	})
+
+	affected := false
+	for _, e := range sorted {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || compare(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if compare(version, e.Fixed) >= 0 {
+				affected = false
+			}
+		case e.LastAffected != "":
+			if compare(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// eventVersion returns whichever boundary version an event carries
+func eventVersion(e event) string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	default:
+		return e.LastAffected
+	}
+}
+
+// firstFixedAfter returns the lowest fixed version above the installed version
+func firstFixedAfter(aff affected, version string, compare func(a, b string) int) string {
+	fixed := ""
+	for _, r := range aff.Ranges {
+		for _, e := range r.Events {
+			if e.Fixed == "" || compare(e.Fixed, version) <= 0 {
+				continue
+			}
+			if fixed == "" || compare(e.Fixed, fixed) < 0 {
+				fixed = e.Fixed
+			}
+		}
+	}
+	return fixed
+}
+
+// severity returns a CRITICAL/HIGH/MEDIUM/LOW rating for the advisory,
+// preferring the database's own rating and falling back to the CVSS vector
+func (adv *advisory) severity() string {
+	if adv.DatabaseSpecific.Severity != "" {
+		rating := strings.ToUpper(adv.DatabaseSpecific.Severity)
+		// GitHub advisories use MODERATE where CVSS says MEDIUM
+		if rating == "MODERATE" {
+			rating = "MEDIUM"
+		}
+		return rating
+	}
+
+	for _, sev := range adv.Severity {
+		if sev.Type != "CVSS_V3" {
+			continue
+		}
+		if score, ok := cvss3BaseScore(sev.Score); ok {
+			return cvssRating(score)
+		}
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,212 @@
+package vuln
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/marolt/go-discovery/pkg/model"
+)
+
+// parseAdvisory decodes an OSV document for use in a test
+func parseAdvisory(t *testing.T, document string) *advisory {
+	t.Helper()
+	var adv advisory
+	if err := json.Unmarshal([]byte(document), &adv); err != nil {
+		t.Fatalf("invalid advisory: %v", err)
+	}
+	return &adv
+}
+
+func TestRangeAffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []event
+		version string
+		want    bool
+	}{
+		{"introduced zero", []event{{Introduced: "0"}, {Fixed: "1.2.3"}}, "0.1", true},
+		{"below fix", []event{{Introduced: "0"}, {Fixed: "1.2.3"}}, "1.2.2", true},
+		{"at fix", []event{{Introduced: "0"}, {Fixed: "1.2.3"}}, "1.2.3", false},
+		{"above fix", []event{{Introduced: "0"}, {Fixed: "1.2.3"}}, "1.10", false},
+		{"before introduced", []event{{Introduced: "1.0"}, {Fixed: "1.5"}}, "0.9", false},
+		{"at introduced", []event{{Introduced: "1.0"}, {Fixed: "1.5"}}, "1.0", true},
+		{"between ranges", []event{{Introduced: "1.0"}, {Fixed: "1.5"}, {Introduced: "2.0"}, {Fixed: "2.3"}}, "1.7", false},
+		{"second range", []event{{Introduced: "1.0"}, {Fixed: "1.5"}, {Introduced: "2.0"}, {Fixed: "2.3"}}, "2.1", true},
+		{"unsorted events", []event{{Fixed: "2.3"}, {Introduced: "2.0"}, {Fixed: "1.5"}, {Introduced: "1.0"}}, "2.1", true},
+		{"at last affected", []event{{Introduced: "0"}, {LastAffected: "1.4"}}, "1.4", true},
+		{"after last affected", []event{{Introduced: "0"}, {LastAffected: "1.4"}}, "1.4.1", false},
+		{"no fix", []event{{Introduced: "1.0"}}, "5.0", true},
+		{"pre-release before introduced", []event{{Introduced: "2.0.0"}, {Fixed: "2.0.5"}}, "2.0.0-rc1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rangeAffects(tt.events, tt.version, compareVersions); got != tt.want {
+				t.Errorf("rangeAffects(%v, %q) = %v, want %v", tt.events, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffects(t *testing.T) {
+	adv := parseAdvisory(t, `{
+		"id": "TEST-1",
+		"affected": [
+			{"package": {"ecosystem": "npm", "name": "semver-pkg"},
+			 "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.4.2"}]}]},
+			{"package": {"ecosystem": "npm", "name": "git-pkg"},
+			 "ranges": [{"type": "GIT", "repo": "https://example.com/repo", "events": [{"introduced": "0"}, {"fixed": "abc123"}]}]},
+			{"package": {"ecosystem": "npm", "name": "listed-pkg"},
+			 "versions": ["2.0.0", "2.0.1"],
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "3.0.0"}, {"last_affected": "3.1.0"}]}]},
+			{"package": {"ecosystem": "Debian:12", "name": "curl"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "7.88.1-10+deb12u5"}]}]},
+			{"package": {"ecosystem": "Debian", "name": "zlib"},
+			 "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1:1.2.13.dfsg-1"}]}]}
+		]
+	}`)
+
+	tests := []struct {
+		name      string
+		ecosystem string
+		release   string
+		pkg       string
+		version   string
+		wantFixed string
+		want      bool
+	}{
+		{"semver range", "npm", "", "semver-pkg", "1.3.9", "1.4.2", true},
+		{"semver fixed", "npm", "", "semver-pkg", "1.4.2", "", false},
+		{"git range ignored", "npm", "", "git-pkg", "1.0.0", "", false},
+		{"listed version", "npm", "", "listed-pkg", "2.0.1", "", true},
+		{"unlisted version", "npm", "", "listed-pkg", "2.0.2", "", false},
+		{"last affected", "npm", "", "listed-pkg", "3.1.0", "", true},
+		{"other package", "npm", "", "unrelated", "1.0.0", "", false},
+		{"other ecosystem", "PyPI", "", "semver-pkg", "1.3.9", "", false},
+		{"matching release", "Debian", "12", "curl", "7.88.1-10+deb12u4", "7.88.1-10+deb12u5", true},
+		{"other release", "Debian", "11", "curl", "7.74.0-1.3+deb11u7", "", false},
+		{"dpkg ordering", "Debian", "12", "curl", "7.88.1-10+deb12u12", "", false},
+		{"unscoped ecosystem", "Debian", "12", "zlib", "1:1.2.13.dfsg-0", "1:1.2.13.dfsg-1", true},
+		{"epoch", "Debian", "12", "zlib", "1.2.13.dfsg-3", "1:1.2.13.dfsg-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, got := adv.affects(tt.ecosystem, tt.release, tt.pkg, tt.version)
+			if got != tt.want || fixed != tt.wantFixed {
+				t.Errorf("affects(%q, %q, %q, %q) = %q, %v, want %q, %v",
+					tt.ecosystem, tt.release, tt.pkg, tt.version, fixed, got, tt.wantFixed, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstFixedAfter(t *testing.T) {
+	adv := parseAdvisory(t, `{
+		"id": "TEST-2",
+		"affected": [
+			{"package": {"ecosystem": "PyPI", "name": "pkg"},
+			 "ranges": [
+				{"type": "ECOSYSTEM", "events": [{"introduced": "3.0"}, {"fixed": "3.1.0"}]},
+				{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.5"}, {"introduced": "2.0"}, {"fixed": "2.0.3"}]}
+			 ]}
+		]
+	}`)
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.2.0", "1.2.5"},
+		{"2.0.0", "2.0.3"},
+		{"2.0.3", "3.1.0"},
+		{"3.0.1", "3.1.0"},
+		{"3.2", ""},
+	}
+
+	for _, tt := range tests {
+		if got := firstFixedAfter(adv.Affected[0], tt.version, compareVersions); got != tt.want {
+			t.Errorf("firstFixedAfter(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNewDistro(t *testing.T) {
+	tests := []struct {
+		id, version string
+		want        distro
+	}{
+		{"debian", "12", distro{"Debian", "12", "deb"}},
+		{"ubuntu", "22.04", distro{"Ubuntu", "22.04", "deb"}},
+		{"alpine", "3.19.1", distro{"Alpine", "v3.19", "apk"}},
+		{"almalinux", "9.3", distro{"AlmaLinux", "9", "rpm"}},
+		{"rocky", "8.9", distro{"Rocky Linux", "8", "rpm"}},
+		{"debian", "", distro{}},
+		{"arch", "", distro{}},
+	}
+
+	for _, tt := range tests {
+		got := newDistro(model.SystemInfo{OSID: tt.id, OSVersion: tt.version})
+		if got != tt.want {
+			t.Errorf("newDistro(%q, %q) = %+v, want %+v", tt.id, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	db := loadFixtureDatabase(t)
+
+	report := &model.DiscoveryReport{
+		SystemInfo: model.SystemInfo{OSID: "debian", OSVersion: "12"},
+		OSPackages: []model.Component{
+			{Ecosystem: "deb", Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "openssl", Location: "/var/lib/dpkg/status"},
+			{Ecosystem: "deb", Name: "openssl", Version: "3.0.11-1~deb12u2", Location: "/var/lib/dpkg/status"},
+		},
+		Components: []model.Component{
+			{Ecosystem: "pypi", Name: "django", Version: "4.2.7", Location: "/srv/app/venv"},
+			{Ecosystem: "npm", Name: "lodash", Version: "4.17.21", Location: "/srv/app/node_modules/lodash"},
+		},
+		DockerContainers: []model.DockerContainer{
+			{Name: "web", Filesystem: &model.ContainerFilesystem{
+				SystemInfo: model.SystemInfo{OSID: "alpine", OSVersion: "3.19.1"},
+				OSPackages: []model.Component{
+					{Ecosystem: "apk", Name: "libcrypto3", Version: "3.1.4-r1", Source: "openssl", Location: "/lib/apk/db/installed"},
+				},
+				Components: []model.Component{
+					{Ecosystem: "npm", Name: "lodash", Version: "4.17.20", Location: "/app/node_modules/lodash"},
+				},
+			}},
+			{Name: "stopped"},
+		},
+	}
+
+	db.Scan(report, log.New(io.Discard, "", 0))
+
+	want := []model.Finding{
+		{ID: "DSA-0001-1", Aliases: []string{"CVE-2024-0001"}, Severity: "HIGH", Summary: "openssl security update",
+			Ecosystem: "deb", Package: "libssl3", InstalledVersion: "3.0.11-1~deb12u1", FixedVersion: "3.0.11-1~deb12u2",
+			Location: "/var/lib/dpkg/status"},
+		{ID: "GHSA-0004", Severity: "MEDIUM", Summary: "Django denial of service",
+			Ecosystem: "pypi", Package: "django", InstalledVersion: "4.2.7", FixedVersion: "4.2.8",
+			Location: "/srv/app/venv"},
+		{ID: "ALPINE-CVE-2024-0003", Severity: "CRITICAL",
+			Ecosystem: "apk", Package: "libcrypto3", InstalledVersion: "3.1.4-r1", FixedVersion: "3.1.4-r5",
+			Location: "web:/lib/apk/db/installed"},
+		{ID: "GHSA-0005", Severity: "UNKNOWN",
+			Ecosystem: "npm", Package: "lodash", InstalledVersion: "4.17.20", FixedVersion: "4.17.21",
+			Location: "web:/app/node_modules/lodash"},
+	}
+
+	if len(report.Findings) != len(want) {
+		t.Fatalf("Scan found %d vulnerabilities, want %d: %+v", len(report.Findings), len(want), report.Findings)
+	}
+	for i := range want {
+		got, _ := json.Marshal(report.Findings[i])
+		expected, _ := json.Marshal(want[i])
+		if string(got) != string(expected) {
+			t.Errorf("finding %d = %s, want %s", i, got, expected)
+		}
+	}
+}
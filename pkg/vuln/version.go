@@ -0,0 +1,484 @@
+package vuln
+
+import "strings"
+
+// postReleaseTags are alphanumeric version parts that sort after the release they follow
+var postReleaseTags = map[string]bool{"post": true, "patch": true, "p": true, "pl": true}
+
+// compareVersions orders two version strings, returning -1, 0 or 1.
+// It splits versions into runs of digits and letters so that semver, PEP 440
+// and most distribution versions compare sensibly: numeric parts compare as
+// numbers, "rc"/"beta" style suffixes sort before the release, and build
+// metadata after "+" is ignored.
+func compareVersions(a, b string) int {
+	ta := tokenizeVersion(a)
+	tb := tokenizeVersion(b)
+
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		// A missing part is equivalent to zero, so 1.0 equals 1.0.0
+		switch {
+		case i >= len(ta):
+			if c := trailingOrder(tb[i]); c != 0 {
+				return -c
+			}
+			continue
+		case i >= len(tb):
+			if c := trailingOrder(ta[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if c := compareToken(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// trailingOrder reports how a version with an extra token compares to one without it
+func trailingOrder(token string) int {
+	if isNumeric(token) && strings.Trim(token, "0") == "" {
+		return 0
+	}
+	if isNumeric(token) || postReleaseTags[token] {
+		return 1
+	}
+	// Pre-release tags (alpha, beta, rc, dev) sort before the bare release
+	return -1
+}
+
+// compareToken compares two version tokens
+func compareToken(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return 1
+	case bNum:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// tokenizeVersion splits a version into alternating digit and letter runs
+func tokenizeVersion(version string) []string {
+	version = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+	version, _, _ = strings.Cut(version, "+")
+
+	var tokens []string
+	start := -1
+	for i := 0; i <= len(version); i++ {
+		boundary := i == len(version) || !isAlnum(version[i]) ||
+			(start >= 0 && isDigit(version[i]) != isDigit(version[start]))
+		if boundary && start >= 0 {
+			tokens = append(tokens, version[start:i])
+			start = -1
+		}
+		if i < len(version) && isAlnum(version[i]) && start < 0 {
+			start = i
+		}
+	}
+	return tokens
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isAlnum reports whether c is a lower-case ASCII letter or digit
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z')
+}
+
+// isNumeric reports whether a version token is a digit run
+func isNumeric(token string) bool {
+	return token != "" && isDigit(token[0])
+}
+
+// versionComparers holds the ordering of ecosystems whose versions follow
+// their package manager's rules rather than those of compareVersions
+var versionComparers = map[string]func(a, b string) int{
+	"Debian":      compareDebianVersions,
+	"Ubuntu":      compareDebianVersions,
+	"AlmaLinux":   compareRPMVersions,
+	"Rocky Linux": compareRPMVersions,
+	"Alpine":      compareAPKVersions,
+}
+
+// versionComparer returns the version ordering used by an OSV ecosystem
+func versionComparer(ecosystem string) func(a, b string) int {
+	if compare, ok := versionComparers[ecosystem]; ok {
+		return compare
+	}
+	return compareVersions
+}
+
+// compareDebianVersions orders two dpkg versions of the form
+// [epoch:]upstream[-revision] the way dpkg --compare-versions does
+func compareDebianVersions(a, b string) int {
+	epochA, upstreamA, revisionA := splitPackageVersion(a)
+	epochB, upstreamB, revisionB := splitPackageVersion(b)
+
+	if c := compareToken(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareDebianPart(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareDebianPart(revisionA, revisionB)
+}
+
+// compareRPMVersions orders two rpm versions of the form
+// [epoch:]version[-release] the way rpm does. A release is only compared
+// when both versions have one.
+func compareRPMVersions(a, b string) int {
+	epochA, versionA, releaseA := splitPackageVersion(a)
+	epochB, versionB, releaseB := splitPackageVersion(b)
+
+	if c := compareToken(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareRPMPart(versionA, versionB); c != 0 || releaseA == "" || releaseB == "" {
+		return c
+	}
+	return compareRPMPart(releaseA, releaseB)
+}
+
+// apkSuffixes orders apk version suffixes: pre-release suffixes sort before
+// the plain version, the others after it
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// Kinds of apk version tokens, in the order apk ranks them when two versions
+// differ in kind at the same position: the kind that comes first is greater
+const (
+	apkDigit = iota
+	apkLetter
+	apkSuffix
+	apkSuffixNumber
+	apkCommitHash
+	apkRevision
+	apkEnd
+)
+
+// apkToken is one component of an apk version
+type apkToken struct {
+	kind  int
+	value string
+}
+
+// compareAPKVersions orders two apk versions of the form
+// 1.2.3[letter][_suffix[N]...][~hash][-rN] the way apk version -t does.
+// Versions apk would reject fall back to compareVersions.
+func compareAPKVersions(a, b string) int {
+	tokensA, okA := tokenizeAPKVersion(a)
+	tokensB, okB := tokenizeAPKVersion(b)
+	if !okA || !okB {
+		return compareVersions(a, b)
+	}
+
+	for i := 0; ; i++ {
+		tokenA, tokenB := apkTokenAt(tokensA, i), apkTokenAt(tokensB, i)
+		if tokenA.kind == apkEnd && tokenB.kind == apkEnd {
+			return 0
+		}
+		if tokenA.kind != tokenB.kind {
+			// Pre-release suffixes sort before anything, otherwise the
+			// version that continues with a lower-ranked kind is greater
+			if tokenA.kind == apkSuffix && apkSuffixes[tokenA.value] < 0 {
+				return -1
+			}
+			if tokenB.kind == apkSuffix && apkSuffixes[tokenB.value] < 0 {
+				return 1
+			}
+			if tokenA.kind < tokenB.kind {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		switch tokenA.kind {
+		case apkDigit:
+			// Later components with a leading zero compare as fractions
+			if i > 0 && (tokenA.value[0] == '0' || tokenB.value[0] == '0') {
+				c = strings.Compare(tokenA.value, tokenB.value)
+			} else {
+				c = compareToken(tokenA.value, tokenB.value)
+			}
+		case apkSuffix:
+			c = sign(apkSuffixes[tokenA.value] - apkSuffixes[tokenB.value])
+		case apkSuffixNumber, apkRevision:
+			c = compareToken(tokenA.value, tokenB.value)
+		default:
+			c = strings.Compare(tokenA.value, tokenB.value)
+		}
+		if c != 0 {
+			return sign(c)
+		}
+	}
+}
+
+// apkTokenAt returns the i-th token, or an end token past the last one
+func apkTokenAt(tokens []apkToken, i int) apkToken {
+	if i < len(tokens) {
+		return tokens[i]
+	}
+	return apkToken{kind: apkEnd}
+}
+
+// tokenizeAPKVersion splits an apk version into its tokens, reporting false
+// when the version does not follow apk's format
+func tokenizeAPKVersion(version string) ([]apkToken, bool) {
+	var tokens []apkToken
+	i := 0
+	digits := func() string {
+		start := i
+		for i < len(version) && isDigit(version[i]) {
+			i++
+		}
+		return version[start:i]
+	}
+
+	number := digits()
+	if number == "" {
+		return nil, false
+	}
+	tokens = append(tokens, apkToken{apkDigit, number})
+	for i < len(version) {
+		last := tokens[len(tokens)-1].kind
+		switch c := version[i]; {
+		case c == '.' && last == apkDigit:
+			i++
+			if number = digits(); number == "" {
+				return nil, false
+			}
+			tokens = append(tokens, apkToken{apkDigit, number})
+		case c >= 'a' && c <= 'z' && last == apkDigit:
+			tokens = append(tokens, apkToken{apkLetter, string(c)})
+			i++
+		case c == '_' && last < apkCommitHash:
+			i++
+			start := i
+			for i < len(version) && version[i] >= 'a' && version[i] <= 'z' {
+				i++
+			}
+			if _, ok := apkSuffixes[version[start:i]]; !ok {
+				return nil, false
+			}
+			tokens = append(tokens, apkToken{apkSuffix, version[start:i]})
+			if number = digits(); number != "" {
+				tokens = append(tokens, apkToken{apkSuffixNumber, number})
+			}
+		case c == '~' && last < apkCommitHash:
+			i++
+			start := i
+			for i < len(version) && (isDigit(version[i]) || (version[i] >= 'a' && version[i] <= 'f')) {
+				i++
+			}
+			if i == start {
+				return nil, false
+			}
+			tokens = append(tokens, apkToken{apkCommitHash, version[start:i]})
+		case strings.HasPrefix(version[i:], "-r") && last < apkRevision:
+			i += 2
+			if number = digits(); number == "" {
+				return nil, false
+			}
+			tokens = append(tokens, apkToken{apkRevision, number})
+		default:
+			return nil, false
+		}
+	}
+	return tokens, true
+}
+
+// splitPackageVersion splits a distribution package version into its epoch
+// (defaulting to 0), upstream version and revision or release
+func splitPackageVersion(version string) (string, string, string) {
+	epoch := "0"
+	if before, after, ok := strings.Cut(version, ":"); ok && before != "" && isNumeric(before) {
+		epoch, version = before, after
+	}
+	if dash := strings.LastIndex(version, "-"); dash >= 0 {
+		return epoch, version[:dash], version[dash+1:]
+	}
+	return epoch, version, ""
+}
+
+// compareDebianPart implements dpkg's verrevcmp: non-digit runs compare
+// character by character with letters before other symbols and "~" before
+// everything, including the end of the string, and digit runs compare as numbers
+func compareDebianPart(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if ac, bc := debianCharOrder(a, i), debianCharOrder(b, j); ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// debianCharOrder returns the weight dpkg gives the character at s[i]
+func debianCharOrder(s string, i int) int {
+	switch {
+	case i >= len(s) || isDigit(s[i]):
+		return 0
+	case isLetter(s[i]):
+		return int(s[i])
+	case s[i] == '~':
+		return -1
+	default:
+		return int(s[i]) + 256
+	}
+}
+
+// compareRPMPart implements rpmvercmp: versions are compared segment by
+// segment, numeric segments sort after alphabetic ones, "~" sorts before
+// anything and "^" after the bare version
+func compareRPMPart(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isRPMVersionChar(a[i]) {
+			i++
+		}
+		for j < len(b) && !isRPMVersionChar(b[j]) {
+			j++
+		}
+
+		aTilde, bTilde := i < len(a) && a[i] == '~', j < len(b) && b[j] == '~'
+		if aTilde || bTilde {
+			if !aTilde {
+				return 1
+			}
+			if !bTilde {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		aCaret, bCaret := i < len(a) && a[i] == '^', j < len(b) && b[j] == '^'
+		if aCaret || bCaret {
+			switch {
+			case i >= len(a):
+				return -1
+			case j >= len(b):
+				return 1
+			case !aCaret:
+				return 1
+			case !bCaret:
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		numeric := isDigit(a[i])
+		segment := func(s string, start int) int {
+			end := start
+			for end < len(s) && isRPMVersionChar(s[end]) && s[end] != '~' && s[end] != '^' &&
+				isDigit(s[end]) == numeric {
+				end++
+			}
+			return end
+		}
+		endA, endB := segment(a, i), segment(b, j)
+		if endB == j {
+			// Numeric segments are newer than alphabetic ones
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if numeric {
+			c = compareToken(a[i:endA], b[j:endB])
+		} else {
+			c = strings.Compare(a[i:endA], b[j:endB])
+		}
+		if c != 0 {
+			return c
+		}
+		i, j = endA, endB
+	}
+
+	switch {
+	case i >= len(a) && j >= len(b):
+		return 0
+	case i >= len(a):
+		return -1
+	default:
+		return 1
+	}
+}
+
+// isRPMVersionChar reports whether c is significant to rpmvercmp
+func isRPMVersionChar(c byte) bool {
+	return isDigit(c) || isLetter(c) || c == '~' || c == '^'
+}
+
+// isLetter reports whether c is an ASCII letter
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// sign reduces a difference to -1, 0 or 1
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package vuln
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "1.2.3", "1.2.3", 0},
+		{"missing part is zero", "1.0", "1.0.0", 0},
+		{"numeric parts", "1.2.10", "1.2.9", 1},
+		{"leading zeros", "1.02", "1.2", 0},
+		{"v prefix", "v1.2.3", "1.2.3", 0},
+		{"build metadata", "1.2.3+build5", "1.2.3", 0},
+		{"release candidate", "1.0rc1", "1.0", -1},
+		{"semver pre-release", "2.0.0-beta.2", "2.0.0-beta.1", 1},
+		{"pre-release before release", "2.0.0-beta", "2.0.0", -1},
+		{"post release", "1.0.post1", "1.0", 1},
+		{"extra part", "1.0.1", "1.0", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareDebianVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "3.0.11-1", "3.0.11-1", 0},
+		{"revision", "3.0.11-2", "3.0.11-1", 1},
+		{"epoch wins", "1:1.0-1", "2.0-1", 1},
+		{"zero epoch", "0:1.0-1", "1.0-1", 0},
+		{"tilde before release", "1.0~rc1-1", "1.0-1", -1},
+		{"security update", "3.0.11-1~deb12u2", "3.0.11-1~deb12u1", 1},
+		{"backport before release", "3.0.11-1~deb12u2", "3.0.11-1", -1},
+		{"binary rebuild", "1.2-3+b1", "1.2-3", 1},
+		{"stable update", "2.36.1-8+deb11u2", "2.36.1-8+deb11u1", 1},
+		{"letters before symbols", "1.0a", "1.0+", -1},
+		{"numeric parts", "1.10", "1.9", 1},
+		{"no revision", "7.88.1", "7.88.1-10", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareDebianVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareDebianVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareDebianVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareDebianVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareRPMVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "3.0.7-24.el9", "3.0.7-24.el9", 0},
+		{"release", "3.0.7-25.el9", "3.0.7-24.el9", 1},
+		{"epoch wins", "1:1.0-1", "2.0-1", 1},
+		{"release ignored when missing", "3.0.7-24.el9", "3.0.7", 0},
+		{"numeric parts", "1.10-1", "1.9-1", 1},
+		{"leading zeros", "2.02-1", "2.2-1", 0},
+		{"numeric after alphabetic", "1.0.1-1", "1.0a-1", 1},
+		{"tilde before release", "1.0~rc1-1", "1.0-1", -1},
+		{"caret after release", "1.0^git1-1", "1.0-1", 1},
+		{"caret before next version", "1.0^git1-1", "1.0.1-1", -1},
+		{"separators ignored", "1_0-1", "1.0-1", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareRPMVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareRPMVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareRPMVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareRPMVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareAPKVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "1.2.4-r2", "1.2.4-r2", 0},
+		{"revision", "1.2.4-r10", "1.2.4-r9", 1},
+		{"revision after plain version", "1.2.4-r0", "1.2.4", 1},
+		{"numeric parts", "3.1.10-r0", "3.1.9-r0", 1},
+		{"extra part", "1.2.4.1-r0", "1.2.4-r0", 1},
+		{"leading zero compares as fraction", "1.02", "1.1", -1},
+		{"letter after plain version", "1.2.4a-r0", "1.2.4-r0", 1},
+		{"letter before next part", "1.2a", "1.2.1", -1},
+		{"alpha before beta", "1.0_alpha2", "1.0_beta1", -1},
+		{"beta before pre", "1.0_beta", "1.0_pre", -1},
+		{"pre before rc", "1.0_pre1", "1.0_rc1", -1},
+		{"rc before release", "1.0_rc3-r1", "1.0-r1", -1},
+		{"suffix numbers", "1.0_rc10", "1.0_rc9", 1},
+		{"release before cvs", "1.0-r5", "1.0_cvs1-r0", -1},
+		{"cvs before svn", "1.0_cvs", "1.0_svn", -1},
+		{"svn before git", "1.0_svn5", "1.0_git1", -1},
+		{"git before hg", "1.0_git20230717", "1.0_hg1", -1},
+		{"hg before p", "1.0_hg1", "1.0_p1", -1},
+		{"git snapshot after release", "1.2.4_git20230717-r4", "1.2.4-r4", 1},
+		{"patch level", "9.4_p1-r0", "9.4-r3", 1},
+		{"commit hash", "1.0~def0-r0", "1.0~abc1-r0", 1},
+		{"not an apk version", "2.0.0-beta", "2.0.0", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareAPKVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareAPKVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareAPKVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareAPKVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}